@@ -0,0 +1,115 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Object is an event logger custom resource, an EventLogger or a ClusterEventLogger.
+// +kubebuilder:object:generate=false
+type Object interface {
+	client.Object
+	// GetSpec returns the spec of the event logger
+	GetSpec() *EventLoggerSpec
+	// GetStatus returns the status of the event logger
+	GetStatus() *EventLoggerStatus
+	// Hash returns the hash of the spec
+	Hash() string
+	// HasChanged check if the spec or operator version has changed
+	HasChanged() bool
+	// Validate the spec
+	Validate() error
+	// Apply update the status of the current event logger
+	Apply(err error)
+	// SetCondition sets the condition of the given type with the current generation of the event logger
+	SetCondition(conditionType string, status metav1.ConditionStatus, reason, message string)
+	// UpdateReadyCondition sets the ready condition to true if all other conditions are true
+	UpdateReadyCondition()
+}
+
+var (
+	_ Object = &EventLogger{}
+	_ Object = &ClusterEventLogger{}
+)
+
+// ClusterEventLoggerSpec defines the desired state of ClusterEventLogger.
+type ClusterEventLoggerSpec struct {
+	EventLoggerSpec `json:",inline"`
+
+	// ExcludeNamespaces optional glob patterns of namespaces to not log the events of e.g. kube-*
+	// +optional
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty" validate:"dive,glob"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// ClusterEventLogger is the Schema for the clustereventloggers API. The logger pod runs in the namespace of the
+// operator and watches the events of all namespaces.
+type ClusterEventLogger struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterEventLoggerSpec `json:"spec,omitempty"`
+	Status EventLoggerStatus      `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterEventLoggerList contains a list of ClusterEventLogger.
+type ClusterEventLoggerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterEventLogger `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterEventLogger{}, &ClusterEventLoggerList{})
+}
+
+// Apply update the status of the current event logger.
+func (in *ClusterEventLogger) Apply(err error) {
+	in.Status.apply(in.Generation, err)
+}
+
+// SetCondition sets the condition of the given type with the current generation of the event logger.
+func (in *ClusterEventLogger) SetCondition(
+	conditionType string,
+	status metav1.ConditionStatus,
+	reason, message string,
+) {
+	in.Status.setCondition(in.Generation, conditionType, status, reason, message)
+}
+
+// UpdateReadyCondition sets the ready condition to true if all other conditions are true.
+func (in *ClusterEventLogger) UpdateReadyCondition() {
+	in.Status.updateReadyCondition(in.Generation)
+}
+
+// GetSpec returns the spec of the event logger.
+func (in *ClusterEventLogger) GetSpec() *EventLoggerSpec {
+	return &in.Spec.EventLoggerSpec
+}
+
+// GetStatus returns the status of the event logger.
+func (in *ClusterEventLogger) GetStatus() *EventLoggerStatus {
+	return &in.Status
+}
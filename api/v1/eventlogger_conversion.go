@@ -0,0 +1,4 @@
+package v1
+
+// Hub marks the EventLogger as the hub the other api versions are converted from and to. It is the storage version.
+func (*EventLogger) Hub() {}
@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// defaultEl defaults EventLoggers and ClusterEventLoggers.
+type defaultEl[T Object] struct {
+	// discovery lists the served kinds, the kinds are not normalized if nil
+	discovery kindDiscovery
+	// logFields returns the default log fields of the operator config, applied to specs without log fields
+	logFields func() []LogField
+}
+
+// Default implements admission.Defaulter so a webhook will be registered for the type.
+func (d *defaultEl[T]) Default(_ context.Context, el T) error {
+	spec := el.GetSpec()
+	if served := servedKinds(d.discovery); served != nil {
+		for i := range spec.Kinds {
+			spec.Kinds[i].canonicalize(served)
+		}
+	}
+	if spec.ScrapeMetrics == nil {
+		spec.ScrapeMetrics = new(false)
+	}
+	if len(spec.LogFields) == 0 && d.logFields != nil {
+		for _, lf := range d.logFields() {
+			spec.LogFields = append(spec.LogFields, *lf.DeepCopy())
+		}
+	}
+	if cel, ok := any(el).(*ClusterEventLogger); ok {
+		cel.Spec.normalize()
+	} else {
+		spec.normalize()
+	}
+	return nil
+}
+
+// canonicalize replaces the name of the kind with the casing served by the api server and sets the api group if the
+// kind is served by a single group. The kind is kept if none or more than one served kind matches its name.
+func (in *Kind) canonicalize(served map[string][]string) {
+	if _, ok := served[in.Name]; !ok {
+		var names []string
+		for name := range served {
+			if strings.EqualFold(name, in.Name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) != 1 {
+			return
+		}
+		in.Name = names[0]
+	}
+	if groups := served[in.Name]; in.APIGroup == nil && len(groups) == 1 {
+		in.APIGroup = new(groups[0])
+	}
+}
+
+// normalize sorts and dedupes the slices whose order has no meaning, so that semantically equal specs have the same
+// hash.
+func (in *EventLoggerSpec) normalize() {
+	in.EventTypes = sortedSet(in.EventTypes)
+	in.Namespaces = sortedSet(in.Namespaces)
+	for i := range in.Kinds {
+		k := &in.Kinds[i]
+		k.EventTypes = sortedSet(k.EventTypes)
+		k.Reasons = sortedSet(k.Reasons)
+		k.SkipReasons = sortedSet(k.SkipReasons)
+		k.MatchingPatterns = sortedSet(k.MatchingPatterns)
+		k.InvolvedObjectNames = sortedSet(k.InvolvedObjectNames)
+		k.InvolvedObjectNamespaces = sortedSet(k.InvolvedObjectNamespaces)
+	}
+}
+
+// normalize sorts and dedupes the slices whose order has no meaning.
+func (in *ClusterEventLoggerSpec) normalize() {
+	in.EventLoggerSpec.normalize()
+	in.ExcludeNamespaces = sortedSet(in.ExcludeNamespaces)
+}
+
+// sortedSet returns the sorted values without duplicates.
+func sortedSet(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
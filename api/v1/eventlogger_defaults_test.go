@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Defaults", func() {
+	var def *defaultEl[*EventLogger]
+	BeforeEach(func() {
+		def = &defaultEl[*EventLogger]{
+			discovery: servedResources{
+				"v1":                {{Name: "pods", Kind: "Pod"}, {Name: "events", Kind: "Event"}},
+				"apps/v1":           {{Name: "deployments", Kind: "Deployment"}},
+				"events.k8s.io/v1":  {{Name: "events", Kind: "Event"}},
+				"example.com/v1":    {{Name: "widgets", Kind: "Widget"}},
+				"other.example/v1":  {{Name: "widgets", Kind: "WIDGET"}},
+				"metrics.k8s.io/v1": {{Name: "pods", Kind: "PodMetrics"}},
+			},
+			logFields: func() []LogField {
+				return []LogField{{Name: "kind", Path: []string{"InvolvedObject", "Kind"}}}
+			},
+		}
+	})
+
+	It("should normalize the kinds", func() {
+		el := &EventLogger{Spec: EventLoggerSpec{Kinds: []Kind{
+			{Name: "pod"},
+			{Name: "Deployment", APIGroup: new("extensions")},
+			{Name: "event"},
+			{Name: "widget"},
+			{Name: "Unknown"},
+		}}}
+		Ω(def.Default(context.TODO(), el)).ShouldNot(HaveOccurred())
+		Ω(el.Spec.Kinds).Should(Equal([]Kind{
+			{Name: "Pod", APIGroup: new("")},
+			{Name: "Deployment", APIGroup: new("extensions")},
+			{Name: "Event"},
+			{Name: "widget"},
+			{Name: "Unknown"},
+		}))
+	})
+
+	It("should keep the kinds if they could not be discovered", func() {
+		def.discovery = servedResources{}
+		el := &EventLogger{Spec: EventLoggerSpec{Kinds: []Kind{{Name: "pod"}}}}
+		Ω(def.Default(context.TODO(), el)).ShouldNot(HaveOccurred())
+		Ω(el.Spec.Kinds).Should(Equal([]Kind{{Name: "pod"}}))
+	})
+
+	It("should default scrape metrics and log fields", func() {
+		el := &EventLogger{}
+		Ω(def.Default(context.TODO(), el)).ShouldNot(HaveOccurred())
+		Ω(el.Spec.ScrapeMetrics).Should(HaveValue(BeFalse()))
+		Ω(el.Spec.LogFields).Should(Equal([]LogField{{Name: "kind", Path: []string{"InvolvedObject", "Kind"}}}))
+
+		el = &EventLogger{Spec: EventLoggerSpec{
+			ScrapeMetrics: new(true),
+			LogFields:     []LogField{{Name: "type", Path: []string{"Type"}}},
+		}}
+		Ω(def.Default(context.TODO(), el)).ShouldNot(HaveOccurred())
+		Ω(el.Spec.ScrapeMetrics).Should(HaveValue(BeTrue()))
+		Ω(el.Spec.LogFields).Should(Equal([]LogField{{Name: "type", Path: []string{"Type"}}}))
+	})
+
+	It("should sort and dedupe the slices", func() {
+		el := &EventLogger{Spec: EventLoggerSpec{
+			EventTypes: []string{"Warning", "Normal", "Warning"},
+			Namespaces: []string{"shop", "checkout"},
+			Kinds: []Kind{{
+				Name:                     "Pod",
+				EventTypes:               []string{},
+				Reasons:                  []string{"Started", "BackOff"},
+				SkipReasons:              []string{"Killing", "Killing"},
+				MatchingPatterns:         []string{"b", "a"},
+				InvolvedObjectNames:      []string{"web-*", "api-*"},
+				InvolvedObjectNamespaces: []string{"b", "a"},
+			}},
+		}}
+		Ω(def.Default(context.TODO(), el)).ShouldNot(HaveOccurred())
+		Ω(el.Spec.EventTypes).Should(Equal([]string{"Normal", "Warning"}))
+		Ω(el.Spec.Namespaces).Should(Equal([]string{"checkout", "shop"}))
+		k := el.Spec.Kinds[0]
+		Ω(k.EventTypes).Should(Equal([]string{}))
+		Ω(k.Reasons).Should(Equal([]string{"BackOff", "Started"}))
+		Ω(k.SkipReasons).Should(Equal([]string{"Killing"}))
+		Ω(k.MatchingPatterns).Should(Equal([]string{"a", "b"}))
+		Ω(k.InvolvedObjectNames).Should(Equal([]string{"api-*", "web-*"}))
+		Ω(k.InvolvedObjectNamespaces).Should(Equal([]string{"a", "b"}))
+	})
+
+	It("should sort the excluded namespaces of a cluster event logger", func() {
+		cdef := &defaultEl[*ClusterEventLogger]{}
+		cel := &ClusterEventLogger{Spec: ClusterEventLoggerSpec{
+			EventLoggerSpec:   EventLoggerSpec{EventTypes: []string{"Warning", "Normal"}},
+			ExcludeNamespaces: []string{"openshift-*", "kube-*"},
+		}}
+		Ω(cdef.Default(context.TODO(), cel)).ShouldNot(HaveOccurred())
+		Ω(cel.Spec.EventTypes).Should(Equal([]string{"Normal", "Warning"}))
+		Ω(cel.Spec.ExcludeNamespaces).Should(Equal([]string{"kube-*", "openshift-*"}))
+	})
+
+	Context("Hash", func() {
+		It("should not change if the slices are reordered", func() {
+			a := &EventLogger{Spec: EventLoggerSpec{
+				EventTypes: []string{"Warning", "Normal"},
+				Kinds:      []Kind{{Name: "Pod", Reasons: []string{"Started", "BackOff"}}},
+			}}
+			b := &EventLogger{Spec: EventLoggerSpec{
+				EventTypes: []string{"Normal", "Warning"},
+				Kinds:      []Kind{{Name: "Pod", Reasons: []string{"BackOff", "Started", "BackOff"}}},
+			}}
+			Ω(a.Hash()).Should(Equal(b.Hash()))
+			// the spec is not changed
+			Ω(a.Spec.EventTypes).Should(Equal([]string{"Warning", "Normal"}))
+
+			b.Spec.Kinds[0].Reasons = []string{"BackOff"}
+			Ω(a.Hash()).ShouldNot(Equal(b.Hash()))
+		})
+		It("should not change if the excluded namespaces are reordered", func() {
+			a := &ClusterEventLogger{
+				ObjectMeta: metav1.ObjectMeta{Name: "a"},
+				Spec:       ClusterEventLoggerSpec{ExcludeNamespaces: []string{"openshift-*", "kube-*"}},
+			}
+			b := &ClusterEventLogger{Spec: ClusterEventLoggerSpec{ExcludeNamespaces: []string{"kube-*", "openshift-*"}}}
+			Ω(a.Hash()).Should(Equal(b.Hash()))
+		})
+	})
+})
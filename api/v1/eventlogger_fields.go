@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// jsonField a field of the corev1.Event or one of its nested structs.
+type jsonField struct {
+	// goPath the go field names of the field, the fields of inlined structs are prefixed with the name of the struct
+	goPath []string
+	// name the name of the field in the json representation
+	name string
+	typ  reflect.Type
+}
+
+// JSONPathOf converts a path of go field names of the corev1.Event to the JSONPath of the field in the json
+// representation e.g. [InvolvedObject, Name] to .involvedObject.name. Unknown fields are kept as they are.
+func JSONPathOf(goPath []string) string {
+	var sb strings.Builder
+	t := reflect.TypeFor[corev1.Event]()
+	for len(goPath) > 0 {
+		fields := jsonFields(t)
+		i := slices.IndexFunc(fields, func(f jsonField) bool {
+			return len(goPath) >= len(f.goPath) && slices.Equal(goPath[:len(f.goPath)], f.goPath)
+		})
+		if i < 0 {
+			sb.WriteString("." + goPath[0])
+			goPath = goPath[1:]
+			t = nil
+			continue
+		}
+		sb.WriteString("." + fields[i].name)
+		goPath = goPath[len(fields[i].goPath):]
+		t = fields[i].typ
+	}
+	return sb.String()
+}
+
+// jsonFields returns the fields of the struct type in the json representation, nil if the type is no struct.
+func jsonFields(t reflect.Type) []jsonField {
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var result []jsonField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && (name == "" || strings.Contains(opts, "inline")) {
+			for _, inlined := range jsonFields(f.Type) {
+				inlined.goPath = append([]string{f.Name}, inlined.goPath...)
+				result = append(result, inlined)
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		result = append(result, jsonField{goPath: []string{f.Name}, name: name, typ: f.Type})
+	}
+	return result
+}
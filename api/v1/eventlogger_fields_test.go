@@ -0,0 +1,21 @@
+package v1_test
+
+import (
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fields", func() {
+	DescribeTable("JSONPathOf should convert the go field names",
+		func(goPath []string, expected string) {
+			Ω(apiv1.JSONPathOf(goPath)).Should(Equal(expected))
+		},
+		Entry("field", []string{"Reason"}, ".reason"),
+		Entry("nested field", []string{"InvolvedObject", "Name"}, ".involvedObject.name"),
+		Entry("embedded field", []string{"ObjectMeta", "Name"}, ".metadata.name"),
+		Entry("pointer field", []string{"Series", "Count"}, ".series.count"),
+		Entry("unknown field", []string{"Foo", "Bar"}, ".Foo.Bar"),
+	)
+})
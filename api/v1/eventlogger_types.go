@@ -17,7 +17,11 @@ limitations under the License.
 package v1
 
 import (
+	"slices"
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/bakito/k8s-event-logger-operator/version"
@@ -27,11 +31,11 @@ import (
 type EventLoggerSpec struct {
 	// Kinds the kinds to log the events for
 	// +kubebuilder:validation:MinItems=1
-	Kinds []Kind `json:"kinds,omitempty"`
+	Kinds []Kind `json:"kinds,omitempty" validate:"dive"`
 
-	// EventTypes the event types to log. If empty all events are logged.
+	// EventTypes the event types to log, Normal or Warning. If empty all events are logged.
 	// +kubebuilder:validation:MinItems=0
-	EventTypes []string `json:"eventTypes,omitempty"`
+	EventTypes []string `json:"eventTypes,omitempty" validate:"dive,oneof=Normal Warning"`
 
 	// Labels additional labels for the logger pod
 	Labels map[string]string `json:"labels,omitempty" validate:"k8s-label-annotation-keys,k8s-label-values"`
@@ -47,6 +51,16 @@ type EventLoggerSpec struct {
 	// +optional
 	Namespace *string `json:"namespace,omitempty"`
 
+	// Namespaces the namespaces to watch on. Can be combined with NamespaceSelector, the logger pod watches all
+	// selected namespaces. Namespaces that do not exist are ignored
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty" validate:"excluded_with=Namespace,dive,k8s-namespace"`
+
+	// NamespaceSelector selects the namespaces to watch on by their labels. The selected namespaces are updated
+	// when namespaces are created, deleted or relabelled; the logger pod is recreated when the selection changes
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty" validate:"excluded_with=Namespace,omitempty,label-selector"`
+
 	// ServiceAccount the service account to use for the logger pod
 	ServiceAccount string `json:"serviceAccount,omitempty"`
 
@@ -61,8 +75,105 @@ type EventLoggerSpec struct {
 	// +optional
 	NodeSelector map[string]string `json:"nodeSelector,omitempty" validate:"k8s-label-annotation-keys,k8s-label-values"`
 
+	// Replicas the number of logger pods. With more than one replica the pods elect a leader that logs the events,
+	// and a PodDisruptionBudget keeps one logger pod available. Default 1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty" validate:"omitempty,min=1"`
+
+	// PodTemplate an optional pod template strategically merged on top of the pod of the logger, which is based on the
+	// container template of the operator config. Containers are merged by name, the logger container is named
+	// event-logger; e.g. tolerations, affinity, priorityClassName or the resources of the logger can be overridden.
+	// The command, args and env of the operator as well as the service account can not be overridden.
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	PodTemplate *corev1.PodTemplateSpec `json:"podTemplate,omitempty" validate:"-"`
+
 	// LogFields fields ot the event to be logged.
-	LogFields []LogField `json:"logFields,omitempty"`
+	LogFields []LogField `json:"logFields,omitempty" validate:"dive"`
+
+	// Sinks the outputs the matched events are sent to. If empty, the events are logged by the logger pod.
+	// +optional
+	Sinks []Sink `json:"sinks,omitempty" validate:"unique=Name,dive"`
+
+	// Deduplication optional deduplication of repeated events
+	// +optional
+	Deduplication *Deduplication `json:"deduplication,omitempty"`
+
+	// RateLimit optional rate limits of the logged events
+	// +optional
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+
+	// EventAPI the api version of the events to watch. The events of the events.k8s.io api are normalized into
+	// the shape of core events, filters and log fields work on both alike. Default v1
+	// +optional
+	EventAPI EventAPI `json:"eventAPI,omitempty" validate:"omitempty,oneof=v1 events.k8s.io/v1"`
+
+	// Expression an optional CEL expression all logged events must match. The event is available as variable event
+	// with the field names of its json representation e.g. event.count > 5 && event.reason.startsWith("Failed")
+	// +optional
+	Expression string `json:"expression,omitempty" validate:"omitempty,cel"`
+
+	// Checkpoint optional persistent checkpoint of the last processed event. The logger stores the resource version
+	// of the last processed event periodically in a config map, after a restart it replays the events newer than the
+	// checkpoint that still exist
+	// +optional
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+}
+
+// EventAPI the api version of the events to watch.
+// +kubebuilder:validation:Enum=v1;events.k8s.io/v1
+type EventAPI string
+
+const (
+	// EventAPICoreV1 watches the core/v1 events.
+	EventAPICoreV1 EventAPI = "v1"
+	// EventAPIEventsV1 watches the events.k8s.io/v1 events.
+	EventAPIEventsV1 EventAPI = "events.k8s.io/v1"
+)
+
+// Checkpoint defines the persistent resume checkpoint of the logger.
+type Checkpoint struct {
+	// Interval the interval the checkpoint is stored in. Default 10s
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// Deduplication defines how repeated events are deduplicated.
+type Deduplication struct {
+	// Window the time window in which repeated events of the same involved object with the same reason and message
+	// are logged only once. When the window closes, the number of suppressed repeats is logged.
+	Window metav1.Duration `json:"window"`
+}
+
+// RateLimit defines token bucket rate limits of the logged events. Events exceeding the limits are suppressed, the
+// number of suppressed events is logged periodically.
+type RateLimit struct {
+	// PerKey the limit of events of the same involved object with the same reason and message
+	// +optional
+	PerKey *Limit `json:"perKey,omitempty"`
+
+	// Global the limit of all events
+	// +optional
+	Global *Limit `json:"global,omitempty"`
+}
+
+// Limit defines a token bucket rate limit.
+type Limit struct {
+	// Events the number of events allowed per interval
+	// +kubebuilder:validation:Minimum=1
+	Events int32 `json:"events" validate:"min=1"`
+
+	// Interval the interval the number of events is allowed in. Default 1s
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Burst the max number of events allowed at once. Default the number of events
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Burst *int32 `json:"burst,omitempty"`
 }
 
 // Kind defines a kind to log events for.
@@ -74,9 +185,9 @@ type Kind struct {
 	// +nullable
 	APIGroup *string `json:"apiGroup,omitempty"`
 
-	// EventTypes the event types to log. If empty events are logged as defined in spec.
+	// EventTypes the event types to log, Normal or Warning. If empty events are logged as defined in spec.
 	// +kubebuilder:validation:MinItems=0
-	EventTypes []string `json:"eventTypes,omitempty"`
+	EventTypes []string `json:"eventTypes,omitempty" validate:"dive,oneof=Normal Warning"`
 
 	// Reasons the event reasons to log. If empty events with any reasons are logged.
 	// +kubebuilder:validation:MinItems=0
@@ -88,19 +199,84 @@ type Kind struct {
 
 	// MatchingPatterns optional regex pattern that must be contained in the message to be logged
 	// +kubebuilder:validation:MinItems=0
-	MatchingPatterns []string `json:"matchingPatterns,omitempty"`
+	MatchingPatterns []string `json:"matchingPatterns,omitempty" validate:"dive,regex"`
 
 	// SkipOnMatch skip the entry if matched
 	SkipOnMatch *bool `json:"skipOnMatch,omitempty"`
+
+	// InvolvedObjectNames optional glob patterns, the name of the involved object must match one of them
+	// +kubebuilder:validation:MinItems=0
+	// +optional
+	InvolvedObjectNames []string `json:"involvedObjectNames,omitempty" validate:"dive,glob"`
+
+	// InvolvedObjectNamespaces optional glob patterns, the namespace of the involved object must match one of them
+	// +kubebuilder:validation:MinItems=0
+	// +optional
+	InvolvedObjectNamespaces []string `json:"involvedObjectNamespaces,omitempty" validate:"dive,glob"`
+
+	// LabelSelector optional selector the labels of the involved object must match. The labels are read from a
+	// metadata-only cache, the logger pod is granted to get, list and watch the kind in the watched namespace.
+	// +optional
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty" validate:"omitempty,label-selector"`
+
+	// Expression an optional CEL expression the events of this kind must match. The event is available as variable
+	// event with the field names of its json representation e.g. event.count > 5 && event.reason.startsWith("Failed")
+	// +optional
+	Expression string `json:"expression,omitempty" validate:"omitempty,cel"`
+
+	// Notification an optional chat notification sent for each event matching this kind
+	// +optional
+	Notification *Notification `json:"notification,omitempty"`
 }
 
-// LogField defines a log field.
+// NotificationType the type of chat a notification is sent to.
+// +kubebuilder:validation:Enum=slack;teams;generic
+type NotificationType string
+
+const (
+	// NotificationTypeSlack sends the notification to a slack incoming webhook.
+	NotificationTypeSlack NotificationType = "slack"
+	// NotificationTypeTeams sends the notification to a microsoft teams incoming webhook.
+	NotificationTypeTeams NotificationType = "teams"
+	// NotificationTypeGeneric posts the notification with the event fields as json.
+	NotificationTypeGeneric NotificationType = "generic"
+)
+
+// Notification defines a chat notification.
+type Notification struct {
+	// Type of the chat. Default generic
+	// +optional
+	Type NotificationType `json:"type,omitempty"`
+
+	// Template a go text/template rendered with the corev1.Event to create the message.
+	// If empty, a message with type, involved object, reason, message and count is created.
+	// +optional
+	Template string `json:"template,omitempty" validate:"event-template"`
+
+	// WebhookSecretRef the key of a secret in the namespace of the EventLogger containing the webhook url of the chat
+	WebhookSecretRef corev1.SecretKeySelector `json:"webhookSecretRef"`
+}
+
+// LogField defines a log field. Exactly one of path, jsonPath, template or value must be set.
 type LogField struct {
 	// name of the log field
 	Name string `json:"name"`
 	// Path within the corev1.Event struct https://github.com/kubernetes/api/blob/master/core/v1/types.go
+	// with the go field names e.g. [InvolvedObject, Name].
+	// Deprecated: use jsonPath with the json field names e.g. .involvedObject.name
 	// +kubebuilder:validation:MinItems=1
-	Path []string `json:"path,omitempty"`
+	// +optional
+	Path []string `json:"path,omitempty" validate:"omitempty,event-field-path"`
+
+	// JSONPath kubectl style JSONPath of the field within the json representation of the event
+	// e.g. .involvedObject.name or .metadata.ownerReferences[0].name. Multiple matched values are logged as list
+	// +optional
+	JSONPath string `json:"jsonPath,omitempty" validate:"omitempty,event-jsonpath"`
+
+	// Template go template rendered with the json representation of the event, to compose the value of the log field
+	// e.g. {{ .involvedObject.kind }}/{{ .involvedObject.name }}
+	// +optional
+	Template string `json:"template,omitempty" validate:"omitempty,event-log-template"`
 
 	// Value a static value of the log field. Can be used to add static log fields
 	// +optional
@@ -108,6 +284,130 @@ type LogField struct {
 	Value *string `json:"value,omitempty"`
 }
 
+// SinkType the type of sink.
+// +kubebuilder:validation:Enum=log;stdout;file;syslog;webhook
+type SinkType string
+
+const (
+	// SinkTypeLog logs the events with the logger of the logger pod.
+	SinkTypeLog SinkType = "log"
+	// SinkTypeStdout writes the events to stdout.
+	SinkTypeStdout SinkType = "stdout"
+	// SinkTypeFile appends the events to a file.
+	SinkTypeFile SinkType = "file"
+	// SinkTypeSyslog sends the events to syslog.
+	SinkTypeSyslog SinkType = "syslog"
+	// SinkTypeWebhook posts the events in batches to an http endpoint.
+	SinkTypeWebhook SinkType = "webhook"
+)
+
+// SinkEncoding the encoding of the events written to a sink.
+// +kubebuilder:validation:Enum=json;text
+type SinkEncoding string
+
+const (
+	// SinkEncodingJSON encodes the events as json.
+	SinkEncodingJSON SinkEncoding = "json"
+	// SinkEncodingText encodes the events as key=value pairs.
+	SinkEncodingText SinkEncoding = "text"
+)
+
+// FailurePolicy defines how failures of a sink are handled.
+// +kubebuilder:validation:Enum=Log;Ignore;Fallback
+type FailurePolicy string
+
+const (
+	// FailurePolicyLog logs the error of the sink.
+	FailurePolicyLog FailurePolicy = "Log"
+	// FailurePolicyIgnore ignores the error of the sink.
+	FailurePolicyIgnore FailurePolicy = "Ignore"
+	// FailurePolicyFallback logs the event with the logger of the logger pod.
+	FailurePolicyFallback FailurePolicy = "Fallback"
+)
+
+// Sink defines an output the matched events are sent to.
+type Sink struct {
+	// Name of the sink
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name" validate:"required"`
+
+	// Type of the sink
+	Type SinkType `json:"type" validate:"required"`
+
+	// Encoding the encoding of the events. Default json
+	// +optional
+	Encoding SinkEncoding `json:"encoding,omitempty"`
+
+	// OnFailure defines how failures of the sink are handled. Default Log
+	// +optional
+	OnFailure FailurePolicy `json:"onFailure,omitempty"`
+
+	// File the config of a sink of type file
+	// +optional
+	File *FileSink `json:"file,omitempty" validate:"required_if=Type file"`
+
+	// Syslog the config of a sink of type syslog
+	// +optional
+	Syslog *SyslogSink `json:"syslog,omitempty" validate:"required_if=Type syslog"`
+
+	// Webhook the config of a sink of type webhook
+	// +optional
+	Webhook *WebhookSink `json:"webhook,omitempty" validate:"required_if=Type webhook"`
+}
+
+// FileSink defines a sink writing to a file.
+type FileSink struct {
+	// Path of the file the events are appended to
+	Path string `json:"path" validate:"required"`
+}
+
+// SyslogSink defines a sink sending to syslog.
+type SyslogSink struct {
+	// Network the network to connect to the syslog server (tcp or udp). If empty, the local syslog server is used.
+	// +kubebuilder:validation:Enum="";tcp;udp
+	// +optional
+	Network string `json:"network,omitempty"`
+
+	// Address the address of the syslog server
+	// +optional
+	Address string `json:"address,omitempty" validate:"required_with=Network"`
+
+	// Tag the syslog tag. Default event-logger
+	// +optional
+	Tag string `json:"tag,omitempty"`
+}
+
+// WebhookSink defines a sink posting the events as json array to an http endpoint.
+type WebhookSink struct {
+	// URL the events are posted to
+	URL string `json:"url" validate:"required,url"`
+
+	// HeadersSecretRef a secret in the namespace of the EventLogger. Each key of the secret is added as header
+	// to the requests. Can be used to provide authentication headers.
+	// +optional
+	HeadersSecretRef *corev1.LocalObjectReference `json:"headersSecretRef,omitempty"`
+
+	// BatchSize the max number of events sent with one request. Default 100
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BatchSize int `json:"batchSize,omitempty"`
+
+	// FlushInterval the max time events are buffered before being sent. Default 5s
+	// +optional
+	FlushInterval *metav1.Duration `json:"flushInterval,omitempty"`
+
+	// QueueSize the max number of events buffered in memory. Events are dropped if the queue is full. Default 1000
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	QueueSize int `json:"queueSize,omitempty"`
+
+	// MaxRetries the max number of retries of a failed request. The interval between the retries increases
+	// exponentially. Default 5
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRetries *int `json:"maxRetries,omitempty"`
+}
+
 // EventLoggerStatus defines the observed state of EventLogger.
 type EventLoggerStatus struct {
 	// OperatorVersion the version of the operator that processed the cr
@@ -118,9 +418,65 @@ type EventLoggerStatus struct {
 	Hash string `json:"hash,omitempty"`
 	// Error
 	Error string `json:"error,omitempty"`
+	// ObservedGeneration the generation of the cr last processed by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LoggerPod the name of the active logger pod
+	// +optional
+	LoggerPod string `json:"loggerPod,omitempty"`
+	// LastFilterApplied the timestamp the logger pod last applied the filter
+	// +optional
+	LastFilterApplied *metav1.Time `json:"lastFilterApplied,omitempty"`
+	// LastRollout the last rollout of the logger pods, reporting why the logger pods were replaced
+	// +optional
+	LastRollout *Rollout `json:"lastRollout,omitempty"`
+	// Conditions the current conditions of the event logger
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
+// Rollout a rollout of the pod template of the logger deployment.
+type Rollout struct {
+	// Time the timestamp of the rollout
+	Time metav1.Time `json:"time"`
+	// Reason why the logger pods were replaced, Created, PodTemplateChanged or PodTemplateDrifted
+	Reason string `json:"reason"`
+	// ChangedFields the fields of the pod template that changed e.g. nodeSelector or containers[event-logger].resources
+	// +optional
+	ChangedFields []string `json:"changedFields,omitempty"`
+	// PodTemplateHash the hash of the rolled out pod template
+	PodTemplateHash string `json:"podTemplateHash"`
+}
+
+const (
+	// RolloutReasonCreated the logger deployment was created.
+	RolloutReasonCreated = "Created"
+	// RolloutReasonPodTemplateChanged the desired pod template differs from the rolled out one.
+	RolloutReasonPodTemplateChanged = "PodTemplateChanged"
+	// RolloutReasonPodTemplateDrifted the pod template of the deployment was changed manually and is reverted.
+	RolloutReasonPodTemplateDrifted = "PodTemplateDrifted"
+)
+
+const (
+	// ConditionReady is true if all other conditions are true.
+	ConditionReady = "Ready"
+	// ConditionPodRunning is true if the logger deployment is rolled out and a logger pod is available.
+	ConditionPodRunning = "PodRunning"
+	// ConditionRBACReady is true if the service account, role and role binding of the logger pod are provisioned.
+	ConditionRBACReady = "RBACReady"
+	// ConditionConfigValid is true if the spec is valid.
+	ConditionConfigValid = "ConfigValid"
+	// ConditionFilterApplied is true if the logger pod applied the current filter.
+	ConditionFilterApplied = "FilterApplied"
+)
+
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
 
 // EventLogger is the Schema for the eventloggers API.
 type EventLogger struct {
@@ -145,13 +501,100 @@ func init() {
 	SchemeBuilder.Register(&EventLogger{}, &EventLoggerList{})
 }
 
+// WatchesNamespaces returns true if the namespaces to watch on are defined by Namespaces or NamespaceSelector.
+func (in *EventLoggerSpec) WatchesNamespaces() bool {
+	return len(in.Namespaces) > 0 || in.NamespaceSelector != nil
+}
+
+// SecretNames returns the names of the secrets referenced by the spec.
+func (in *EventLoggerSpec) SecretNames() []string {
+	var names []string
+	add := func(name string) {
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, s := range in.Sinks {
+		if s.Webhook != nil && s.Webhook.HeadersSecretRef != nil {
+			add(s.Webhook.HeadersSecretRef.Name)
+		}
+	}
+	for _, k := range in.Kinds {
+		if k.Notification != nil {
+			add(k.Notification.WebhookSecretRef.Name)
+		}
+	}
+	return names
+}
+
 // Apply update the status of the current event logger.
 func (in *EventLogger) Apply(err error) {
+	in.Status.apply(in.Generation, err)
+}
+
+// SetCondition sets the condition of the given type with the current generation of the event logger.
+func (in *EventLogger) SetCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	in.Status.setCondition(in.Generation, conditionType, status, reason, message)
+}
+
+// UpdateReadyCondition sets the ready condition to true if all other conditions are true.
+func (in *EventLogger) UpdateReadyCondition() {
+	in.Status.updateReadyCondition(in.Generation)
+}
+
+// GetSpec returns the spec of the event logger.
+func (in *EventLogger) GetSpec() *EventLoggerSpec {
+	return &in.Spec
+}
+
+// GetStatus returns the status of the event logger.
+func (in *EventLogger) GetStatus() *EventLoggerStatus {
+	return &in.Status
+}
+
+func (in *EventLoggerStatus) apply(generation int64, err error) {
 	if err != nil {
-		in.Status.Error = err.Error()
+		in.Error = err.Error()
+	} else {
+		in.Error = ""
+	}
+	in.LastProcessed = metav1.Now()
+	in.OperatorVersion = version.Version
+	in.ObservedGeneration = generation
+	in.updateReadyCondition(generation)
+}
+
+func (in *EventLoggerStatus) setCondition(
+	generation int64,
+	conditionType string,
+	status metav1.ConditionStatus,
+	reason, message string,
+) {
+	meta.SetStatusCondition(&in.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func (in *EventLoggerStatus) updateReadyCondition(generation int64) {
+	var notReady []string
+	for _, t := range []string{
+		ConditionConfigValid,
+		ConditionRBACReady,
+		ConditionPodRunning,
+		ConditionFilterApplied,
+	} {
+		if !meta.IsStatusConditionTrue(in.Conditions, t) {
+			notReady = append(notReady, t)
+		}
+	}
+	if len(notReady) == 0 {
+		in.setCondition(generation, ConditionReady, metav1.ConditionTrue, "Ready", "")
 	} else {
-		in.Status.Error = ""
+		in.setCondition(generation, ConditionReady, metav1.ConditionFalse, "NotReady",
+			"conditions not true: "+strings.Join(notReady, ", "))
 	}
-	in.Status.LastProcessed = metav1.Now()
-	in.Status.OperatorVersion = version.Version
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
@@ -60,5 +61,39 @@ var _ = Describe("V1", func() {
 			Ω(el.Status.OperatorVersion).Should(Equal(version.Version))
 			Ω(el.Status.LastProcessed).ShouldNot(Equal(Equal(metav1.Time{})))
 		})
+		It("should set the observed generation", func() {
+			el.Generation = 3
+			el.Apply(nil)
+			Ω(el.Status.ObservedGeneration).Should(Equal(int64(3)))
+		})
+	})
+	Context("UpdateReadyCondition", func() {
+		var el *apiv1.EventLogger
+		BeforeEach(func() {
+			el = &apiv1.EventLogger{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+			for _, t := range []string{
+				apiv1.ConditionConfigValid,
+				apiv1.ConditionRBACReady,
+				apiv1.ConditionPodRunning,
+				apiv1.ConditionFilterApplied,
+			} {
+				el.SetCondition(t, metav1.ConditionTrue, "Test", "")
+			}
+		})
+		It("should be ready if all conditions are true", func() {
+			el.UpdateReadyCondition()
+			c := meta.FindStatusCondition(el.Status.Conditions, apiv1.ConditionReady)
+			Ω(c).ShouldNot(BeNil())
+			Ω(c.Status).Should(Equal(metav1.ConditionTrue))
+			Ω(c.ObservedGeneration).Should(Equal(int64(2)))
+		})
+		It("should not be ready if a condition is not true", func() {
+			el.SetCondition(apiv1.ConditionPodRunning, metav1.ConditionFalse, "Pending", "")
+			el.UpdateReadyCondition()
+			c := meta.FindStatusCondition(el.Status.Conditions, apiv1.ConditionReady)
+			Ω(c).ShouldNot(BeNil())
+			Ω(c.Status).Should(Equal(metav1.ConditionFalse))
+			Ω(c.Message).Should(ContainSubstring(apiv1.ConditionPodRunning))
+		})
 	})
 })
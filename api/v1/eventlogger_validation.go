@@ -7,15 +7,23 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
 	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	english "github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"github.com/go-playground/validator/v10/translations/en"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/validate/content"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/bakito/k8s-event-logger-operator/version"
 )
@@ -24,19 +32,81 @@ import (
 type contextKey string
 
 var (
-	specKey   = contextKey("spec")
-	errorsKey = contextKey("errors")
+	specKey    = contextKey("spec")
+	detailsKey = contextKey("details")
 )
 
+// ExpressionParser parses the templates of the sinks, the CEL expressions of the filters and the JSONPaths and
+// templates of the log fields. The api does not depend on the sink, filter and log field packages, the operator
+// registers their parser with RegisterExpressionParser.
+type ExpressionParser interface {
+	// ParseTemplate returns an error if the template can not be rendered with an event
+	ParseTemplate(tmpl string) error
+	// ParseCEL returns an error if the expression does not compile to a bool
+	ParseCEL(expr string) error
+	// ParseJSONPath returns an error if the JSONPath of a log field is invalid or selects unknown fields of an event
+	ParseJSONPath(expr string) error
+	// ParseLogTemplate returns an error if the template of a log field is invalid or uses unknown fields of an event
+	ParseLogTemplate(tmpl string) error
+}
+
+var expressionParser ExpressionParser
+
+// RegisterExpressionParser registers the parser validating the templates, CEL expressions and JSONPaths of the specs.
+// It must be called before the specs are validated, they are not validated without a parser.
+func RegisterExpressionParser(p ExpressionParser) {
+	expressionParser = p
+}
+
 // HasChanged check if the spec or operator version has changed.
 func (in *EventLogger) HasChanged() bool {
-	return in.Status.Hash != in.Spec.Hash() || in.Status.OperatorVersion != version.Version
+	return in.Status.Hash != in.Hash() || in.Status.OperatorVersion != version.Version
+}
+
+// Hash returns the hash of the spec.
+func (in *EventLogger) Hash() string {
+	return in.Spec.Hash()
+}
+
+// Validate the spec. The returned error is an invalid api error reporting the path of each invalid field.
+func (in *EventLogger) Validate() error {
+	if errs := newEventLoggerValidator(&in.Spec).validate(); len(errs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("EventLogger").GroupKind(), in.Name, errs)
+	}
+	return nil
+}
+
+// HasChanged check if the spec or operator version has changed.
+func (in *ClusterEventLogger) HasChanged() bool {
+	return in.Status.Hash != in.Hash() || in.Status.OperatorVersion != version.Version
+}
+
+// Hash returns the hash of the spec.
+func (in *ClusterEventLogger) Hash() string {
+	spec := in.Spec.DeepCopy()
+	spec.normalize()
+	return hash(spec)
+}
+
+// Validate the spec. The returned error is an invalid api error reporting the path of each invalid field.
+func (in *ClusterEventLogger) Validate() error {
+	if errs := newEventLoggerValidator(&in.Spec).validate(); len(errs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("ClusterEventLogger").GroupKind(), in.Name, errs)
+	}
+	return nil
 }
 
-// Hash the event.
+// Hash the event. The slices without meaningful order are sorted, specs only differing in their order have the same
+// hash.
 func (in *EventLoggerSpec) Hash() string {
+	spec := in.DeepCopy()
+	spec.normalize()
+	return hash(spec)
+}
+
+func hash(spec any) string {
 	h := sha256.New()
-	bytes, _ := json.Marshal(in)
+	bytes, _ := json.Marshal(spec)
 	_, _ = h.Write(bytes)
 	sum := h.Sum(nil)
 	return hex.EncodeToString(sum)
@@ -69,40 +139,227 @@ func k8sLabelAnnotationKeys(_ context.Context, fl validator.FieldLevel) bool {
 	return true
 }
 
-// eventLoggerValidator is a custom validator for the event logger.
-type eventLoggerValidator struct {
-	val   *validator.Validate
-	ctx   context.Context //nolint:containedctx
-	spec  *EventLoggerSpec
-	trans ut.Translator
+func eventTemplate(_ context.Context, fl validator.FieldLevel) bool {
+	if tmpl, ok := fl.Field().Interface().(string); ok && tmpl != "" && expressionParser != nil {
+		return expressionParser.ParseTemplate(tmpl) == nil
+	}
+	return true
 }
 
-// Custom error for event logger validation.
-type eventLoggerValidatorError struct {
-	errList []string
+func glob(_ context.Context, fl validator.FieldLevel) bool {
+	if pattern, ok := fl.Field().Interface().(string); ok {
+		_, err := path.Match(pattern, "")
+		return err == nil
+	}
+	return true
 }
 
-func (err eventLoggerValidatorError) Error() string {
-	return strings.Join(err.errList, "\n")
+func labelSelector(_ context.Context, fl validator.FieldLevel) bool {
+	if ls, ok := fl.Field().Interface().(metav1.LabelSelector); ok {
+		_, err := metav1.LabelSelectorAsSelector(&ls)
+		return err == nil
+	}
+	return true
 }
 
-func (err *eventLoggerValidatorError) addError(errStr string) {
-	err.errList = append(err.errList, errStr)
+func k8sNamespace(_ context.Context, fl validator.FieldLevel) bool {
+	if ns, ok := fl.Field().Interface().(string); ok {
+		return len(validation.IsDNS1123Label(ns)) == 0
+	}
+	return true
+}
+
+func celExpression(ctx context.Context, fl validator.FieldLevel) bool {
+	if expr, ok := fl.Field().Interface().(string); ok && expr != "" && expressionParser != nil {
+		if err := expressionParser.ParseCEL(expr); err != nil {
+			addDetail(ctx, fl, err)
+			return false
+		}
+	}
+	return true
+}
+
+func regex(ctx context.Context, fl validator.FieldLevel) bool {
+	if pattern, ok := fl.Field().Interface().(string); ok {
+		if _, err := regexp.Compile(pattern); err != nil {
+			addDetail(ctx, fl, err)
+			return false
+		}
+	}
+	return true
+}
+
+func eventFieldPath(ctx context.Context, fl validator.FieldLevel) bool {
+	if p, ok := fl.Field().Interface().([]string); ok && len(p) > 0 {
+		if err := resolveEventField(p); err != nil {
+			addDetail(ctx, fl, err)
+			return false
+		}
+	}
+	return true
+}
+
+func eventJSONPath(ctx context.Context, fl validator.FieldLevel) bool {
+	if expr, ok := fl.Field().Interface().(string); ok && expr != "" && expressionParser != nil {
+		if err := expressionParser.ParseJSONPath(expr); err != nil {
+			addDetail(ctx, fl, err)
+			return false
+		}
+	}
+	return true
+}
+
+func eventLogTemplate(ctx context.Context, fl validator.FieldLevel) bool {
+	if tmpl, ok := fl.Field().Interface().(string); ok && tmpl != "" && expressionParser != nil {
+		if err := expressionParser.ParseLogTemplate(tmpl); err != nil {
+			addDetail(ctx, fl, err)
+			return false
+		}
+	}
+	return true
+}
+
+// resolveEventField returns an error if the path does not resolve to a field of a corev1.Event. The log fields are
+// read from the event converted into a map with the go field names: nested and embedded structs are converted into
+// nested maps, the path ends at any other type.
+func resolveEventField(p []string) error {
+	t := reflect.TypeFor[corev1.Event]()
+	for i, name := range p {
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || !hasExportedFields(t) {
+			return fmt.Errorf("%s of type %s has no fields", strings.Join(p[:i], "."), t)
+		}
+		f, ok := t.FieldByName(name)
+		if !ok || !f.IsExported() {
+			return fmt.Errorf("%s has no field %s", strings.Join(append([]string{"Event"}, p[:i]...), "."), name)
+		}
+		if len(f.Index) > 1 {
+			// promoted fields of embedded structs are nested below the name of the embedded struct
+			embedded := make([]string, 0, len(f.Index)-1)
+			for j := 1; j < len(f.Index); j++ {
+				embedded = append(embedded, t.FieldByIndex(f.Index[:j]).Name)
+			}
+			return fmt.Errorf("%s is a field of the embedded %s, use the path %s",
+				name, strings.Join(embedded, "."), strings.Join(slices.Concat(p[:i], embedded, p[i:]), "."))
+		}
+		t = f.Type
+	}
+	return nil
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := range t.NumField() {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// addDetail stores the error of the invalid field, it is added to the translated validation error.
+func addDetail(ctx context.Context, fl validator.FieldLevel, err error) {
+	if details, ok := ctx.Value(detailsKey).(map[string]string); ok {
+		details[detailKey(fl.GetTag(), fl.Field().Interface())] = err.Error()
+	}
+}
+
+func detailKey(tag string, value any) string {
+	return fmt.Sprintf("%s:%v", tag, value)
+}
+
+// clusterEventLoggerSpec reports the namespace fields, a cluster event logger watches all namespaces.
+func clusterEventLoggerSpec(sl validator.StructLevel) {
+	if spec, ok := sl.Current().Interface().(ClusterEventLoggerSpec); ok {
+		if spec.Namespace != nil {
+			sl.ReportError(spec.Namespace, "namespace", "Namespace", "cluster-unsupported", "")
+		}
+		if len(spec.Namespaces) > 0 {
+			sl.ReportError(spec.Namespaces, "namespaces", "Namespaces", "cluster-unsupported", "")
+		}
+		if spec.NamespaceSelector != nil {
+			sl.ReportError(spec.NamespaceSelector, "namespaceSelector", "NamespaceSelector", "cluster-unsupported", "")
+		}
+	}
+}
+
+// logField reports log fields with none or more than one of path, jsonPath, template and value. The error is
+// reported on the log field itself, with the names of the fields that are set as value.
+func logField(sl validator.StructLevel) {
+	if lf, ok := sl.Current().Interface().(LogField); ok {
+		var set []string
+		for name, s := range map[string]bool{
+			"path":     len(lf.Path) > 0,
+			"jsonPath": lf.JSONPath != "",
+			"template": lf.Template != "",
+			"value":    lf.Value != nil,
+		} {
+			if s {
+				set = append(set, name)
+			}
+		}
+		switch len(set) {
+		case 0:
+			sl.ReportError("", "", "", "log-field-source", "")
+		case 1:
+		default:
+			slices.Sort(set)
+			sl.ReportError(strings.Join(set, ", "), "", "", "one-log-field-source", "")
+		}
+	}
+}
+
+func secretKeySelector(sl validator.StructLevel) {
+	if sel, ok := sl.Current().Interface().(corev1.SecretKeySelector); ok {
+		if sel.Name == "" {
+			sl.ReportError(sel.Name, "name", "Name", "required", "")
+		}
+		if sel.Key == "" {
+			sl.ReportError(sel.Key, "key", "Key", "required", "")
+		}
+	}
+}
+
+// eventLoggerValidator is a custom validator for the event logger.
+type eventLoggerValidator struct {
+	val   *validator.Validate
+	ctx   context.Context //nolint:containedctx
+	spec  any
+	trans ut.Translator
 }
 
 // newEventLoggerValidator creates a new EventLoggerValidator.
-func newEventLoggerValidator(spec *EventLoggerSpec) *eventLoggerValidator {
+// The spec is an EventLoggerSpec or a ClusterEventLoggerSpec.
+func newEventLoggerValidator(spec any) *eventLoggerValidator {
 	result := validator.New()
+	// report the json names of the fields
+	result.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name, _, _ := strings.Cut(fld.Tag.Get("json"), ",")
+		return name
+	})
 
 	_ = result.RegisterValidationCtx("k8s-label-annotation-keys", k8sLabelAnnotationKeys)
 	_ = result.RegisterValidationCtx("k8s-label-values", k8sLabelValues)
+	_ = result.RegisterValidationCtx("event-template", eventTemplate)
+	_ = result.RegisterValidationCtx("glob", glob)
+	_ = result.RegisterValidationCtx("label-selector", labelSelector)
+	_ = result.RegisterValidationCtx("cel", celExpression)
+	_ = result.RegisterValidationCtx("k8s-namespace", k8sNamespace)
+	_ = result.RegisterValidationCtx("regex", regex)
+	_ = result.RegisterValidationCtx("event-field-path", eventFieldPath)
+	_ = result.RegisterValidationCtx("event-jsonpath", eventJSONPath)
+	_ = result.RegisterValidationCtx("event-log-template", eventLogTemplate)
+	result.RegisterStructValidation(secretKeySelector, corev1.SecretKeySelector{})
+	result.RegisterStructValidation(logField, LogField{})
+	result.RegisterStructValidation(clusterEventLoggerSpec, ClusterEventLoggerSpec{})
 
 	errKey := strings.Join(content.IsLabelKey("a@a"), " ")
 	errLabelVal := strings.Join(content.IsLabelValue("a:/a"), " ")
 
 	// context
 	ctx := context.WithValue(context.Background(), specKey, spec)
-	ctx = context.WithValue(ctx, errorsKey, &eventLoggerValidatorError{})
+	ctx = context.WithValue(ctx, detailsKey, map[string]string{})
 
 	// default translations
 	eng := english.New()
@@ -123,6 +380,54 @@ func newEventLoggerValidator(spec *EventLoggerSpec) *eventLoggerValidator {
 			tag:         "k8s-label-values",
 			translation: "'values in {0}' must match the pattern " + errLabelVal,
 		},
+		{
+			tag:         "event-template",
+			translation: "must be a valid go template that can be rendered with a corev1.Event",
+		},
+		{
+			tag:         "glob",
+			translation: "must be a valid glob pattern",
+		},
+		{
+			tag:         "label-selector",
+			translation: "{0} must be a valid label selector",
+		},
+		{
+			tag:         "cel",
+			translation: "must be a valid CEL expression evaluating to bool",
+		},
+		{
+			tag:         "k8s-namespace",
+			translation: "must be a valid namespace name",
+		},
+		{
+			tag:         "regex",
+			translation: "must be a valid regular expression",
+		},
+		{
+			tag:         "event-field-path",
+			translation: "must be the path of a field of a corev1.Event",
+		},
+		{
+			tag:         "event-jsonpath",
+			translation: "must be a JSONPath of a field of a corev1.Event",
+		},
+		{
+			tag:         "event-log-template",
+			translation: "must be a valid go template of fields of a corev1.Event",
+		},
+		{
+			tag:         "log-field-source",
+			translation: "exactly one of path, jsonPath, template or value must be set",
+		},
+		{
+			tag:         "one-log-field-source",
+			translation: "only one of path, jsonPath, template or value may be set, found {0}",
+		},
+		{
+			tag:         "cluster-unsupported",
+			translation: "is not supported by a ClusterEventLogger, it watches all namespaces",
+		},
 	}
 	for _, t := range translations {
 		_ = result.RegisterTranslation(t.tag, trans, registrationFunc(t.tag, t.translation), translateFunc)
@@ -150,32 +455,72 @@ func translateFunc(tr ut.Translator, fe validator.FieldError) string {
 	return t
 }
 
-// Validate validates the entire event logger spec for errors and returns an error (it can be casted to
-// eventLoggerValidatorError, containing a list of errors inside). When error is printed as string, it will
-// automatically contain the full list of validation errors.
+// Validate validates the entire event logger spec for errors and returns an aggregate of the field errors.
 func (v *eventLoggerValidator) Validate() error {
-	// validate spec
+	return v.validate().ToAggregate()
+}
+
+// validate validates the entire event logger spec and returns an error for each invalid field, reporting its path
+// within the cr.
+func (v *eventLoggerValidator) validate() field.ErrorList {
 	err := v.val.StructCtx(v.ctx, v.spec)
 	if err == nil {
 		return nil
 	}
 
-	// collect human-readable errors
-	result := eventLoggerValidatorError{}
+	var errs field.ErrorList
 	var vErrors validator.ValidationErrors
 	errors.As(err, &vErrors)
 	for _, vErr := range vErrors {
-		errStr := fmt.Sprintf("%s: %s", vErr.Namespace(), vErr.Translate(v.trans))
-		result.addError(errStr)
+		errs = append(errs, v.fieldError(vErr))
 	}
+	return errs
+}
 
-	// collect additional errors stored in context
-	elve, ok := v.ctx.Value(errorsKey).(*eventLoggerValidatorError)
-	if ok {
-		for _, errStr := range elve.errList {
-			result.addError(errStr)
+// fieldError converts the validation error into a field error with the human-readable detail.
+func (v *eventLoggerValidator) fieldError(fe validator.FieldError) *field.Error {
+	p := fieldPath(fe.Namespace())
+	detail := fe.Translate(v.trans)
+	if details, ok := v.ctx.Value(detailsKey).(map[string]string); ok {
+		if d, ok := details[detailKey(fe.Tag(), fe.Value())]; ok {
+			detail += ": " + d
 		}
 	}
 
-	return result
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "log-field-source":
+		return field.Required(p, detail)
+	case "excluded_with", "cluster-unsupported", "one-log-field-source":
+		return field.Forbidden(p, detail)
+	case "oneof":
+		return field.NotSupported(p, fe.Value(), strings.Fields(fe.Param()))
+	}
+	return field.Invalid(p, fe.Value(), detail)
+}
+
+// fieldPath converts the namespace of a validation error e.g. EventLoggerSpec.kinds[0].matchingPatterns[1] into
+// the path of the field within the cr. The root struct and the inlined EventLoggerSpec of a ClusterEventLoggerSpec
+// are not part of the path.
+func fieldPath(namespace string) *field.Path {
+	p := field.NewPath("spec")
+	segments := strings.Split(namespace, ".")
+	for _, s := range segments[1:] {
+		// errors of struct level validations reported on the struct itself end with an empty segment
+		if s == "EventLoggerSpec" || s == "" {
+			continue
+		}
+		name, rest, _ := strings.Cut(s, "[")
+		p = p.Child(name)
+		for rest != "" {
+			var idx string
+			idx, rest, _ = strings.Cut(rest, "]")
+			rest = strings.TrimPrefix(rest, "[")
+			if i, err := strconv.Atoi(idx); err == nil {
+				p = p.Index(i)
+			} else {
+				p = p.Key(idx)
+			}
+		}
+	}
+	return p
 }
@@ -1,6 +1,12 @@
 package v1_test
 
 import (
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -46,5 +52,374 @@ var _ = Describe("V1", func() {
 			}
 			Ω(s.Validate()).Should(HaveOccurred())
 		})
+		It("should accept valid sinks", func() {
+			s := &apiv1.EventLoggerSpec{
+				Sinks: []apiv1.Sink{
+					{Name: "stdout", Type: apiv1.SinkTypeStdout},
+					{Name: "file", Type: apiv1.SinkTypeFile, File: &apiv1.FileSink{Path: "/tmp/events.log"}},
+				},
+			}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should have a missing sink config", func() {
+			s := &apiv1.EventLoggerSpec{
+				Sinks: []apiv1.Sink{{Name: "file", Type: apiv1.SinkTypeFile}},
+			}
+			Ω(s.Validate()).Should(HaveOccurred())
+		})
+		It("should have duplicate sink names", func() {
+			s := &apiv1.EventLoggerSpec{
+				Sinks: []apiv1.Sink{
+					{Name: "stdout", Type: apiv1.SinkTypeStdout},
+					{Name: "stdout", Type: apiv1.SinkTypeLog},
+				},
+			}
+			Ω(s.Validate()).Should(HaveOccurred())
+		})
+		It("should have an invalid webhook url", func() {
+			s := &apiv1.EventLoggerSpec{
+				Sinks: []apiv1.Sink{{Name: "webhook", Type: apiv1.SinkTypeWebhook, Webhook: &apiv1.WebhookSink{URL: "foo"}}},
+			}
+			Ω(s.Validate()).Should(HaveOccurred())
+		})
+		It("should accept the events.k8s.io event api", func() {
+			s := &apiv1.EventLoggerSpec{EventAPI: apiv1.EventAPIEventsV1}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should have an invalid event api", func() {
+			s := &apiv1.EventLoggerSpec{EventAPI: "events.k8s.io/v1beta1"}
+			Ω(s.Validate()).Should(HaveOccurred())
+		})
+		It("should accept a valid rate limit", func() {
+			s := &apiv1.EventLoggerSpec{
+				RateLimit: &apiv1.RateLimit{PerKey: &apiv1.Limit{Events: 1}},
+			}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should have an invalid rate limit", func() {
+			s := &apiv1.EventLoggerSpec{
+				RateLimit: &apiv1.RateLimit{Global: &apiv1.Limit{Events: 0}},
+			}
+			Ω(s.Validate()).Should(HaveOccurred())
+		})
+		It("should accept involved object selectors", func() {
+			s := &apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{
+				Name:                     "Deployment",
+				InvolvedObjectNames:      []string{"payments-*"},
+				InvolvedObjectNamespaces: []string{"shop-[a-z]"},
+				LabelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"payments"}},
+				}},
+			}}}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should have an invalid glob pattern", func() {
+			s := &apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{Name: "Deployment", InvolvedObjectNames: []string{"payments-["}}}}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("must be a valid glob pattern"))
+		})
+		It("should have an invalid label selector", func() {
+			s := &apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{
+				Name: "Deployment",
+				LabelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: metav1.LabelSelectorOpIn},
+				}},
+			}}}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("must be a valid label selector"))
+		})
+	})
+	Context("Validate namespaces", func() {
+		It("should accept namespaces and namespace selector", func() {
+			s := &apiv1.EventLoggerSpec{
+				Namespaces:        []string{"shop", "checkout"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should have an invalid namespace name", func() {
+			s := &apiv1.EventLoggerSpec{Namespaces: []string{"Shop"}}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("must be a valid namespace name"))
+		})
+		It("should not allow namespaces with namespace", func() {
+			s := &apiv1.EventLoggerSpec{Namespace: new("shop"), Namespaces: []string{"checkout"}}
+			Ω(s.Validate()).Should(HaveOccurred())
+		})
+		It("should not allow a namespace selector with namespace", func() {
+			s := &apiv1.EventLoggerSpec{
+				Namespace:         new("shop"),
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			}
+			Ω(s.Validate()).Should(HaveOccurred())
+		})
+		It("should have an invalid namespace selector", func() {
+			s := &apiv1.EventLoggerSpec{NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Equals"}},
+			}}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("must be a valid label selector"))
+		})
+	})
+	Context("Validate cluster event logger", func() {
+		It("should succeed", func() {
+			cel := &apiv1.ClusterEventLogger{Spec: apiv1.ClusterEventLoggerSpec{
+				EventLoggerSpec:   apiv1.EventLoggerSpec{EventTypes: []string{"Warning"}},
+				ExcludeNamespaces: []string{"kube-*", "openshift-?"},
+			}}
+			Ω(cel.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should reject the namespace fields", func() {
+			cel := &apiv1.ClusterEventLogger{Spec: apiv1.ClusterEventLoggerSpec{EventLoggerSpec: apiv1.EventLoggerSpec{
+				Namespace:         new("shop"),
+				Namespaces:        []string{"checkout"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			}}}
+			err := cel.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("spec.namespace: Forbidden: is not supported"))
+			Ω(err.Error()).Should(ContainSubstring("spec.namespaces: Forbidden: is not supported"))
+			Ω(err.Error()).Should(ContainSubstring("spec.namespaceSelector: Forbidden: is not supported"))
+		})
+		It("should reject invalid exclude patterns", func() {
+			cel := &apiv1.ClusterEventLogger{Spec: apiv1.ClusterEventLoggerSpec{ExcludeNamespaces: []string{"kube-["}}}
+			Ω(cel.Validate()).Should(HaveOccurred())
+		})
+		It("should validate the inlined spec", func() {
+			cel := &apiv1.ClusterEventLogger{Spec: apiv1.ClusterEventLoggerSpec{EventLoggerSpec: apiv1.EventLoggerSpec{
+				Labels: map[string]string{"in valid": "valid"},
+			}}}
+			Ω(cel.Validate()).Should(HaveOccurred())
+		})
+	})
+	Context("Validate expression", func() {
+		It("should accept valid expressions", func() {
+			s := &apiv1.EventLoggerSpec{
+				Expression: `event.type == "Warning"`,
+				Kinds:      []apiv1.Kind{{Name: "Pod", Expression: `event.count > 5 && event.reason.startsWith("Failed")`}},
+			}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should reject an expression that does not compile", func() {
+			s := &apiv1.EventLoggerSpec{Expression: `event.type ==`}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("must be a valid CEL expression evaluating to bool"))
+			Ω(err.Error()).Should(ContainSubstring("Syntax error"))
+		})
+		It("should reject an expression that does not type-check", func() {
+			s := &apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{Name: "Pod", Expression: `event.counter > 5`}}}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("undefined field 'counter'"))
+		})
+	})
+	Context("Validate notification", func() {
+		var s *apiv1.EventLoggerSpec
+		BeforeEach(func() {
+			s = &apiv1.EventLoggerSpec{
+				Kinds: []apiv1.Kind{{
+					Name: "Pod",
+					Notification: &apiv1.Notification{
+						Template: "{{ .Reason }}: {{ .Message }}",
+						WebhookSecretRef: corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "chat"},
+							Key:                  "url",
+						},
+					},
+				}},
+			}
+		})
+		It("should succeed", func() {
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should have an invalid template", func() {
+			s.Kinds[0].Notification.Template = "{{ .Reason "
+			Ω(s.Validate()).Should(MatchError(ContainSubstring("spec.kinds[0].notification.template")))
+		})
+		It("should have a template with an unknown field", func() {
+			s.Kinds[0].Notification.Template = "{{ .Foo }}"
+			Ω(s.Validate()).Should(MatchError(ContainSubstring("spec.kinds[0].notification.template")))
+		})
+		It("should have a missing secret key", func() {
+			s.Kinds[0].Notification.WebhookSecretRef.Key = ""
+			Ω(s.Validate()).Should(MatchError(ContainSubstring("key")))
+		})
+	})
+	Context("Validate matching patterns", func() {
+		It("should accept valid patterns", func() {
+			s := &apiv1.EventLoggerSpec{
+				Kinds: []apiv1.Kind{{Name: "Pod", MatchingPatterns: []string{"^Back-off .*", "(?i)failed"}}},
+			}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should report the invalid pattern", func() {
+			s := &apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{
+				{Name: "Deployment"},
+				{Name: "Pod", MatchingPatterns: []string{"valid", "(unclosed"}},
+			}}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring(`spec.kinds[1].matchingPatterns[1]: Invalid value: "(unclosed"`))
+			Ω(err.Error()).Should(ContainSubstring("missing closing )"))
+		})
+	})
+	Context("Validate event types", func() {
+		It("should accept the event types", func() {
+			s := &apiv1.EventLoggerSpec{
+				EventTypes: []string{"Normal", "Warning"},
+				Kinds:      []apiv1.Kind{{Name: "Pod", EventTypes: []string{"Warning"}}},
+			}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should reject unknown event types", func() {
+			s := &apiv1.EventLoggerSpec{
+				EventTypes: []string{"Normal", "Error"},
+				Kinds:      []apiv1.Kind{{Name: "Pod", EventTypes: []string{"warning"}}},
+			}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring(`spec.eventTypes[1]: Unsupported value: "Error"`))
+			Ω(err.Error()).Should(ContainSubstring(`spec.kinds[0].eventTypes[0]: Unsupported value: "warning"`))
+		})
+	})
+	Context("Validate log fields", func() {
+		It("should accept paths of event fields and values", func() {
+			s := &apiv1.EventLoggerSpec{LogFields: []apiv1.LogField{
+				{Name: "name", Path: []string{"InvolvedObject", "Name"}},
+				{Name: "type", Path: []string{"Type"}},
+				{Name: "namespace", Path: []string{"ObjectMeta", "Namespace"}},
+				{Name: "labels", Path: []string{"ObjectMeta", "Labels"}},
+				{Name: "time", Path: []string{"LastTimestamp", "Time"}},
+				{Name: "static", Value: new("")},
+			}}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should report an unknown field", func() {
+			s := &apiv1.EventLoggerSpec{LogFields: []apiv1.LogField{
+				{Name: "name", Path: []string{"InvolvedObject", "Nmae"}},
+			}}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("spec.logFields[0].path: Invalid value"))
+			Ω(err.Error()).Should(ContainSubstring("Event.InvolvedObject has no field Nmae"))
+		})
+		It("should report a path below a field that is not a struct", func() {
+			s := &apiv1.EventLoggerSpec{LogFields: []apiv1.LogField{
+				{Name: "reason", Path: []string{"Reason", "Length"}},
+				{Name: "time", Path: []string{"LastTimestamp", "Time", "Unix"}},
+			}}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("spec.logFields[0].path"))
+			Ω(err.Error()).Should(ContainSubstring("spec.logFields[1].path"))
+			Ω(err.Error()).Should(ContainSubstring("LastTimestamp.Time of type time.Time has no fields"))
+		})
+		It("should suggest the path of a promoted field", func() {
+			s := &apiv1.EventLoggerSpec{LogFields: []apiv1.LogField{{Name: "namespace", Path: []string{"Namespace"}}}}
+			Ω(s.Validate()).Should(MatchError(ContainSubstring("use the path ObjectMeta.Namespace")))
+		})
+		It("should accept json paths and templates", func() {
+			s := &apiv1.EventLoggerSpec{LogFields: []apiv1.LogField{
+				{Name: "name", JSONPath: ".involvedObject.name"},
+				{Name: "owner", JSONPath: "{.metadata.ownerReferences[0].name}"},
+				{Name: "object", Template: "{{ .involvedObject.kind }}/{{ .involvedObject.name }}"},
+			}}
+			Ω(s.Validate()).ShouldNot(HaveOccurred())
+		})
+		It("should report a json path with go field names", func() {
+			s := &apiv1.EventLoggerSpec{LogFields: []apiv1.LogField{
+				{Name: "name", JSONPath: ".InvolvedObject.Name"},
+			}}
+			Ω(s.Validate()).Should(MatchError(ContainSubstring(
+				`spec.logFields[0].jsonPath: Invalid value: ".InvolvedObject.Name": must be a JSONPath of a field of a ` +
+					`corev1.Event: the event has no field InvolvedObject, did you mean involvedObject`)))
+		})
+		It("should report an invalid template", func() {
+			s := &apiv1.EventLoggerSpec{LogFields: []apiv1.LogField{
+				{Name: "object", Template: "{{ .involvedObject.Kind }}"},
+				{Name: "message", Template: "{{ .message "},
+			}}
+			err := s.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring(".involvedObject has no field Kind, did you mean kind"))
+			Ω(err.Error()).Should(ContainSubstring("spec.logFields[1].template: Invalid value"))
+		})
+		It("should reject more than one of path, json path, template and value", func() {
+			s := &apiv1.EventLoggerSpec{LogFields: []apiv1.LogField{
+				{Name: "type", Path: []string{"Type"}, Value: new("static")},
+				{Name: "name", JSONPath: ".involvedObject.name", Template: "{{ .involvedObject.name }}"},
+			}}
+			err := s.Validate()
+			Ω(err).Should(MatchError(ContainSubstring(
+				"spec.logFields[0]: Forbidden: only one of path, jsonPath, template or value may be set, found path, value")))
+			Ω(err).Should(MatchError(ContainSubstring(
+				"spec.logFields[1]: Forbidden: only one of path, jsonPath, template or value may be set, " +
+					"found jsonPath, template")))
+		})
+		It("should reject neither path, json path, template nor value", func() {
+			s := &apiv1.EventLoggerSpec{LogFields: []apiv1.LogField{{Name: "type"}}}
+			Ω(s.Validate()).Should(MatchError(
+				"spec.logFields[0]: Required value: exactly one of path, jsonPath, template or value must be set"))
+		})
+	})
+	Context("Validate cr", func() {
+		It("should return an invalid error with the field paths", func() {
+			el := &apiv1.EventLogger{
+				ObjectMeta: metav1.ObjectMeta{Name: "logger"},
+				Spec: apiv1.EventLoggerSpec{
+					Kinds:     []apiv1.Kind{{Name: "Pod", MatchingPatterns: []string{"["}}},
+					LogFields: []apiv1.LogField{{Name: "type"}},
+				},
+			}
+			err := el.Validate()
+			Ω(apierrors.IsInvalid(err)).Should(BeTrue())
+			var status apierrors.APIStatus
+			Ω(errors.As(err, &status)).Should(BeTrue())
+			causes := status.Status().Details.Causes
+			Ω(causes).Should(HaveLen(2))
+			Ω(causes[0].Field).Should(Equal("spec.kinds[0].matchingPatterns[0]"))
+			Ω(causes[1].Field).Should(Equal("spec.logFields[0]"))
+			Ω(err.Error()).Should(HavePrefix(`EventLogger.eventlogger.bakito.ch "logger" is invalid`))
+		})
+		It("should report the fields of the inlined spec of a cluster event logger", func() {
+			cel := &apiv1.ClusterEventLogger{
+				ObjectMeta: metav1.ObjectMeta{Name: "logger"},
+				Spec: apiv1.ClusterEventLoggerSpec{EventLoggerSpec: apiv1.EventLoggerSpec{
+					Labels: map[string]string{"in valid": "valid"},
+				}},
+			}
+			err := cel.Validate()
+			Ω(apierrors.IsInvalid(err)).Should(BeTrue())
+			Ω(err.Error()).Should(ContainSubstring("spec.labels: Invalid value"))
+		})
+		It("should return no error if valid", func() {
+			Ω((&apiv1.EventLogger{}).Validate()).Should(BeNil())
+			Ω((&apiv1.ClusterEventLogger{}).Validate()).Should(BeNil())
+		})
+	})
+	Context("SecretNames", func() {
+		It("should return the unique secret names", func() {
+			s := &apiv1.EventLoggerSpec{
+				Sinks: []apiv1.Sink{
+					{Name: "a", Webhook: &apiv1.WebhookSink{HeadersSecretRef: &corev1.LocalObjectReference{Name: "s1"}}},
+					{Name: "b", Webhook: &apiv1.WebhookSink{HeadersSecretRef: &corev1.LocalObjectReference{Name: "s1"}}},
+					{Name: "c", Webhook: &apiv1.WebhookSink{}},
+					{Name: "d"},
+				},
+				Kinds: []apiv1.Kind{
+					{Name: "Pod", Notification: &apiv1.Notification{
+						WebhookSecretRef: corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "s2"},
+						},
+					}},
+				},
+			}
+			Ω(s.SecretNames()).Should(Equal([]string{"s1", "s2"}))
+		})
 	})
 })
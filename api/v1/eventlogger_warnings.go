@@ -0,0 +1,200 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// kindDiscoveryInterval the interval the discovered resources are cached for.
+const kindDiscoveryInterval = time.Minute
+
+// kindDiscovery lists the resources served by the api server.
+type kindDiscovery interface {
+	ServerPreferredResources() ([]*metav1.APIResourceList, error)
+}
+
+// cachedKindDiscovery serves the resources from the cache of the discovery, the cache is invalidated after the
+// interval, so that the kinds of new crds are discovered without a discovery of all groups per admission request.
+type cachedKindDiscovery struct {
+	discovery.CachedDiscoveryInterface
+	interval time.Duration
+
+	mu         sync.Mutex
+	validUntil time.Time
+}
+
+func newCachedKindDiscovery(dc discovery.DiscoveryInterface, interval time.Duration) *cachedKindDiscovery {
+	return &cachedKindDiscovery{CachedDiscoveryInterface: memory.NewMemCacheClient(dc), interval: interval}
+}
+
+// ServerPreferredResources implements kindDiscovery interface.
+func (d *cachedKindDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	d.mu.Lock()
+	if now := time.Now(); now.After(d.validUntil) {
+		d.Invalidate()
+		d.validUntil = now.Add(d.interval)
+	}
+	d.mu.Unlock()
+	return d.CachedDiscoveryInterface.ServerPreferredResources()
+}
+
+// matchAllSamples messages every pattern matching all messages matches.
+var matchAllSamples = []string{"", "x", "Back-off restarting failed container", "0 1\n2"}
+
+// warnings returns the admission warnings of risky but valid configurations, that likely log nothing or not what
+// was intended. The checks reading the cluster are skipped if the state could not be read.
+func (v *validateEl[T]) warnings(ctx context.Context, el T) admission.Warnings {
+	spec := el.GetSpec()
+	kindsPath := field.NewPath("spec", "kinds")
+
+	var w admission.Warnings
+	served := servedKinds(v.discovery)
+	for i, k := range spec.Kinds {
+		p := kindsPath.Index(i)
+		w = append(w, kindWarnings(p, k, served)...)
+		w = append(w, reasonWarnings(p, k)...)
+		w = append(w, patternWarnings(p, k)...)
+	}
+	w = append(w, v.namespaceWarnings(ctx, spec)...)
+	w = append(w, logFieldWarnings(spec)...)
+	if _, ok := any(el).(*ClusterEventLogger); ok {
+		w = append(w, eventTypeWarnings(spec)...)
+	}
+	return w
+}
+
+// servedKinds returns the api groups of the kinds served by the api server, nil if they could not be discovered.
+func servedKinds(d kindDiscovery) map[string][]string {
+	if d == nil {
+		return nil
+	}
+	// the resources of the other groups are returned if the discovery of a group failed
+	lists, _ := d.ServerPreferredResources()
+	if len(lists) == 0 {
+		return nil
+	}
+	served := make(map[string][]string)
+	for _, l := range lists {
+		gv, err := schema.ParseGroupVersion(l.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range l.APIResources {
+			if !strings.Contains(r.Name, "/") && !slices.Contains(served[r.Kind], gv.Group) {
+				served[r.Kind] = append(served[r.Kind], gv.Group)
+			}
+		}
+	}
+	return served
+}
+
+func kindWarnings(p *field.Path, k Kind, served map[string][]string) admission.Warnings {
+	if served == nil {
+		return nil
+	}
+	groups, ok := served[k.Name]
+	if !ok {
+		return admission.Warnings{
+			fmt.Sprintf("%s: kind %s is not served by the api server, no events are logged for it", p.Child("name"), k.Name),
+		}
+	}
+	if k.APIGroup != nil && !slices.Contains(groups, *k.APIGroup) {
+		return admission.Warnings{fmt.Sprintf("%s: kind %s is not served in the api group %q but in %q",
+			p.Child("apiGroup"), k.Name, *k.APIGroup, groups)}
+	}
+	return nil
+}
+
+func reasonWarnings(p *field.Path, k Kind) admission.Warnings {
+	var both []string
+	for _, r := range k.Reasons {
+		if slices.Contains(k.SkipReasons, r) && !slices.Contains(both, r) {
+			both = append(both, r)
+		}
+	}
+	if len(both) == 0 {
+		return nil
+	}
+	return admission.Warnings{fmt.Sprintf("%s: the reasons %s are also skipped, the events with these reasons are never logged",
+		p.Child("skipReasons"), strings.Join(both, ", "))}
+}
+
+// patternWarnings reports the patterns matching every message. The patterns are matched against sample messages,
+// a pattern matching all samples matches anything.
+func patternWarnings(p *field.Path, k Kind) admission.Warnings {
+	var w admission.Warnings
+	for i, mp := range k.MatchingPatterns {
+		re, err := regexp.Compile(mp)
+		if err != nil {
+			continue
+		}
+		if !slices.ContainsFunc(matchAllSamples, func(s string) bool { return !re.MatchString(s) }) {
+			effect := "the pattern has no effect"
+			if k.SkipOnMatch != nil && *k.SkipOnMatch {
+				effect = "no events of the kind are logged"
+			}
+			w = append(w, fmt.Sprintf("%s: %q matches every message, %s", p.Child("matchingPatterns").Index(i), mp, effect))
+		}
+	}
+	return w
+}
+
+// namespaceWarnings reports the watched namespaces that do not exist.
+func (v *validateEl[T]) namespaceWarnings(ctx context.Context, spec *EventLoggerSpec) admission.Warnings {
+	if v.client == nil {
+		return nil
+	}
+	var w admission.Warnings
+	missing := func(p *field.Path, name string) {
+		err := v.client.Get(ctx, client.ObjectKey{Name: name}, &corev1.Namespace{})
+		if apierrors.IsNotFound(err) {
+			w = append(w, fmt.Sprintf("%s: namespace %s does not exist, its events are logged once it is created", p, name))
+		}
+	}
+	if spec.Namespace != nil && *spec.Namespace != "" {
+		missing(field.NewPath("spec", "namespace"), *spec.Namespace)
+	}
+	for i, ns := range spec.Namespaces {
+		missing(field.NewPath("spec", "namespaces").Index(i), ns)
+	}
+	return w
+}
+
+// logFieldWarnings reports the log fields with a deprecated path of go field names.
+func logFieldWarnings(spec *EventLoggerSpec) admission.Warnings {
+	var w admission.Warnings
+	for i, lf := range spec.LogFields {
+		if len(lf.Path) > 0 {
+			w = append(w, fmt.Sprintf("%s: path is deprecated, use jsonPath %s",
+				field.NewPath("spec", "logFields").Index(i).Child("path"), JSONPathOf(lf.Path)))
+		}
+	}
+	return w
+}
+
+// eventTypeWarnings reports a cluster event logger logging all event types of a kind.
+func eventTypeWarnings(spec *EventLoggerSpec) admission.Warnings {
+	if len(spec.EventTypes) > 0 {
+		return nil
+	}
+	if len(spec.Kinds) > 0 && !slices.ContainsFunc(spec.Kinds, func(k Kind) bool { return len(k.EventTypes) == 0 }) {
+		return nil
+	}
+	return admission.Warnings{fmt.Sprintf("%s: no event types defined, the Normal and Warning events of all namespaces "+
+		"are logged, consider logging only Warning events", field.NewPath("spec", "eventTypes"))}
+}
@@ -19,36 +19,67 @@ package v1
 import (
 	"context"
 
+	"k8s.io/client-go/discovery"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
-// SetupWebhookWithManager setup with manager.
-func (*EventLogger) SetupWebhookWithManager(mgr ctrl.Manager) error {
+// SetupWebhookWithManager setup with manager. The default log fields are applied to event loggers without log fields.
+func (*EventLogger) SetupWebhookWithManager(mgr ctrl.Manager, defaultLogFields func() []LogField) error {
+	dc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	kd := newCachedKindDiscovery(dc, kindDiscoveryInterval)
 	return ctrl.NewWebhookManagedBy(mgr, &EventLogger{}).
-		WithValidator(&validateEl{}).
+		WithDefaulter(&defaultEl[*EventLogger]{discovery: kd, logFields: defaultLogFields}).
+		WithValidator(&validateEl[*EventLogger]{client: mgr.GetClient(), discovery: kd}).
 		Complete()
 }
 
+// SetupWebhookWithManager setup with manager. The default log fields are applied to event loggers without log fields.
+func (*ClusterEventLogger) SetupWebhookWithManager(mgr ctrl.Manager, defaultLogFields func() []LogField) error {
+	dc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	kd := newCachedKindDiscovery(dc, kindDiscoveryInterval)
+	return ctrl.NewWebhookManagedBy(mgr, &ClusterEventLogger{}).
+		WithDefaulter(&defaultEl[*ClusterEventLogger]{discovery: kd, logFields: defaultLogFields}).
+		WithValidator(&validateEl[*ClusterEventLogger]{client: mgr.GetClient(), discovery: kd}).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/mutate-eventlogger-bakito-ch-v1-eventlogger,mutating=true,failurePolicy=fail,sideEffects=None,groups=eventlogger.bakito.ch,resources=eventloggers,versions=v1,name=meventlogger.bakito.ch,admissionReviewVersions={v1,v1beta1}
+// +kubebuilder:webhook:verbs=create;update,path=/mutate-eventlogger-bakito-ch-v1-clustereventlogger,mutating=true,failurePolicy=fail,sideEffects=None,groups=eventlogger.bakito.ch,resources=clustereventloggers,versions=v1,name=mclustereventlogger.bakito.ch,admissionReviewVersions={v1,v1beta1}
 // +kubebuilder:webhook:verbs=create;update,path=/validate-eventlogger-bakito-ch-v1-eventlogger,mutating=false,failurePolicy=fail,sideEffects=None,groups=eventlogger.bakito.ch,resources=eventloggers,versions=v1,name=veventlogger.bakito.ch,admissionReviewVersions={v1,v1beta1}
+// +kubebuilder:webhook:verbs=create;update,path=/validate-eventlogger-bakito-ch-v1-clustereventlogger,mutating=false,failurePolicy=fail,sideEffects=None,groups=eventlogger.bakito.ch,resources=clustereventloggers,versions=v1,name=vclustereventlogger.bakito.ch,admissionReviewVersions={v1,v1beta1}
 
-type validateEl struct{}
+// validateEl validates EventLoggers and ClusterEventLoggers.
+type validateEl[T Object] struct {
+	// client reads the watched namespaces, the namespace warnings are skipped if nil
+	client client.Reader
+	// discovery lists the served kinds, the kind warnings are skipped if nil
+	discovery kindDiscovery
+}
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
-func (v *validateEl) ValidateCreate(_ context.Context, el *EventLogger) (warnings admission.Warnings, err error) {
-	return v.validate(el)
+func (v *validateEl[T]) ValidateCreate(ctx context.Context, el T) (warnings admission.Warnings, err error) {
+	return v.validate(ctx, el)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
-func (v *validateEl) ValidateUpdate(_ context.Context, el, _ *EventLogger) (warnings admission.Warnings, err error) {
-	return v.validate(el)
+func (v *validateEl[T]) ValidateUpdate(ctx context.Context, el, _ T) (warnings admission.Warnings, err error) {
+	return v.validate(ctx, el)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
-func (*validateEl) ValidateDelete(_ context.Context, _ *EventLogger) (warnings admission.Warnings, err error) {
+func (*validateEl[T]) ValidateDelete(_ context.Context, _ T) (warnings admission.Warnings, err error) {
 	return nil, nil
 }
 
-func (*validateEl) validate(el *EventLogger) (admission.Warnings, error) {
-	return nil, el.Spec.Validate()
+// validate returns the validation errors and the warnings of risky configurations.
+func (v *validateEl[T]) validate(ctx context.Context, el T) (admission.Warnings, error) {
+	return v.warnings(ctx, el), el.Validate()
 }
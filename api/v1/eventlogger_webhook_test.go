@@ -2,6 +2,14 @@ package v1
 
 import (
 	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -9,9 +17,9 @@ import (
 
 var _ = Describe("V1", func() {
 	var el *EventLogger
-	var val *validateEl
+	var val *validateEl[*EventLogger]
 	BeforeEach(func() {
-		val = &validateEl{}
+		val = &validateEl[*EventLogger]{}
 		el = &EventLogger{
 			Spec: EventLoggerSpec{},
 		}
@@ -65,4 +73,135 @@ var _ = Describe("V1", func() {
 			})
 		})
 	})
+	Context("Warnings", func() {
+		BeforeEach(func() {
+			val.client = fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}}).
+				Build()
+			val.discovery = servedResources{
+				"v1":      {{Name: "pods", Kind: "Pod"}, {Name: "pods/log", Kind: "Pod"}},
+				"apps/v1": {{Name: "deployments", Kind: "Deployment"}},
+			}
+		})
+		It("should have no warnings", func() {
+			el.Spec.Namespace = new("shop")
+			el.Spec.Kinds = []Kind{
+				{Name: "Pod", Reasons: []string{"BackOff"}, SkipReasons: []string{"Started"}},
+				{Name: "Deployment", APIGroup: new("apps"), MatchingPatterns: []string{"^Scaled"}},
+			}
+			w, err := val.ValidateCreate(context.TODO(), el)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(w).Should(BeEmpty())
+		})
+		It("should warn about kinds not served by the api server", func() {
+			el.Spec.Kinds = []Kind{{Name: "Pods"}, {Name: "Deployment", APIGroup: new("extensions")}}
+			w, err := val.ValidateCreate(context.TODO(), el)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(w).Should(ConsistOf(
+				"spec.kinds[0].name: kind Pods is not served by the api server, no events are logged for it",
+				`spec.kinds[1].apiGroup: kind Deployment is not served in the api group "extensions" but in ["apps"]`,
+			))
+		})
+		It("should skip the kinds if they could not be discovered", func() {
+			val.discovery = servedResources{}
+			el.Spec.Kinds = []Kind{{Name: "Pods"}}
+			Ω(val.ValidateCreate(context.TODO(), el)).Should(BeEmpty())
+		})
+		It("should warn about reasons that are also skipped", func() {
+			el.Spec.Kinds = []Kind{{Name: "Pod", Reasons: []string{"BackOff", "Started"}, SkipReasons: []string{"Started"}}}
+			w, _ := val.ValidateUpdate(context.TODO(), el, nil)
+			Ω(w).Should(ConsistOf(HavePrefix("spec.kinds[0].skipReasons: the reasons Started are also skipped")))
+		})
+		It("should warn about patterns matching every message", func() {
+			el.Spec.Kinds = []Kind{
+				{Name: "Pod", MatchingPatterns: []string{"^Back-off", ".*"}},
+				{Name: "Deployment", MatchingPatterns: []string{"x*"}, SkipOnMatch: new(true)},
+			}
+			w, _ := val.ValidateCreate(context.TODO(), el)
+			Ω(w).Should(ConsistOf(
+				`spec.kinds[0].matchingPatterns[1]: ".*" matches every message, the pattern has no effect`,
+				`spec.kinds[1].matchingPatterns[0]: "x*" matches every message, no events of the kind are logged`,
+			))
+		})
+		It("should warn about namespaces that do not exist", func() {
+			el.Spec.Namespace = new("checkout")
+			w, _ := val.ValidateCreate(context.TODO(), el)
+			Ω(w).Should(ConsistOf(
+				"spec.namespace: namespace checkout does not exist, its events are logged once it is created",
+			))
+			el.Spec.Namespace = nil
+			el.Spec.Namespaces = []string{"shop", "checkout"}
+			w, _ = val.ValidateCreate(context.TODO(), el)
+			Ω(w).Should(ConsistOf(
+				"spec.namespaces[1]: namespace checkout does not exist, its events are logged once it is created",
+			))
+		})
+		It("should warn about deprecated log field paths", func() {
+			el.Spec.LogFields = []LogField{
+				{Name: "kind", JSONPath: ".involvedObject.kind"},
+				{Name: "name", Path: []string{"InvolvedObject", "Name"}},
+			}
+			w, _ := val.ValidateCreate(context.TODO(), el)
+			Ω(w).Should(ConsistOf(
+				"spec.logFields[1].path: path is deprecated, use jsonPath .involvedObject.name",
+			))
+		})
+		It("should warn about a cluster event logger without event types", func() {
+			cval := &validateEl[*ClusterEventLogger]{}
+			cel := &ClusterEventLogger{Spec: ClusterEventLoggerSpec{EventLoggerSpec: EventLoggerSpec{
+				Kinds: []Kind{{Name: "Pod", EventTypes: []string{"Warning"}}, {Name: "Node"}},
+			}}}
+			w, err := cval.ValidateCreate(context.TODO(), cel)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(w).Should(HaveLen(1))
+			Ω(w[0]).Should(HavePrefix("spec.eventTypes: no event types defined"))
+
+			cel.Spec.Kinds[1].EventTypes = []string{"Warning"}
+			Ω(cval.ValidateCreate(context.TODO(), cel)).Should(BeEmpty())
+			cel.Spec.Kinds = nil
+			cel.Spec.EventTypes = []string{"Warning"}
+			Ω(cval.ValidateCreate(context.TODO(), cel)).Should(BeEmpty())
+		})
+	})
+	Context("cachedKindDiscovery", func() {
+		var fd *fakediscovery.FakeDiscovery
+		BeforeEach(func() {
+			fd = &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods", Kind: "Pod"}}},
+			}}}
+		})
+		It("should serve the resources from the cache", func() {
+			kd := newCachedKindDiscovery(fd, time.Hour)
+			Ω(servedKinds(kd)).Should(Equal(map[string][]string{"Pod": {""}}))
+			calls := len(fd.Actions())
+			Ω(calls).ShouldNot(BeZero())
+
+			fd.Resources = []*metav1.APIResourceList{
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "nodes", Kind: "Node"}}},
+			}
+			Ω(servedKinds(kd)).Should(Equal(map[string][]string{"Pod": {""}}))
+			Ω(fd.Actions()).Should(HaveLen(calls))
+		})
+		It("should discover the resources again after the interval", func() {
+			kd := newCachedKindDiscovery(fd, 0)
+			Ω(servedKinds(kd)).Should(HaveKey("Pod"))
+
+			fd.Resources = []*metav1.APIResourceList{
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "nodes", Kind: "Node"}}},
+			}
+			Ω(servedKinds(kd)).Should(Equal(map[string][]string{"Node": {""}}))
+		})
+	})
 })
+
+// servedResources the served resources by group version.
+type servedResources map[string][]metav1.APIResource
+
+func (s servedResources) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	var lists []*metav1.APIResourceList
+	for gv, resources := range s {
+		lists = append(lists, &metav1.APIResourceList{GroupVersion: gv, APIResources: resources})
+	}
+	return lists, nil
+}
@@ -5,9 +5,13 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/expression"
 )
 
 func TestV1(t *testing.T) {
 	RegisterFailHandler(Fail)
+	apiv1.RegisterExpressionParser(expression.Parser{})
 	RunSpecs(t, "V1 Suite")
 }
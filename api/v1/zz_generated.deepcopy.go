@@ -22,9 +22,126 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Checkpoint) DeepCopyInto(out *Checkpoint) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Checkpoint.
+func (in *Checkpoint) DeepCopy() *Checkpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(Checkpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEventLogger) DeepCopyInto(out *ClusterEventLogger) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEventLogger.
+func (in *ClusterEventLogger) DeepCopy() *ClusterEventLogger {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEventLogger)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterEventLogger) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEventLoggerList) DeepCopyInto(out *ClusterEventLoggerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterEventLogger, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEventLoggerList.
+func (in *ClusterEventLoggerList) DeepCopy() *ClusterEventLoggerList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEventLoggerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterEventLoggerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEventLoggerSpec) DeepCopyInto(out *ClusterEventLoggerSpec) {
+	*out = *in
+	in.EventLoggerSpec.DeepCopyInto(&out.EventLoggerSpec)
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEventLoggerSpec.
+func (in *ClusterEventLoggerSpec) DeepCopy() *ClusterEventLoggerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEventLoggerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Deduplication) DeepCopyInto(out *Deduplication) {
+	*out = *in
+	out.Window = in.Window
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Deduplication.
+func (in *Deduplication) DeepCopy() *Deduplication {
+	if in == nil {
+		return nil
+	}
+	out := new(Deduplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EventLogger) DeepCopyInto(out *EventLogger) {
 	*out = *in
@@ -123,6 +240,16 @@ func (in *EventLoggerSpec) DeepCopyInto(out *EventLoggerSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ImagePullSecrets != nil {
 		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
 		*out = make([]corev1.LocalObjectReference, len(*in))
@@ -135,6 +262,16 @@ func (in *EventLoggerSpec) DeepCopyInto(out *EventLoggerSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(corev1.PodTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.LogFields != nil {
 		in, out := &in.LogFields, &out.LogFields
 		*out = make([]LogField, len(*in))
@@ -142,6 +279,28 @@ func (in *EventLoggerSpec) DeepCopyInto(out *EventLoggerSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Sinks != nil {
+		in, out := &in.Sinks, &out.Sinks
+		*out = make([]Sink, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Deduplication != nil {
+		in, out := &in.Deduplication, &out.Deduplication
+		*out = new(Deduplication)
+		**out = **in
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(RateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Checkpoint != nil {
+		in, out := &in.Checkpoint, &out.Checkpoint
+		*out = new(Checkpoint)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventLoggerSpec.
@@ -158,6 +317,22 @@ func (in *EventLoggerSpec) DeepCopy() *EventLoggerSpec {
 func (in *EventLoggerStatus) DeepCopyInto(out *EventLoggerStatus) {
 	*out = *in
 	in.LastProcessed.DeepCopyInto(&out.LastProcessed)
+	if in.LastFilterApplied != nil {
+		in, out := &in.LastFilterApplied, &out.LastFilterApplied
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRollout != nil {
+		in, out := &in.LastRollout, &out.LastRollout
+		*out = new(Rollout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventLoggerStatus.
@@ -170,6 +345,21 @@ func (in *EventLoggerStatus) DeepCopy() *EventLoggerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileSink) DeepCopyInto(out *FileSink) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileSink.
+func (in *FileSink) DeepCopy() *FileSink {
+	if in == nil {
+		return nil
+	}
+	out := new(FileSink)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Kind) DeepCopyInto(out *Kind) {
 	*out = *in
@@ -203,6 +393,26 @@ func (in *Kind) DeepCopyInto(out *Kind) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.InvolvedObjectNames != nil {
+		in, out := &in.InvolvedObjectNames, &out.InvolvedObjectNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InvolvedObjectNamespaces != nil {
+		in, out := &in.InvolvedObjectNamespaces, &out.InvolvedObjectNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Notification != nil {
+		in, out := &in.Notification, &out.Notification
+		*out = new(Notification)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Kind.
@@ -215,6 +425,31 @@ func (in *Kind) DeepCopy() *Kind {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Limit) DeepCopyInto(out *Limit) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Burst != nil {
+		in, out := &in.Burst, &out.Burst
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Limit.
+func (in *Limit) DeepCopy() *Limit {
+	if in == nil {
+		return nil
+	}
+	out := new(Limit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LogField) DeepCopyInto(out *LogField) {
 	*out = *in
@@ -239,3 +474,140 @@ func (in *LogField) DeepCopy() *LogField {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Notification) DeepCopyInto(out *Notification) {
+	*out = *in
+	in.WebhookSecretRef.DeepCopyInto(&out.WebhookSecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Notification.
+func (in *Notification) DeepCopy() *Notification {
+	if in == nil {
+		return nil
+	}
+	out := new(Notification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimit) DeepCopyInto(out *RateLimit) {
+	*out = *in
+	if in.PerKey != nil {
+		in, out := &in.PerKey, &out.PerKey
+		*out = new(Limit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Global != nil {
+		in, out := &in.Global, &out.Global
+		*out = new(Limit)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimit.
+func (in *RateLimit) DeepCopy() *RateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rollout) DeepCopyInto(out *Rollout) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.ChangedFields != nil {
+		in, out := &in.ChangedFields, &out.ChangedFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rollout.
+func (in *Rollout) DeepCopy() *Rollout {
+	if in == nil {
+		return nil
+	}
+	out := new(Rollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Sink) DeepCopyInto(out *Sink) {
+	*out = *in
+	if in.File != nil {
+		in, out := &in.File, &out.File
+		*out = new(FileSink)
+		**out = **in
+	}
+	if in.Syslog != nil {
+		in, out := &in.Syslog, &out.Syslog
+		*out = new(SyslogSink)
+		**out = **in
+	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(WebhookSink)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Sink.
+func (in *Sink) DeepCopy() *Sink {
+	if in == nil {
+		return nil
+	}
+	out := new(Sink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyslogSink) DeepCopyInto(out *SyslogSink) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyslogSink.
+func (in *SyslogSink) DeepCopy() *SyslogSink {
+	if in == nil {
+		return nil
+	}
+	out := new(SyslogSink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookSink) DeepCopyInto(out *WebhookSink) {
+	*out = *in
+	if in.HeadersSecretRef != nil {
+		in, out := &in.HeadersSecretRef, &out.HeadersSecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.FlushInterval != nil {
+		in, out := &in.FlushInterval, &out.FlushInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookSink.
+func (in *WebhookSink) DeepCopy() *WebhookSink {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookSink)
+	in.DeepCopyInto(out)
+	return out
+}
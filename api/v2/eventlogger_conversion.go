@@ -0,0 +1,287 @@
+package v2
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+)
+
+// AnnotationV1LogFieldPaths the annotation preserving the deprecated v1 log field paths of go field names. They are
+// converted to a jsonPath and restored when converting back, as long as the jsonPath was not changed.
+const AnnotationV1LogFieldPaths = "eventlogger.bakito.ch/v1-log-field-paths"
+
+// AnnotationV1Namespace the annotation preserving the namespace of the v1 spec. It is converted to the first element
+// of the namespaces and restored when converting back, as long as the first element was not changed.
+const AnnotationV1Namespace = "eventlogger.bakito.ch/v1-namespace"
+
+// AnnotationV1SkipOnMatch the annotation preserving the skipOnMatch values of the v1 kinds that v2 can not express:
+// false and true without matching patterns. They are restored when converting back, as long as the kind has no
+// exclude message patterns and a restored true has no matching patterns.
+const AnnotationV1SkipOnMatch = "eventlogger.bakito.ch/v1-skip-on-match"
+
+var _ conversion.Convertible = &EventLogger{}
+
+// ConvertTo converts the EventLogger to the v1 hub.
+func (in *EventLogger) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1.EventLogger)
+	in.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	in.Status.DeepCopyInto(&dst.Status)
+
+	s := in.Spec.DeepCopy()
+	dst.Spec = v1.EventLoggerSpec{
+		EventTypes:        eventTypesToV1(s.Filter.EventTypes),
+		Expression:        s.Filter.Expression,
+		Namespaces:        s.Namespaces,
+		NamespaceSelector: s.NamespaceSelector,
+		Sinks:             s.Sinks,
+		Deduplication:     s.Deduplication,
+		RateLimit:         s.RateLimit,
+		EventAPI:          s.EventAPI,
+		Checkpoint:        s.Checkpoint,
+		Labels:            s.Labels,
+		Annotations:       s.Annotations,
+		ScrapeMetrics:     s.ScrapeMetrics,
+		ServiceAccount:    s.ServiceAccount,
+		ImagePullSecrets:  s.ImagePullSecrets,
+		NodeSelector:      s.NodeSelector,
+		Replicas:          s.Replicas,
+		PodTemplate:       s.PodTemplate,
+	}
+	v1Namespace, hasV1Namespace := popAnnotation(&dst.ObjectMeta, AnnotationV1Namespace)
+	switch {
+	case s.AllNamespaces:
+		dst.Spec.Namespace = new("")
+	case hasV1Namespace && len(s.Namespaces) > 0 && s.Namespaces[0] == v1Namespace:
+		dst.Spec.Namespace = &v1Namespace
+		dst.Spec.Namespaces = s.Namespaces[1:]
+		if len(dst.Spec.Namespaces) == 0 {
+			dst.Spec.Namespaces = nil
+		}
+	}
+	var skipOnMatch []*bool
+	if a, ok := popAnnotation(&dst.ObjectMeta, AnnotationV1SkipOnMatch); ok {
+		_ = json.Unmarshal([]byte(a), &skipOnMatch)
+	}
+	for i, k := range s.Filter.Kinds {
+		kind := k.toV1()
+		if i < len(skipOnMatch) && skipOnMatch[i] != nil && kind.SkipOnMatch == nil &&
+			(!*skipOnMatch[i] || len(kind.MatchingPatterns) == 0) {
+			kind.SkipOnMatch = skipOnMatch[i]
+		}
+		dst.Spec.Kinds = append(dst.Spec.Kinds, kind)
+	}
+	var v1Paths [][]string
+	if a, ok := popAnnotation(&dst.ObjectMeta, AnnotationV1LogFieldPaths); ok {
+		_ = json.Unmarshal([]byte(a), &v1Paths)
+	}
+	for i, lf := range s.LogFields {
+		f := v1.LogField{
+			Name:     lf.Name,
+			JSONPath: lf.JSONPath,
+			Template: lf.Template,
+			Value:    lf.Value,
+		}
+		if i < len(v1Paths) && len(v1Paths[i]) > 0 && v1.JSONPathOf(v1Paths[i]) == lf.JSONPath {
+			f.Path = v1Paths[i]
+			f.JSONPath = ""
+		}
+		dst.Spec.LogFields = append(dst.Spec.LogFields, f)
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1 hub to this version. A namespace of the v1 spec is prepended to the namespaces list and
+// preserved in the AnnotationV1Namespace annotation, the other fields are converted without loss. The deprecated log
+// field paths are converted to jsonPaths and preserved in the AnnotationV1LogFieldPaths annotation, the skipOnMatch
+// values v2 can not express in the AnnotationV1SkipOnMatch annotation.
+func (in *EventLogger) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1.EventLogger)
+	src.ObjectMeta.DeepCopyInto(&in.ObjectMeta)
+	src.Status.DeepCopyInto(&in.Status)
+
+	s := src.Spec.DeepCopy()
+	in.Spec = EventLoggerSpec{
+		Filter: Filter{
+			EventTypes: eventTypesFromV1(s.EventTypes),
+			Expression: s.Expression,
+		},
+		Namespaces:        s.Namespaces,
+		NamespaceSelector: s.NamespaceSelector,
+		Sinks:             s.Sinks,
+		Deduplication:     s.Deduplication,
+		RateLimit:         s.RateLimit,
+		EventAPI:          s.EventAPI,
+		Checkpoint:        s.Checkpoint,
+		Labels:            s.Labels,
+		Annotations:       s.Annotations,
+		ScrapeMetrics:     s.ScrapeMetrics,
+		ServiceAccount:    s.ServiceAccount,
+		ImagePullSecrets:  s.ImagePullSecrets,
+		NodeSelector:      s.NodeSelector,
+		Replicas:          s.Replicas,
+		PodTemplate:       s.PodTemplate,
+	}
+	delete(in.Annotations, AnnotationV1Namespace)
+	if s.Namespace != nil {
+		if *s.Namespace == "" {
+			in.Spec.AllNamespaces = true
+		} else {
+			in.Spec.Namespaces = append([]string{*s.Namespace}, in.Spec.Namespaces...)
+			setAnnotation(&in.ObjectMeta, AnnotationV1Namespace, *s.Namespace)
+		}
+	}
+	var skipOnMatch []*bool
+	for i, k := range s.Kinds {
+		in.Spec.Filter.Kinds = append(in.Spec.Filter.Kinds, kindFromV1(k))
+		if k.SkipOnMatch != nil && (!*k.SkipOnMatch || len(k.MatchingPatterns) == 0) {
+			skipOnMatch = append(skipOnMatch, make([]*bool, i+1-len(skipOnMatch))...)
+			skipOnMatch[i] = k.SkipOnMatch
+		}
+	}
+	delete(in.Annotations, AnnotationV1SkipOnMatch)
+	if skipOnMatch != nil {
+		a, err := json.Marshal(skipOnMatch)
+		if err != nil {
+			return err
+		}
+		setAnnotation(&in.ObjectMeta, AnnotationV1SkipOnMatch, string(a))
+	}
+	var v1Paths [][]string
+	for i, lf := range s.LogFields {
+		f := LogField{
+			Name:     lf.Name,
+			JSONPath: lf.JSONPath,
+			Template: lf.Template,
+			Value:    lf.Value,
+		}
+		if len(lf.Path) > 0 {
+			f.JSONPath = v1.JSONPathOf(lf.Path)
+			v1Paths = append(v1Paths, make([][]string, i+1-len(v1Paths))...)
+			v1Paths[i] = lf.Path
+		}
+		in.Spec.LogFields = append(in.Spec.LogFields, f)
+	}
+	delete(in.Annotations, AnnotationV1LogFieldPaths)
+	if v1Paths != nil {
+		a, err := json.Marshal(v1Paths)
+		if err != nil {
+			return err
+		}
+		setAnnotation(&in.ObjectMeta, AnnotationV1LogFieldPaths, string(a))
+	}
+	return nil
+}
+
+// popAnnotation removes the annotation and returns its value, the annotations are set to nil if none is left.
+func popAnnotation(meta *metav1.ObjectMeta, key string) (string, bool) {
+	value, ok := meta.Annotations[key]
+	if !ok {
+		return "", false
+	}
+	delete(meta.Annotations, key)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	return value, true
+}
+
+func setAnnotation(meta *metav1.ObjectMeta, key, value string) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[key] = value
+}
+
+func (in *Kind) toV1() v1.Kind {
+	k := v1.Kind{
+		Name:         in.Name,
+		APIGroup:     in.APIGroup,
+		EventTypes:   eventTypesToV1(in.EventTypes),
+		Expression:   in.Expression,
+		Notification: in.Notification,
+	}
+	if in.Include != nil {
+		k.Reasons = in.Include.Reasons
+		k.MatchingPatterns = in.Include.MessagePatterns
+	}
+	if in.Exclude != nil {
+		k.SkipReasons = in.Exclude.Reasons
+		if len(in.Exclude.MessagePatterns) > 0 {
+			k.MatchingPatterns = in.Exclude.MessagePatterns
+			k.SkipOnMatch = new(true)
+		}
+	}
+	if in.InvolvedObject != nil {
+		k.InvolvedObjectNames = in.InvolvedObject.Names
+		k.InvolvedObjectNamespaces = in.InvolvedObject.Namespaces
+		k.LabelSelector = in.InvolvedObject.LabelSelector
+	}
+	return k
+}
+
+// kindFromV1 converts a v1 kind, the matching patterns are moved to the exclude block if skipOnMatch is true.
+func kindFromV1(k v1.Kind) Kind {
+	kind := Kind{
+		Name:       k.Name,
+		APIGroup:   k.APIGroup,
+		EventTypes: eventTypesFromV1(k.EventTypes),
+		Include:    &Match{Reasons: k.Reasons},
+		Exclude:    &Match{Reasons: k.SkipReasons},
+		InvolvedObject: &InvolvedObject{
+			Names:         k.InvolvedObjectNames,
+			Namespaces:    k.InvolvedObjectNamespaces,
+			LabelSelector: k.LabelSelector,
+		},
+		Expression:   k.Expression,
+		Notification: k.Notification,
+	}
+	if k.SkipOnMatch != nil && *k.SkipOnMatch {
+		kind.Exclude.MessagePatterns = k.MatchingPatterns
+	} else {
+		kind.Include.MessagePatterns = k.MatchingPatterns
+	}
+	// empty blocks are omitted
+	if kind.Include.empty() {
+		kind.Include = nil
+	}
+	if kind.Exclude.empty() {
+		kind.Exclude = nil
+	}
+	if kind.InvolvedObject.empty() {
+		kind.InvolvedObject = nil
+	}
+	return kind
+}
+
+func (in *Match) empty() bool {
+	return len(in.Reasons) == 0 && len(in.MessagePatterns) == 0
+}
+
+func (in *InvolvedObject) empty() bool {
+	return len(in.Names) == 0 && len(in.Namespaces) == 0 && in.LabelSelector == nil
+}
+
+func eventTypesToV1(types []EventType) []string {
+	if types == nil {
+		return nil
+	}
+	v1Types := make([]string, len(types))
+	for i, t := range types {
+		v1Types[i] = string(t)
+	}
+	return v1Types
+}
+
+func eventTypesFromV1(types []string) []EventType {
+	if types == nil {
+		return nil
+	}
+	v2Types := make([]EventType, len(types))
+	for i, t := range types {
+		v2Types[i] = EventType(t)
+	}
+	return v2Types
+}
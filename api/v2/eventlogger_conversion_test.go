@@ -0,0 +1,245 @@
+package v2_test
+
+import (
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+	"sigs.k8s.io/randfill"
+
+	v1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	v2 "github.com/bakito/k8s-event-logger-operator/api/v2"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var v1Paths = [][]string{
+	nil,
+	{"Reason"},
+	{"InvolvedObject", "Name"},
+	{"ObjectMeta", "Namespace"},
+	{"TypeMeta", "Kind"},
+	{"Source", "Component"},
+	{"Series", "LastObservedTime"},
+	{"Related"},
+	{"Unknown", "Name"},
+}
+
+var _ = Describe("Conversion", func() {
+	Context("round trip", func() {
+		var f *randfill.Filler
+		BeforeEach(func() {
+			f = fuzzer()
+		})
+		It("should convert v1 to v2 and back without loss", func() {
+			for range 500 {
+				hub := &v1.EventLogger{}
+				f.Fill(hub)
+
+				spoke := &v2.EventLogger{}
+				Ω(spoke.ConvertFrom(hub)).ShouldNot(HaveOccurred())
+				converted := &v1.EventLogger{}
+				Ω(spoke.ConvertTo(converted)).ShouldNot(HaveOccurred())
+				Ω(apiequality.Semantic.DeepEqual(hub, converted)).Should(BeTrue(), cmp.Diff(hub, converted))
+			}
+		})
+		It("should convert v2 to v1 and back without loss", func() {
+			for range 500 {
+				spoke := &v2.EventLogger{}
+				f.Fill(spoke)
+
+				hub := &v1.EventLogger{}
+				Ω(spoke.ConvertTo(hub)).ShouldNot(HaveOccurred())
+				converted := &v2.EventLogger{}
+				Ω(converted.ConvertFrom(hub)).ShouldNot(HaveOccurred())
+				Ω(apiequality.Semantic.DeepEqual(spoke, converted)).Should(BeTrue(), cmp.Diff(spoke, converted))
+			}
+		})
+	})
+
+	Context("ConvertFrom", func() {
+		It("should convert the namespace", func() {
+			spoke := &v2.EventLogger{}
+			Ω(spoke.ConvertFrom(&v1.EventLogger{Spec: v1.EventLoggerSpec{Namespace: new("")}})).ShouldNot(HaveOccurred())
+			Ω(spoke.Spec.AllNamespaces).Should(BeTrue())
+
+			Ω(spoke.ConvertFrom(&v1.EventLogger{Spec: v1.EventLoggerSpec{Namespace: new("a")}})).ShouldNot(HaveOccurred())
+			Ω(spoke.Spec.AllNamespaces).Should(BeFalse())
+			Ω(spoke.Spec.Namespaces).Should(Equal([]string{"a"}))
+			Ω(spoke.Annotations).Should(HaveKeyWithValue(v2.AnnotationV1Namespace, "a"))
+		})
+		It("should convert the matching patterns to include or exclude blocks", func() {
+			spoke := &v2.EventLogger{}
+			Ω(spoke.ConvertFrom(&v1.EventLogger{Spec: v1.EventLoggerSpec{Kinds: []v1.Kind{
+				{Name: "Pod", Reasons: []string{"a"}, MatchingPatterns: []string{"b"}},
+				{Name: "Pod", SkipReasons: []string{"a"}, MatchingPatterns: []string{"b"}, SkipOnMatch: new(true)},
+				{Name: "Pod", SkipOnMatch: new(true)},
+			}}})).ShouldNot(HaveOccurred())
+			Ω(spoke.Spec.Filter.Kinds).Should(Equal([]v2.Kind{
+				{Name: "Pod", Include: &v2.Match{Reasons: []string{"a"}, MessagePatterns: []string{"b"}}},
+				{Name: "Pod", Exclude: &v2.Match{Reasons: []string{"a"}, MessagePatterns: []string{"b"}}},
+				{Name: "Pod"},
+			}))
+		})
+		It("should convert the go field names of the path to a json path", func() {
+			spoke := &v2.EventLogger{}
+			var logFields []v1.LogField
+			for _, p := range v1Paths {
+				logFields = append(logFields, v1.LogField{Path: p})
+			}
+			logFields = append(logFields, v1.LogField{JSONPath: ".message"})
+			Ω(spoke.ConvertFrom(&v1.EventLogger{Spec: v1.EventLoggerSpec{LogFields: logFields}})).ShouldNot(HaveOccurred())
+			Ω(spoke.Spec.LogFields).Should(Equal([]v2.LogField{
+				{},
+				{JSONPath: ".reason"},
+				{JSONPath: ".involvedObject.name"},
+				{JSONPath: ".metadata.namespace"},
+				{JSONPath: ".kind"},
+				{JSONPath: ".source.component"},
+				{JSONPath: ".series.lastObservedTime"},
+				{JSONPath: ".related"},
+				{JSONPath: ".Unknown.Name"},
+				{JSONPath: ".message"},
+			}))
+			Ω(spoke.Annotations).Should(HaveKeyWithValue(v2.AnnotationV1LogFieldPaths,
+				`[null,["Reason"],["InvolvedObject","Name"],["ObjectMeta","Namespace"],["TypeMeta","Kind"],`+
+					`["Source","Component"],["Series","LastObservedTime"],["Related"],["Unknown","Name"]]`))
+		})
+		It("should not annotate log fields without path", func() {
+			spoke := &v2.EventLogger{}
+			Ω(spoke.ConvertFrom(&v1.EventLogger{Spec: v1.EventLoggerSpec{LogFields: []v1.LogField{
+				{Name: "name", JSONPath: ".involvedObject.name"},
+				{Name: "static", Value: new("a")},
+			}}})).ShouldNot(HaveOccurred())
+			Ω(spoke.Annotations).ShouldNot(HaveKey(v2.AnnotationV1LogFieldPaths))
+		})
+	})
+
+	Context("ConvertTo", func() {
+		It("should convert all namespaces to an empty namespace", func() {
+			hub := &v1.EventLogger{}
+			Ω((&v2.EventLogger{Spec: v2.EventLoggerSpec{AllNamespaces: true}}).ConvertTo(hub)).ShouldNot(HaveOccurred())
+			Ω(hub.Spec.Namespace).Should(Equal(new("")))
+		})
+		It("should restore the namespace if the first namespace was not changed", func() {
+			hub := &v1.EventLogger{}
+			Ω((&v2.EventLogger{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v2.AnnotationV1Namespace: "a"}},
+				Spec:       v2.EventLoggerSpec{Namespaces: []string{"a"}},
+			}).ConvertTo(hub)).ShouldNot(HaveOccurred())
+			Ω(hub.Spec.Namespace).Should(Equal(new("a")))
+			Ω(hub.Spec.Namespaces).Should(BeNil())
+			Ω(hub.Annotations).Should(BeNil())
+
+			hub = &v1.EventLogger{}
+			Ω((&v2.EventLogger{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v2.AnnotationV1Namespace: "a"}},
+				Spec:       v2.EventLoggerSpec{Namespaces: []string{"b", "a"}},
+			}).ConvertTo(hub)).ShouldNot(HaveOccurred())
+			Ω(hub.Spec.Namespace).Should(BeNil())
+			Ω(hub.Spec.Namespaces).Should(Equal([]string{"b", "a"}))
+			Ω(hub.Annotations).Should(BeNil())
+		})
+		It("should skip on match for exclude message patterns", func() {
+			hub := &v1.EventLogger{}
+			Ω((&v2.EventLogger{Spec: v2.EventLoggerSpec{Filter: v2.Filter{Kinds: []v2.Kind{{
+				Name:    "Pod",
+				Include: &v2.Match{Reasons: []string{"a"}},
+				Exclude: &v2.Match{MessagePatterns: []string{"b"}},
+			}}}}}).ConvertTo(hub)).ShouldNot(HaveOccurred())
+			Ω(hub.Spec.Kinds).Should(Equal([]v1.Kind{
+				{Name: "Pod", Reasons: []string{"a"}, MatchingPatterns: []string{"b"}, SkipOnMatch: new(true)},
+			}))
+		})
+		It("should restore the skipOnMatch values v2 can not express", func() {
+			hub := &v1.EventLogger{}
+			Ω((&v2.EventLogger{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+					v2.AnnotationV1SkipOnMatch: `[false,true,true]`,
+				}},
+				Spec: v2.EventLoggerSpec{Filter: v2.Filter{Kinds: []v2.Kind{
+					{Name: "Pod", Include: &v2.Match{MessagePatterns: []string{"a"}}},
+					{Name: "Pod"},
+					{Name: "Pod", Include: &v2.Match{MessagePatterns: []string{"b"}}},
+				}}},
+			}).ConvertTo(hub)).ShouldNot(HaveOccurred())
+			Ω(hub.Spec.Kinds).Should(Equal([]v1.Kind{
+				{Name: "Pod", MatchingPatterns: []string{"a"}, SkipOnMatch: new(false)},
+				{Name: "Pod", SkipOnMatch: new(true)},
+				{Name: "Pod", MatchingPatterns: []string{"b"}},
+			}))
+			Ω(hub.Annotations).Should(BeNil())
+		})
+		It("should restore the paths of unchanged json paths", func() {
+			hub := &v1.EventLogger{}
+			Ω((&v2.EventLogger{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+					v2.AnnotationV1LogFieldPaths: `[["Reason"],null,["InvolvedObject","Name"]]`,
+				}},
+				Spec: v2.EventLoggerSpec{LogFields: []v2.LogField{
+					{Name: "reason", JSONPath: ".reason"},
+					{Name: "message", JSONPath: ".message"},
+					{Name: "name", JSONPath: ".involvedObject.namespace"},
+				}},
+			}).ConvertTo(hub)).ShouldNot(HaveOccurred())
+			Ω(hub.Spec.LogFields).Should(Equal([]v1.LogField{
+				{Name: "reason", Path: []string{"Reason"}},
+				{Name: "message", JSONPath: ".message"},
+				{Name: "name", JSONPath: ".involvedObject.namespace"},
+			}))
+			Ω(hub.Annotations).Should(BeNil())
+		})
+	})
+
+	It("should be convertible", func() {
+		s := runtime.NewScheme()
+		Ω(v1.AddToScheme(s)).ShouldNot(HaveOccurred())
+		Ω(v2.AddToScheme(s)).ShouldNot(HaveOccurred())
+		Ω(conversion.IsConvertible(s, &v1.EventLogger{})).Should(BeTrue())
+	})
+})
+
+// fuzzer fills the event loggers with random values. In v2, only one block of a kind has message patterns. Log fields
+// with a path have no json path.
+func fuzzer() *randfill.Filler {
+	return randfill.NewWithSeed(GinkgoRandomSeed()).NilChance(0.2).NumElements(0, 3).Funcs(
+		func(in *v1.EventLogger, c randfill.Continue) {
+			c.FillNoCustom(in)
+			in.TypeMeta = metav1.TypeMeta{}
+		},
+		func(in *v2.EventLogger, c randfill.Continue) {
+			c.FillNoCustom(in)
+			in.TypeMeta = metav1.TypeMeta{}
+		},
+		func(in *v2.Kind, c randfill.Continue) {
+			c.FillNoCustom(in)
+			if in.Include != nil && in.Exclude != nil {
+				in.Exclude.MessagePatterns = nil
+			}
+			if in.Include != nil && len(in.Include.Reasons) == 0 && len(in.Include.MessagePatterns) == 0 {
+				in.Include = nil
+			}
+			if in.Exclude != nil && len(in.Exclude.Reasons) == 0 && len(in.Exclude.MessagePatterns) == 0 {
+				in.Exclude = nil
+			}
+			if io := in.InvolvedObject; io != nil && len(io.Names) == 0 && len(io.Namespaces) == 0 &&
+				io.LabelSelector == nil {
+				in.InvolvedObject = nil
+			}
+		},
+		func(in *v1.LogField, c randfill.Continue) {
+			c.FillNoCustom(in)
+			in.Path = v1Paths[c.Intn(len(v1Paths))]
+			if len(in.Path) > 0 {
+				in.JSONPath = ""
+			}
+		},
+		func(in *corev1.PodTemplateSpec, c randfill.Continue) {
+			c.Fill(&in.Labels)
+			c.Fill(&in.Spec.PriorityClassName)
+		},
+	)
+}
@@ -0,0 +1,254 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+)
+
+// EventLoggerSpec defines the desired state of EventLogger.
+// +kubebuilder:validation:XValidation:rule="!(has(self.allNamespaces) && self.allNamespaces && (has(self.namespaces) || has(self.namespaceSelector)))",message="allNamespaces can not be combined with namespaces or namespaceSelector"
+type EventLoggerSpec struct {
+	// Filter selects the events to log
+	// +optional
+	Filter Filter `json:"filter,omitempty"`
+
+	// AllNamespaces if true, the events of all namespaces are watched. If neither allNamespaces, namespaces nor
+	// namespaceSelector is set, the namespace of the EventLogger is watched
+	// +optional
+	AllNamespaces bool `json:"allNamespaces,omitempty"`
+
+	// Namespaces the namespaces to watch on. Can be combined with NamespaceSelector, the logger pod watches all
+	// selected namespaces. Namespaces that do not exist are ignored
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects the namespaces to watch on by their labels. The selected namespaces are updated
+	// when namespaces are created, deleted or relabelled; the logger pod is recreated when the selection changes
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// LogFields fields of the event to be logged.
+	// +optional
+	LogFields []LogField `json:"logFields,omitempty"`
+
+	// Sinks the outputs the matched events are sent to. If empty, the events are logged by the logger pod.
+	// +optional
+	Sinks []v1.Sink `json:"sinks,omitempty"`
+
+	// Deduplication optional deduplication of repeated events
+	// +optional
+	Deduplication *v1.Deduplication `json:"deduplication,omitempty"`
+
+	// RateLimit optional rate limits of the logged events
+	// +optional
+	RateLimit *v1.RateLimit `json:"rateLimit,omitempty"`
+
+	// EventAPI the api version of the events to watch. The events of the events.k8s.io api are normalized into
+	// the shape of core events, filters and log fields work on both alike. Default v1
+	// +optional
+	EventAPI v1.EventAPI `json:"eventAPI,omitempty"`
+
+	// Checkpoint optional persistent checkpoint of the last processed event. The logger stores the resource version
+	// of the last processed event periodically in a config map, after a restart it replays the events newer than the
+	// checkpoint that still exist
+	// +optional
+	Checkpoint *v1.Checkpoint `json:"checkpoint,omitempty"`
+
+	// Labels additional labels for the logger pod
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations additional annotations for the logger pod
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// ScrapeMetrics if true, prometheus scrape annotations are added to the pod
+	// +optional
+	ScrapeMetrics *bool `json:"scrapeMetrics,omitempty"`
+
+	// ServiceAccount the service account to use for the logger pod
+	// +optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+
+	// ImagePullSecrets is an optional list of references to secrets in the same namespace to use for pulling any of the images used by this EventLoggerSpec.
+	// If specified, these secrets will be passed to individual puller implementations for them to use.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// NodeSelector is a selector that must be true for the pod to fit on a node.
+	// Selector which must match a node's labels for the pod to be scheduled on that node.
+	// More info: https://kubernetes.io/docs/concepts/configuration/assign-pod-node/
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Replicas the number of logger pods. With more than one replica the pods elect a leader that logs the events,
+	// and a PodDisruptionBudget keeps one logger pod available. Default 1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// PodTemplate an optional pod template strategically merged on top of the pod of the logger, which is based on the
+	// container template of the operator config. Containers are merged by name, the logger container is named
+	// event-logger; e.g. tolerations, affinity, priorityClassName or the resources of the logger can be overridden.
+	// The command, args and env of the operator as well as the service account can not be overridden.
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	PodTemplate *corev1.PodTemplateSpec `json:"podTemplate,omitempty"`
+}
+
+// Filter selects the events to log. An event is logged if it matches the event types, one of the kinds and the
+// expression.
+type Filter struct {
+	// EventTypes the event types to log, Normal or Warning. If empty all events are logged.
+	// +optional
+	EventTypes []EventType `json:"eventTypes,omitempty"`
+
+	// Kinds the kinds to log the events for. If empty the events of all kinds are logged.
+	// +optional
+	Kinds []Kind `json:"kinds,omitempty"`
+
+	// Expression an optional CEL expression all logged events must match. The event is available as variable event
+	// with the field names of its json representation e.g. event.count > 5 && event.reason.startsWith("Failed")
+	// +optional
+	Expression string `json:"expression,omitempty"`
+}
+
+// EventType the type of event.
+// +kubebuilder:validation:Enum=Normal;Warning
+type EventType string
+
+// Kind defines a kind to log events for.
+// +kubebuilder:validation:XValidation:rule="!(has(self.include) && has(self.include.messagePatterns) && has(self.exclude) && has(self.exclude.messagePatterns))",message="include and exclude message patterns can not be combined"
+type Kind struct {
+	// Name the name of the kind
+	// +kubebuilder:validation:MinLength=3
+	Name string `json:"name"`
+
+	// APIGroup the api group of the kind. If not set, the kind is matched in any api group
+	// +optional
+	// +nullable
+	APIGroup *string `json:"apiGroup,omitempty"`
+
+	// EventTypes the event types to log, Normal or Warning. If empty events are logged as defined in the filter.
+	// +optional
+	EventTypes []EventType `json:"eventTypes,omitempty"`
+
+	// Include the events of the kind are logged only if they match all conditions of the block
+	// +optional
+	Include *Match `json:"include,omitempty"`
+
+	// Exclude the events of the kind matching any condition of the block are not logged
+	// +optional
+	Exclude *Match `json:"exclude,omitempty"`
+
+	// InvolvedObject optional conditions the involved object of the events must match
+	// +optional
+	InvolvedObject *InvolvedObject `json:"involvedObject,omitempty"`
+
+	// Expression an optional CEL expression the events of this kind must match. The event is available as variable
+	// event with the field names of its json representation e.g. event.count > 5 && event.reason.startsWith("Failed")
+	// +optional
+	Expression string `json:"expression,omitempty"`
+
+	// Notification an optional chat notification sent for each event matching this kind
+	// +optional
+	Notification *v1.Notification `json:"notification,omitempty"`
+}
+
+// Match defines the conditions of an include or exclude block. The message patterns can only be defined in one
+// of the blocks.
+type Match struct {
+	// Reasons the event reasons. In an include block, events with any reason are matched if empty.
+	// +optional
+	Reasons []string `json:"reasons,omitempty"`
+
+	// MessagePatterns regex patterns, one of them must be contained in the message of the event
+	// +optional
+	MessagePatterns []string `json:"messagePatterns,omitempty"`
+}
+
+// InvolvedObject defines the conditions the involved object of an event must match.
+type InvolvedObject struct {
+	// Names optional glob patterns, the name of the involved object must match one of them
+	// +optional
+	Names []string `json:"names,omitempty"`
+
+	// Namespaces optional glob patterns, the namespace of the involved object must match one of them
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// LabelSelector optional selector the labels of the involved object must match. The labels are read from a
+	// metadata-only cache, the logger pod is granted to get, list and watch the kind in the watched namespace.
+	// +optional
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+// LogField defines a log field. Exactly one of jsonPath, template or value must be set.
+// +kubebuilder:validation:XValidation:rule="[has(self.jsonPath), has(self.template), has(self.value)].filter(x, x).size() == 1",message="exactly one of jsonPath, template or value must be set"
+type LogField struct {
+	// Name of the log field
+	Name string `json:"name"`
+
+	// JSONPath kubectl style JSONPath of the field within the json representation of the event
+	// e.g. .involvedObject.name or .metadata.ownerReferences[0].name. Multiple matched values are logged as list
+	// +optional
+	JSONPath string `json:"jsonPath,omitempty"`
+
+	// Template go template rendered with the json representation of the event, to compose the value of the log field
+	// e.g. {{ .involvedObject.kind }}/{{ .involvedObject.name }}
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// Value a static value of the log field. Can be used to add static log fields
+	// +optional
+	// +nullable
+	Value *string `json:"value,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:unservedversion
+
+// EventLogger is the Schema for the eventloggers API. The version is served by the operator once the conversion
+// webhook is enabled.
+type EventLogger struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EventLoggerSpec      `json:"spec,omitempty"`
+	Status v1.EventLoggerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EventLoggerList contains a list of EventLogger.
+type EventLoggerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []EventLogger `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EventLogger{}, &EventLoggerList{})
+}
@@ -0,0 +1,13 @@
+package v2_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestV2(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "V2 Suite")
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -17,6 +18,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
 	cnst "github.com/bakito/k8s-event-logger-operator/pkg/constants"
 	"github.com/bakito/operator-utils/pkg/filter"
 )
@@ -41,7 +43,8 @@ type Reconciler struct {
 	Log    logr.Logger
 	Scheme *runtime.Scheme
 
-	cfg              *Cfg
+	// cfg is read by the webhooks and the setup reconciler while a reload of the configmap replaces it
+	cfg              *atomic.Pointer[Cfg]
 	once             sync.Once
 	eventLoggerImage string
 }
@@ -108,7 +111,21 @@ func (r *Reconciler) readConfig(ctx context.Context, reqLogger logr.Logger, nn t
 		container.ImagePullPolicy = corev1.PullAlways
 	}
 
-	r.cfg.ContainerTemplate = container
+	var logFields []eventloggerv1.LogField
+	if lf, ok := cm.Data[cnst.ConfigKeyDefaultLogFields]; ok {
+		if err := yaml.Unmarshal([]byte(lf), &logFields); err != nil {
+			return err
+		}
+		// the webhook applies the default log fields without validating them again
+		if err := (&eventloggerv1.EventLoggerSpec{LogFields: logFields}).Validate(); err != nil {
+			return fmt.Errorf("configmap %q contains invalid %q: %w", nn.String(), cnst.ConfigKeyDefaultLogFields, err)
+		}
+	}
+
+	r.cfg.Store(&Cfg{
+		ContainerTemplate: container,
+		DefaultLogFields:  logFields,
+	})
 
 	return nil
 }
@@ -139,17 +156,18 @@ func (r *Reconciler) setupEventLoggerImage(nn types.NamespacedName) error {
 
 func (r *Reconciler) Ctx() context.Context {
 	r.once.Do(func() {
-		r.cfg = &Cfg{}
+		r.cfg = &atomic.Pointer[Cfg]{}
+		r.cfg.Store(&Cfg{})
 	})
 	return context.WithValue(context.Background(), configKey, r.cfg)
 }
 
 func GetCfg(ctx context.Context) *Cfg {
-	c, ok := ctx.Value(configKey).(*Cfg)
+	c, ok := ctx.Value(configKey).(*atomic.Pointer[Cfg])
 	if !ok {
 		return nil
 	}
-	return new(*c)
+	return new(*c.Load())
 }
 
 // SetupWithManager setup with manager.
@@ -187,4 +205,6 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 type Cfg struct {
 	ContainerTemplate corev1.Container
+	// DefaultLogFields the log fields defaulted by the webhook for event loggers without log fields
+	DefaultLogFields []eventloggerv1.LogField
 }
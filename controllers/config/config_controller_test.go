@@ -113,6 +113,70 @@ resources:
 			Ω(cfg.ContainerTemplate.Resources.Requests.Memory().String()).Should(Equal("222Mi"))
 			Ω(cfg.ContainerTemplate.Resources.Limits.Cpu().String()).Should(Equal("333m"))
 			Ω(cfg.ContainerTemplate.Resources.Limits.Memory().String()).Should(Equal("444Mi"))
+			Ω(cfg.DefaultLogFields).Should(BeEmpty())
+		})
+		It("should read the default log fields", func() {
+			configMap.Data = map[string]string{
+				cnst.ConfigKeyContainerTemplate: "",
+				cnst.ConfigKeyDefaultLogFields: `
+- name: kind
+  path:
+    - InvolvedObject
+    - Kind
+- name: cluster
+  value: prod
+`,
+			}
+			cr.Reader = fake.NewClientBuilder().WithScheme(s).WithObjects(configMap).Build()
+			_, err := cr.Reconcile(cr.Ctx(), reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      configMap.Name,
+					Namespace: configMap.Namespace,
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			cfg := GetCfg(cr.Ctx())
+			Ω(cfg.DefaultLogFields).Should(Equal([]apiv1.LogField{
+				{Name: "kind", Path: []string{"InvolvedObject", "Kind"}},
+				{Name: "cluster", Value: new("prod")},
+			}))
+		})
+		It("should fail if the default log fields can not be parsed", func() {
+			configMap.Data = map[string]string{
+				cnst.ConfigKeyContainerTemplate: "",
+				cnst.ConfigKeyDefaultLogFields:  "name: kind",
+			}
+			cr.Reader = fake.NewClientBuilder().WithScheme(s).WithObjects(configMap).Build()
+			_, err := cr.Reconcile(cr.Ctx(), reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      configMap.Name,
+					Namespace: configMap.Namespace,
+				},
+			})
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should fail if the default log fields are invalid", func() {
+			configMap.Data = map[string]string{
+				cnst.ConfigKeyContainerTemplate: "",
+				cnst.ConfigKeyDefaultLogFields: `
+- name: kind
+  jsonPath: .involvedObject.kind
+- name: name
+  jsonPath: .involvedObject.name
+  value: static
+`,
+			}
+			cr.Reader = fake.NewClientBuilder().WithScheme(s).WithObjects(configMap).Build()
+			_, err := cr.Reconcile(cr.Ctx(), reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      configMap.Name,
+					Namespace: configMap.Namespace,
+				},
+			})
+			Ω(err).Should(MatchError(ContainSubstring(`contains invalid "default_log_fields.yaml"`)))
+			Ω(err).Should(MatchError(ContainSubstring("spec.logFields[1]: Forbidden")))
+			Ω(GetCfg(cr.Ctx()).DefaultLogFields).Should(BeEmpty())
 		})
 	})
 
@@ -0,0 +1,109 @@
+package conversion
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	cnst "github.com/bakito/k8s-event-logger-operator/pkg/constants"
+)
+
+var errNoCABundle = errors.New("the webhook configuration " + cnst.WebhookConfigurationName + " has no ca bundle yet")
+
+var _ manager.LeaderElectionRunnable = &Enabler{}
+
+// Enabler configures the conversion webhook of the EventLogger crd and serves all of its versions. The crd is
+// installed with the converted versions not served, as they can only be served once the webhook is running, and
+// the crds of the chart can not reference the service of the release.
+// The crd is checked periodically, so that a reapplied crd is enabled again. Until then, the converted versions are
+// not served; GitOps tools applying the crd have to ignore the conversion and the served flags, or they revert them.
+type Enabler struct {
+	Reader client.Reader
+	Client client.Client
+	Log    logr.Logger
+	// Service the name of the service of the webhook server
+	Service string
+	// Namespace the namespace of the service
+	Namespace string
+	// Interval the interval the crd is checked in
+	Interval time.Duration
+}
+
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,resourceNames=eventloggers.eventlogger.bakito.ch,verbs=get;patch
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,resourceNames=eventlogger.bakito.ch,verbs=get
+
+// Start enables the conversion until the context is done.
+func (e *Enabler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := e.enable(ctx); err != nil {
+			e.Log.Error(err, "could not enable the conversion webhook", "crd", cnst.EventLoggerCRDName)
+		}
+	}, e.Interval)
+	return nil
+}
+
+// NeedLeaderElection the crd is only updated by the leader.
+func (*Enabler) NeedLeaderElection() bool {
+	return true
+}
+
+// enable configures the conversion webhook with the ca bundle of the validating webhook and serves all versions.
+func (e *Enabler) enable(ctx context.Context) error {
+	whc := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := e.Reader.Get(ctx, client.ObjectKey{Name: cnst.WebhookConfigurationName}, whc); err != nil {
+		return err
+	}
+	var caBundle []byte
+	for _, wh := range whc.Webhooks {
+		if len(bytes.TrimSpace(wh.ClientConfig.CABundle)) > 0 {
+			caBundle = wh.ClientConfig.CABundle
+			break
+		}
+	}
+	if caBundle == nil {
+		return errNoCABundle
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := e.Reader.Get(ctx, client.ObjectKey{Name: cnst.EventLoggerCRDName}, crd); err != nil {
+		return err
+	}
+	orig := crd.DeepCopy()
+	crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				Service: &apiextensionsv1.ServiceReference{
+					Namespace: e.Namespace,
+					Name:      e.Service,
+					Path:      new("/convert"),
+					Port:      new(int32(443)),
+				},
+				CABundle: caBundle,
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}
+	var served []string
+	for i := range crd.Spec.Versions {
+		crd.Spec.Versions[i].Served = true
+		served = append(served, crd.Spec.Versions[i].Name)
+	}
+	if equality.Semantic.DeepEqual(orig.Spec, crd.Spec) {
+		return nil
+	}
+	if err := e.Client.Patch(ctx, crd, client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{})); err != nil {
+		return err
+	}
+	e.Log.Info("enabled the conversion webhook", "crd", cnst.EventLoggerCRDName, "versions", served)
+	return nil
+}
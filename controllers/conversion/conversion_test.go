@@ -0,0 +1,91 @@
+package conversion
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cnst "github.com/bakito/k8s-event-logger-operator/pkg/constants"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Enabler", func() {
+	var (
+		ctx context.Context
+		crd *apiextensionsv1.CustomResourceDefinition
+		whc *admissionregistrationv1.ValidatingWebhookConfiguration
+		e   *Enabler
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		crd = &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: cnst.EventLoggerCRDName},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+					{Name: "v1", Served: true, Storage: true},
+					{Name: "v2"},
+				},
+			},
+		}
+		whc = &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: cnst.WebhookConfigurationName},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{
+				{
+					Name:         "veventlogger.bakito.ch",
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: []byte("ca")},
+				},
+			},
+		}
+	})
+	newEnabler := func(objs ...client.Object) *Enabler {
+		s := runtime.NewScheme()
+		Ω(apiextensionsv1.AddToScheme(s)).ShouldNot(HaveOccurred())
+		Ω(admissionregistrationv1.AddToScheme(s)).ShouldNot(HaveOccurred())
+		cl := fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+		return &Enabler{Reader: cl, Client: cl, Log: logr.Discard(), Service: "operator", Namespace: "ns"}
+	}
+
+	It("should configure the conversion webhook and serve all versions", func() {
+		e = newEnabler(crd, whc)
+		Ω(e.enable(ctx)).ShouldNot(HaveOccurred())
+
+		Ω(e.Reader.Get(ctx, client.ObjectKeyFromObject(crd), crd)).ShouldNot(HaveOccurred())
+		Ω(crd.Spec.Versions[1].Served).Should(BeTrue())
+		Ω(crd.Spec.Conversion.Strategy).Should(Equal(apiextensionsv1.WebhookConverter))
+		cc := crd.Spec.Conversion.Webhook.ClientConfig
+		Ω(cc.CABundle).Should(Equal([]byte("ca")))
+		Ω(cc.Service).Should(Equal(&apiextensionsv1.ServiceReference{
+			Namespace: "ns",
+			Name:      "operator",
+			Path:      new("/convert"),
+			Port:      new(int32(443)),
+		}))
+	})
+	It("should not update an enabled crd", func() {
+		e = newEnabler(crd, whc)
+		Ω(e.enable(ctx)).ShouldNot(HaveOccurred())
+		Ω(e.Reader.Get(ctx, client.ObjectKeyFromObject(crd), crd)).ShouldNot(HaveOccurred())
+		rv := crd.ResourceVersion
+
+		Ω(e.enable(ctx)).ShouldNot(HaveOccurred())
+		Ω(e.Reader.Get(ctx, client.ObjectKeyFromObject(crd), crd)).ShouldNot(HaveOccurred())
+		Ω(crd.ResourceVersion).Should(Equal(rv))
+	})
+	It("should wait for the ca bundle of the webhook", func() {
+		whc.Webhooks[0].ClientConfig.CABundle = []byte("\n")
+		e = newEnabler(crd, whc)
+		Ω(e.enable(ctx)).Should(MatchError(errNoCABundle))
+
+		Ω(e.Reader.Get(ctx, client.ObjectKeyFromObject(crd), crd)).ShouldNot(HaveOccurred())
+		Ω(crd.Spec.Versions[1].Served).Should(BeFalse())
+		Ω(crd.Spec.Conversion).Should(BeNil())
+	})
+})
@@ -0,0 +1,219 @@
+package logging
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	checkpointKeyResourceVersion = "resourceVersion"
+	checkpointKeyTimestamp       = "timestamp"
+	// checkpointFlushTimeout the max time to store the final checkpoint when the logger stops
+	checkpointFlushTimeout = 5 * time.Second
+)
+
+// Checkpoint configures the persistent resume checkpoint of the logger.
+type Checkpoint struct {
+	// ConfigMap the name of the config map in the namespace of the pod the checkpoint is stored in
+	ConfigMap string
+	// Interval the interval the checkpoint is stored in
+	Interval time.Duration
+}
+
+// checkpoint is the last processed event.
+type checkpoint struct {
+	resourceVersion string
+	// timestamp the latest timestamp of the processed events
+	timestamp time.Time
+}
+
+// checkpointStore reads the checkpoint from the config map and stores the checkpoint of the predicate periodically.
+type checkpointStore struct {
+	client    client.Client
+	key       client.ObjectKey
+	interval  time.Duration
+	predicate *loggingPredicate
+	log       logr.Logger
+	stored    string
+	// loaded is closed once the checkpoint to resume from is loaded
+	loaded chan struct{}
+}
+
+// load reads the checkpoint from the config map, nil is returned if no checkpoint was stored yet.
+func (s *checkpointStore) load(ctx context.Context) (*checkpoint, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, s.key, cm); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	rv := cm.Data[checkpointKeyResourceVersion]
+	if rv == "" {
+		return nil, nil
+	}
+	cp := &checkpoint{resourceVersion: rv}
+	if ts, err := time.Parse(time.RFC3339, cm.Data[checkpointKeyTimestamp]); err == nil {
+		cp.timestamp = ts
+	}
+	s.stored = rv
+	return cp, nil
+}
+
+// save stores the checkpoint in the config map.
+func (s *checkpointStore) save(ctx context.Context, cp checkpoint) error {
+	cm := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, s.key, cm); err != nil {
+		return err
+	}
+	orig := cm.DeepCopy()
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[checkpointKeyResourceVersion] = cp.resourceVersion
+	cm.Data[checkpointKeyTimestamp] = cp.timestamp.UTC().Format(time.RFC3339)
+	if err := s.client.Patch(ctx, cm, client.MergeFrom(orig)); err != nil {
+		return err
+	}
+	s.stored = cp.resourceVersion
+	return nil
+}
+
+// store saves the checkpoint of the predicate if it changed since it was stored last.
+func (s *checkpointStore) store(ctx context.Context) {
+	cp, ok := s.predicate.checkpoint()
+	if !ok || cp.resourceVersion == s.stored {
+		return
+	}
+	if err := s.save(ctx, cp); err != nil {
+		s.log.Error(err, "could not store the checkpoint", "configMap", s.key.Name)
+	}
+}
+
+// Start implements manager.Runnable. The checkpoint to resume from is loaded when the logger becomes the leader, so
+// that a promoted standby resumes from the checkpoint of the previous leader. The checkpoint is stored periodically
+// and once more when the logger stops.
+func (s *checkpointStore) Start(ctx context.Context) error {
+	cp, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	if cp != nil {
+		s.log.WithValues("resourceVersion", cp.resourceVersion, "timestamp", cp.timestamp).
+			Info("resuming from checkpoint")
+	}
+	s.predicate.start(cp)
+	close(s.loaded)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// the context of the manager is cancelled, the final checkpoint is stored with a fresh one
+			fctx, cancel := context.WithTimeout(context.Background(), checkpointFlushTimeout)
+			defer cancel()
+			s.store(fctx)
+			return nil
+		case <-ticker.C:
+			s.store(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the active logger stores the checkpoint.
+func (s *checkpointStore) NeedLeaderElection() bool {
+	return true
+}
+
+// checkpoint returns the last processed event. False is returned if no event was processed yet or the events
+// of the checkpoint the logger was started with are not replayed yet.
+func (p *loggingPredicate) checkpoint() (checkpoint, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumeFrom != nil || p.replaying || p.lastTimestamp.IsZero() {
+		return checkpoint{}, false
+	}
+	return checkpoint{resourceVersion: p.lastVersion, timestamp: p.lastTimestamp}, true
+}
+
+// replayPending returns true if the replay of the checkpoint the logger was started with did not start yet.
+func (p *loggingPredicate) replayPending() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resumeFrom != nil
+}
+
+// startReplay returns the checkpoint to replay the events from, nil if there is none. The events of the watch are
+// processed again once the replay is started, the events processed by both are deduplicated.
+func (p *loggingPredicate) startReplay() *checkpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	from := p.resumeFrom
+	if from != nil {
+		p.resumeFrom = nil
+		p.replaying = true
+	}
+	return from
+}
+
+// abortReplay restores the checkpoint if the events to replay could not be read.
+func (p *loggingPredicate) abortReplay(from *checkpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resumeFrom = from
+	p.replaying = false
+}
+
+// replay processes the events not older than the checkpoint in the order of their timestamps. The events of the
+// watch are not processed until the replay is started, the replayed events include them, as the cache is updated
+// before the events are handled. Returns the number of replayed events.
+func (p *loggingPredicate) replay(from *checkpoint, objs []runtime.Object) int {
+	if from == nil {
+		return 0
+	}
+	defer func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.replaying = false
+	}()
+
+	var events []*corev1.Event
+	for _, obj := range objs {
+		if evt, ok := toEvent(obj); ok && !lastSeen(evt).Before(from.timestamp.Truncate(time.Second)) {
+			events = append(events, evt)
+		}
+	}
+	slices.SortStableFunc(events, func(a, b *corev1.Event) int {
+		return lastSeen(a).Compare(lastSeen(b))
+	})
+	for _, evt := range events {
+		p.process(evt)
+	}
+	return len(events)
+}
+
+// replay logs the events newer than the checkpoint the logger was started with, that still exist.
+// The events are read from the cache of the watch after the replay is started, so no event of the watch is missed.
+func (r *Reconciler) replay(ctx context.Context, logger logr.Logger) error {
+	from := r.predicate.startReplay()
+	if from == nil {
+		return nil
+	}
+	_, eventList := eventObjects(r.EventAPI)
+	if err := r.List(ctx, eventList); err != nil {
+		r.predicate.abortReplay(from)
+		return err
+	}
+	objs, err := meta.ExtractList(eventList)
+	if err != nil {
+		r.predicate.abortReplay(from)
+		return err
+	}
+	logger.WithValues("events", r.predicate.replay(from, objs)).Info("replayed the events since the checkpoint")
+	return nil
+}
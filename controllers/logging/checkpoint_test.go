@@ -0,0 +1,266 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/bakito/k8s-event-logger-operator/pkg/filter"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checkpoint", func() {
+	var (
+		buf bytes.Buffer
+		lp  *loggingPredicate
+	)
+
+	BeforeEach(func() {
+		buf.Reset()
+		lp = &loggingPredicate{Config: testConfig(&snapshot{
+			filter: filter.Always,
+			sinks:  sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+		})}
+	})
+
+	now := time.Now().Truncate(time.Second)
+
+	newEvent := func(rv string, message string, age time.Duration) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "evt-" + message,
+				UID:             types.UID(message),
+				ResourceVersion: rv,
+			},
+			Message:       message,
+			LastTimestamp: metav1.NewTime(now.Add(-age)),
+		}
+	}
+
+	Context("replay", func() {
+		It("should replay the events since the checkpoint in order", func() {
+			lp.resumeFrom = &checkpoint{resourceVersion: "999", timestamp: now.Add(-time.Hour)}
+			lp.seen = newSeenEvents(defaultSeenEventsSize, lp.resumeFrom.timestamp)
+
+			Ω(lp.logEvent(newEvent("1006", "during-startup", 0))).Should(BeFalse())
+			Ω(buf.String()).Should(BeEmpty())
+
+			from := lp.startReplay()
+			Ω(lp.replayPending()).Should(BeFalse())
+			_, ok := lp.checkpoint()
+			Ω(ok).Should(BeFalse())
+
+			replayed := lp.replay(from, []runtime.Object{
+				newEvent("1006", "during-startup", 0),
+				newEvent("10", "second", 30*time.Minute),
+				newEvent("998", "old", 2*time.Hour),
+				newEvent("1000", "first", time.Hour),
+				&corev1.Pod{},
+			})
+			Ω(replayed).Should(Equal(3))
+			Ω(lp.replayPending()).Should(BeFalse())
+			Ω(lp.replaying).Should(BeFalse())
+			Ω(lp.lastVersion).Should(Equal("1006"))
+			out := buf.String()
+			Ω(out).ShouldNot(ContainSubstring("old"))
+			Ω(strings.Index(out, "first")).Should(BeNumerically("<", strings.Index(out, "second")))
+			Ω(strings.Index(out, "second")).Should(BeNumerically("<", strings.Index(out, "during-startup")))
+
+			// the watch delivers the replayed event
+			buf.Reset()
+			lp.logEvent(newEvent("1006", "during-startup", 0))
+			Ω(buf.String()).Should(BeEmpty())
+		})
+		It("should do nothing without checkpoint", func() {
+			Ω(lp.replay(lp.startReplay(), []runtime.Object{newEvent("1", "first", 0)})).Should(Equal(0))
+			Ω(buf.String()).Should(BeEmpty())
+		})
+		It("should process the events of the watch once the replay is started", func() {
+			lp.resumeFrom = &checkpoint{resourceVersion: "999", timestamp: now.Add(-time.Hour)}
+			lp.seen = newSeenEvents(defaultSeenEventsSize, lp.resumeFrom.timestamp)
+			from := lp.startReplay()
+
+			lp.logEvent(newEvent("1001", "during-replay", 0))
+			Ω(buf.String()).Should(ContainSubstring("during-replay"))
+
+			buf.Reset()
+			Ω(lp.replay(from, []runtime.Object{newEvent("1001", "during-replay", 0)})).Should(Equal(1))
+			Ω(buf.String()).Should(BeEmpty())
+		})
+		It("should restore the checkpoint if the replay is aborted", func() {
+			from := &checkpoint{resourceVersion: "999", timestamp: now}
+			lp.resumeFrom = from
+			lp.abortReplay(lp.startReplay())
+			Ω(lp.resumeFrom).Should(BeIdenticalTo(from))
+			Ω(lp.replaying).Should(BeFalse())
+		})
+	})
+
+	Context("leaderController", func() {
+		It("should not log the events of the previous leader again after a failover", func() {
+			// the standby was started long before it is elected, the previous leader logged the existing events
+			existing := []*corev1.Event{
+				newEvent("1", "before-standby", 3*time.Hour),
+				newEvent("2", "logged-by-leader", time.Hour),
+				newEvent("3", "logged-by-leader-too", time.Minute),
+			}
+			lc := &leaderController{
+				Controller: &relistController{predicate: lp, events: existing},
+				predicate:  lp,
+			}
+			Ω(lc.NeedLeaderElection()).Should(BeTrue())
+
+			Ω(lc.Start(context.Background())).ShouldNot(HaveOccurred())
+			Ω(buf.String()).Should(BeEmpty())
+
+			evt := newEvent("4", "after-failover", 0)
+			evt.LastTimestamp = metav1.Now()
+			lp.logEvent(evt)
+			Ω(buf.String()).Should(ContainSubstring("after-failover"))
+		})
+	})
+
+	Context("checkpoint", func() {
+		It("should not return a checkpoint before an event was processed", func() {
+			_, ok := lp.checkpoint()
+			Ω(ok).Should(BeFalse())
+		})
+		It("should not return a checkpoint while the replay is pending", func() {
+			lp.lastTimestamp = now
+			lp.resumeFrom = &checkpoint{resourceVersion: "1"}
+			_, ok := lp.checkpoint()
+			Ω(ok).Should(BeFalse())
+		})
+		It("should return the last processed event and the latest timestamp", func() {
+			lp.logEvent(newEvent("7", "newer", 0))
+			lp.logEvent(newEvent("8", "older", time.Minute))
+			cp, ok := lp.checkpoint()
+			Ω(ok).Should(BeTrue())
+			Ω(cp.resourceVersion).Should(Equal("8"))
+			Ω(cp.timestamp).Should(BeTemporally("==", now))
+		})
+	})
+
+	Context("checkpointStore", func() {
+		var (
+			ctx   context.Context
+			cl    client.Client
+			store *checkpointStore
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "checkpoint"}}
+			cl = fake.NewClientBuilder().WithObjects(cm).Build()
+			store = &checkpointStore{
+				client:    cl,
+				key:       client.ObjectKeyFromObject(cm),
+				interval:  time.Millisecond,
+				predicate: lp,
+				log:       logr.Discard(),
+				loaded:    make(chan struct{}),
+			}
+		})
+
+		It("should return no checkpoint if none was stored", func() {
+			Ω(store.load(ctx)).Should(BeNil())
+		})
+		It("should return no checkpoint if the config map does not exist", func() {
+			store.key.Name = "missing"
+			Ω(store.load(ctx)).Should(BeNil())
+		})
+		It("should store and load the checkpoint", func() {
+			ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			Ω(store.save(ctx, checkpoint{resourceVersion: "42", timestamp: ts})).ShouldNot(HaveOccurred())
+
+			cp, err := store.load(ctx)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cp.resourceVersion).Should(Equal("42"))
+			Ω(cp.timestamp).Should(BeTemporally("==", ts))
+		})
+		It("should store the checkpoint periodically and when stopped", func() {
+			lp.logEvent(newEvent("7", "seven", 0))
+
+			sctx, cancel := context.WithCancel(ctx)
+			done := make(chan error)
+			go func() { done <- store.Start(sctx) }()
+			Eventually(func() string {
+				cm := &corev1.ConfigMap{}
+				_ = cl.Get(ctx, store.key, cm)
+				return cm.Data[checkpointKeyResourceVersion]
+			}).Should(Equal("7"))
+
+			evt := newEvent("8", "eight", 0)
+			evt.LastTimestamp = metav1.Now()
+			lp.logEvent(evt)
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+
+			cp, err := store.load(ctx)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cp.resourceVersion).Should(Equal("8"))
+		})
+		It("should resume from the checkpoint stored by the previous leader after a failover", func() {
+			lc := &leaderController{
+				Controller: &relistController{predicate: lp, events: []*corev1.Event{
+					newEvent("1", "before-checkpoint", 2*time.Hour),
+					newEvent("2", "since-checkpoint", time.Minute),
+				}},
+				predicate: lp,
+				loaded:    store.loaded,
+			}
+			// the previous leader stores the checkpoint after the standby was set up
+			Ω(store.save(ctx, checkpoint{resourceVersion: "1", timestamp: now.Add(-time.Hour)})).ShouldNot(HaveOccurred())
+
+			sctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go func() { _ = store.Start(sctx) }()
+			Ω(lc.Start(sctx)).ShouldNot(HaveOccurred())
+			Ω(lp.replayPending()).Should(BeTrue())
+			Ω(buf.String()).Should(BeEmpty())
+
+			from := lp.startReplay()
+			Ω(from.resourceVersion).Should(Equal("1"))
+			Ω(lp.replay(from, []runtime.Object{
+				newEvent("1", "before-checkpoint", 2*time.Hour),
+				newEvent("2", "since-checkpoint", time.Minute),
+			})).Should(Equal(1))
+			Ω(buf.String()).Should(ContainSubstring("since-checkpoint"))
+			Ω(buf.String()).ShouldNot(ContainSubstring("before-checkpoint"))
+		})
+		It("should not start the controller if the logger stops before the checkpoint is loaded", func() {
+			lc := &leaderController{predicate: lp, loaded: store.loaded}
+			sctx, cancel := context.WithCancel(ctx)
+			cancel()
+			Ω(lc.Start(sctx)).ShouldNot(HaveOccurred())
+		})
+	})
+})
+
+// relistController delivers the existing events to the predicate when it is started, like the informer of the watch.
+type relistController struct {
+	controller.Controller
+	predicate *loggingPredicate
+	events    []*corev1.Event
+}
+
+// Start implements controller.Controller.
+func (c *relistController) Start(context.Context) error {
+	for _, evt := range c.events {
+		c.predicate.Create(event.CreateEvent{Object: evt})
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/filter"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	Context("swap", func() {
+		It("should return the previous snapshot", func() {
+			c := testConfig(&snapshot{name: "a"})
+			prev := c.swap(&snapshot{name: "b"})
+			Ω(prev.name).Should(Equal("a"))
+			Ω(c.load().name).Should(Equal("b"))
+		})
+		It("should return an empty snapshot if none was applied", func() {
+			c := &Config{}
+			Ω(c.swap(&snapshot{name: "b"})).Should(BeIdenticalTo(emptySnapshot))
+		})
+		It("should wait for the events processed with the previous snapshot", func() {
+			c := testConfig(&snapshot{name: "a"})
+			s, release := c.acquire()
+			Ω(s.name).Should(Equal("a"))
+
+			swapped := make(chan *snapshot)
+			go func() { swapped <- c.swap(&snapshot{name: "b"}) }()
+			Consistently(swapped, 50*time.Millisecond).ShouldNot(Receive())
+
+			release()
+			var prev *snapshot
+			Eventually(swapped).Should(Receive(&prev))
+			Ω(prev.name).Should(Equal("a"))
+		})
+	})
+
+	Context("reset", func() {
+		It("should keep the name and flush the throttle to the sinks", func() {
+			var buf bytes.Buffer
+			c := testConfig(&snapshot{
+				name:   "logger",
+				filter: filter.Always,
+				sinks:  sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+			})
+			s := *c.load()
+			s.throttle = c.newThrottle(throttleSpec{
+				deduplication: &apiv1.Deduplication{Window: metav1.Duration{Duration: time.Hour}},
+			})
+			c.swap(&s)
+			lp := &loggingPredicate{Config: c}
+			for i := range 2 {
+				lp.logEvent(&corev1.Event{
+					ObjectMeta: metav1.ObjectMeta{ResourceVersion: strconv.Itoa(i + 1), Name: "test-event-name"},
+					Reason:     "BackOff",
+					Message:    "test-message",
+				})
+			}
+
+			c.reset(logr.Discard())
+			Ω(c.load()).Should(Equal(&snapshot{name: "logger"}))
+			Ω(buf.String()).Should(ContainSubstring(`"msg":"suppressed 1 repeats"`))
+		})
+		It("should close the throttle after the events processed with the applied config", func() {
+			var buf bytes.Buffer
+			c := testConfig(&snapshot{
+				name:   "logger",
+				filter: filter.Always,
+				sinks:  sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+			})
+			s := *c.load()
+			s.throttle = c.newThrottle(throttleSpec{
+				deduplication: &apiv1.Deduplication{Window: metav1.Duration{Duration: time.Hour}},
+			})
+			c.swap(&s)
+			evt := &corev1.Event{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-event-name"},
+				Reason:     "BackOff",
+				Message:    "test-message",
+			}
+			Ω(s.throttle.Allow(evt)).Should(BeTrue())
+
+			// an event is still processed with the applied config while it is reset
+			cur, release := c.acquire()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				c.reset(logr.Discard())
+			}()
+			Consistently(done, 50*time.Millisecond).ShouldNot(BeClosed())
+			Ω(cur.throttle.Allow(evt)).Should(BeFalse())
+			release()
+
+			Eventually(done).Should(BeClosed())
+			Ω(c.load()).Should(Equal(&snapshot{name: "logger"}))
+			Ω(buf.String()).Should(ContainSubstring(`"msg":"suppressed 1 repeats"`))
+		})
+	})
+
+	Context("concurrency", func() {
+		It("should process events while the config is reconciled", func() {
+			Ω(apiv1.AddToScheme(scheme.Scheme)).ShouldNot(HaveOccurred())
+			el := &apiv1.EventLogger{
+				ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace},
+			}
+			cl := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(el).
+				WithStatusSubresource(el).
+				Build()
+			r := &Reconciler{
+				Client:     cl,
+				Log:        logr.Discard(),
+				Config:     ConfigFor(testName, testNamespace),
+				LoggerMode: true,
+				PodName:    "logger-pod",
+			}
+			lp := &loggingPredicate{Config: r.Config}
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: testName}}
+			ctx := context.Background()
+
+			const rounds = 50
+			var wg sync.WaitGroup
+			wg.Go(func() {
+				defer GinkgoRecover()
+				for i := range rounds {
+					cr := &apiv1.EventLogger{}
+					Ω(cl.Get(ctx, req.NamespacedName, cr)).ShouldNot(HaveOccurred())
+					cr.Spec.Kinds = []apiv1.Kind{{Name: "Pod", Reasons: []string{"Reason" + strconv.Itoa(i)}}}
+					cr.Spec.LogFields = []apiv1.LogField{{Name: "reason", JSONPath: ".reason"}}
+					if i%2 == 1 {
+						cr.Spec.Deduplication = &apiv1.Deduplication{Window: metav1.Duration{Duration: time.Minute}}
+					} else {
+						cr.Spec.Deduplication = nil
+					}
+					Ω(cl.Update(ctx, cr)).ShouldNot(HaveOccurred())
+					_, err := r.Reconcile(ctx, req)
+					Ω(err).ShouldNot(HaveOccurred())
+				}
+			})
+			for w := range 4 {
+				wg.Go(func() {
+					defer GinkgoRecover()
+					for i := range rounds * 10 {
+						lp.logEvent(&corev1.Event{
+							ObjectMeta: metav1.ObjectMeta{
+								UID:             types.UID(strconv.Itoa(w)),
+								ResourceVersion: strconv.Itoa(i),
+							},
+							InvolvedObject: corev1.ObjectReference{Kind: "Pod"},
+							Reason:         "Reason" + strconv.Itoa(i%rounds),
+						})
+						lp.Create(event.CreateEvent{Object: el})
+						lp.checkpoint()
+					}
+				})
+			}
+			wg.Wait()
+
+			s := r.Config.load()
+			Ω(s.name).Should(Equal(testName))
+			Ω(s.filter).ShouldNot(BeNil())
+			Ω(s.filter.String()).Should(ContainSubstring("Reason" + strconv.Itoa(rounds-1)))
+			Ω(s.throttle).ShouldNot(BeNil())
+			r.Close()
+			Ω(r.Config.load().filter).Should(BeNil())
+
+			Ω(cl.Get(ctx, req.NamespacedName, el)).ShouldNot(HaveOccurred())
+			Ω(el.Status.LoggerPod).Should(Equal("logger-pod"))
+		})
+	})
+})
@@ -19,22 +19,33 @@ package logging
 import (
 	"context"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/logfield"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+	"github.com/bakito/k8s-event-logger-operator/pkg/status"
 )
 
 var eventLog = ctrl.Log.WithName("event")
@@ -45,30 +56,43 @@ type Reconciler struct {
 	Log    logr.Logger
 	Scheme *runtime.Scheme
 	Config *Config
-	// LoggerMode if enabled, the controller does only logging and no update on the custom resource
+	// Cache the cache the labels of the involved objects are read from, label selectors match no events if nil
+	Cache cache.Cache
+	// LoggerMode if enabled, the controller does only logging and updates only the filter status of the custom resource
 	LoggerMode bool
+	// PodName the name of the pod the controller is running in
+	PodName string
+	// LeaderElection the logger pods elect a leader, the controller runs in the elected pod only
+	LeaderElection bool
+	// EventAPI the api version of the events to watch
+	EventAPI eventloggerv1.EventAPI
+	// Checkpoint optional persistent checkpoint of the last processed event, the events since the checkpoint are
+	// replayed when the logger starts
+	Checkpoint *Checkpoint
+
+	predicate *loggingPredicate
 }
 
 // +kubebuilder:rbac:groups=eventlogger.bakito.ch,resources=eventloggers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=eventlogger.bakito.ch,resources=eventloggers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=eventlogger.bakito.ch,resources=clustereventloggers,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=eventlogger.bakito.ch,resources=clustereventloggers/status,verbs=get;update;patch
 
-// Reconcile EventLogger to update the current config.
+// Reconcile EventLogger or ClusterEventLogger to update the current config.
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reqLogger := r.Log.WithValues("namespace", req.Namespace, "name", req.Name)
-	if r.Config.name == "" {
-		r.Config.name = req.Name
-	}
 
 	reqLogger.V(2).Info("Reconciling event logger")
 
 	// Fetch the EventLogger cr
-	cr := &eventloggerv1.EventLogger{}
+	cr := r.newObject()
 	err := r.Get(ctx, req.NamespacedName, cr)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
-			r.Config.filter = nil
+			r.Config.reset(reqLogger)
 			reqLogger.Info("cr was deleted, removing filter")
 			return reconcile.Result{}, nil
 		}
@@ -76,54 +100,241 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return r.updateCR(ctx, cr, reqLogger, err)
 	}
 
+	needUpdate, err := r.applyConfig(ctx, req, cr, reqLogger)
+
+	// the events since the checkpoint are replayed with the current config, also if the new one can not be applied,
+	// as the events of the watch are not processed before
+	if r.predicate != nil && r.predicate.replayPending() {
+		if rerr := r.replay(ctx, reqLogger); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+
+	if err != nil || needUpdate || !r.filterApplied(cr) {
+		return r.updateCR(ctx, cr, reqLogger, err)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// applyConfig applies the config of the cr, the current config is kept if it can not be applied. Returns true if the
+// config was updated.
+func (r *Reconciler) applyConfig(
+	ctx context.Context,
+	req ctrl.Request,
+	cr eventloggerv1.Object,
+	reqLogger logr.Logger,
+) (bool, error) {
+	// keep the current config if the cr was not validated by the webhook, an invalid pattern must not crash the logger
+	if err := cr.Validate(); err != nil {
+		return false, err
+	}
+
+	// the next snapshot of the config, the replaced resources are closed once it is applied and the newly created
+	// ones if it is discarded
+	cur := r.Config.load()
+	next := *cur
+	if next.name == "" {
+		next.name = req.Name
+	}
+	var created, replaced []func()
+	discard := func(err error) (bool, error) {
+		for _, closeFn := range created {
+			closeFn()
+		}
+		return false, err
+	}
+
+	spec := cr.GetSpec()
 	needUpdate := false
-	if !reflect.DeepEqual(r.Config.logFields, cr.Spec.LogFields) {
-		r.Config.logFields = cr.Spec.LogFields
-		reqLogger.WithValues("logFields", r.Config.logFields).Info("apply new log fields")
+	if !reflect.DeepEqual(cur.logFieldSpecs, spec.LogFields) {
+		logFields, err := newLogFields(spec.LogFields)
+		if err != nil {
+			return discard(err)
+		}
+		next.logFields = logFields
+		next.logFieldSpecs = spec.LogFields
+		reqLogger.WithValues("logFields", next.logFieldSpecs).Info("apply new log fields")
 		needUpdate = true
 	}
 
-	newFilter := newFilter(cr.Spec)
-	if r.Config.filter == nil || !r.Config.filter.Equals(newFilter) {
-		r.Config.filter = newFilter
-		reqLogger.WithValues("filter", r.Config.filter.String()).Info("apply new filter")
+	if !reflect.DeepEqual(cur.sinkSpecs, spec.Sinks) {
+		sinks, err := newSinks(ctx, r.Client, r.secretNamespace(cr), spec.Sinks, countSinkErrors(next.name))
+		if err != nil {
+			return discard(err)
+		}
+		created = append(created, func() { closeSinks(sinks, reqLogger) })
+		replaced = append(replaced, func() { closeSinks(cur.sinks, reqLogger) })
+		next.sinks = sinks
+		next.sinkSpecs = spec.Sinks
+		reqLogger.WithValues("sinks", len(spec.Sinks)).Info("apply new sinks")
 		needUpdate = true
 	}
 
-	if needUpdate {
-		return r.updateCR(ctx, cr, reqLogger, nil)
+	if nk := notificationKinds(*spec); !reflect.DeepEqual(cur.notifyKinds, nk) {
+		notifiers, err := newNotifiers(
+			ctx, r.Client, r.secretNamespace(cr), nk, newObjectLabels(r.Cache), countSinkErrors(next.name),
+		)
+		if err != nil {
+			return discard(err)
+		}
+		created = append(created, func() { closeNotifiers(notifiers, reqLogger) })
+		replaced = append(replaced, func() { closeNotifiers(cur.notifiers, reqLogger) })
+		next.notifiers = notifiers
+		next.notifyKinds = nk
+		reqLogger.WithValues("notifications", len(nk)).Info("apply new notifications")
+		needUpdate = true
 	}
 
-	return reconcile.Result{}, nil
+	if ts := (throttleSpec{deduplication: spec.Deduplication, rateLimit: spec.RateLimit}); !reflect.DeepEqual(
+		cur.throttleSpec,
+		ts,
+	) {
+		next.throttle = r.Config.newThrottle(ts)
+		next.throttleSpec = ts
+		replaced = append(replaced, func() { closeThrottle(cur.throttle) })
+		reqLogger.WithValues("deduplication", ts.deduplication, "rateLimit", ts.rateLimit).Info("apply new throttle")
+		needUpdate = true
+	}
+
+	if cel, ok := cr.(*eventloggerv1.ClusterEventLogger); ok &&
+		!reflect.DeepEqual(cur.excludeNamespaces, cel.Spec.ExcludeNamespaces) {
+		next.excludeNamespaces = cel.Spec.ExcludeNamespaces
+		reqLogger.WithValues("excludeNamespaces", next.excludeNamespaces).Info("apply new excluded namespaces")
+		needUpdate = true
+	}
+
+	ol := newObjectLabels(r.Cache)
+	newFilter, err := newFilter(*spec, ol)
+	if err != nil {
+		return discard(err)
+	}
+	if cur.filter == nil || !cur.filter.Equals(newFilter) {
+		if r.Cache != nil {
+			startLabelInformers(ctx, r.Cache, r.RESTMapper(), spec.Kinds, reqLogger)
+		}
+		next.filter = newFilter
+		kindClauses, err := newKindClauses(*spec, ol)
+		if err != nil {
+			return discard(err)
+		}
+		next.kindClauses = kindClauses
+		reqLogger.WithValues("filter", next.filter.String()).Info("apply new filter")
+		needUpdate = true
+	}
+
+	if needUpdate || next.name != cur.name {
+		r.Config.swap(&next)
+		for _, closeFn := range replaced {
+			closeFn()
+		}
+	}
+
+	return needUpdate, nil
+}
+
+// newObject returns a new empty cr of the kind of the config.
+func (r *Reconciler) newObject() eventloggerv1.Object {
+	if r.Config.cluster {
+		return &eventloggerv1.ClusterEventLogger{}
+	}
+	return &eventloggerv1.EventLogger{}
+}
+
+// secretNamespace returns the namespace of the secrets referenced by the cr. The secrets of a ClusterEventLogger
+// are read from the namespace of the logger pod.
+func (r *Reconciler) secretNamespace(cr eventloggerv1.Object) string {
+	if r.Config.cluster {
+		return r.Config.podNamespace
+	}
+	return cr.GetNamespace()
+}
+
+// Close closes the throttle, sinks and notifiers of the config when the logger stops,
+// the pending summaries and buffered events are flushed.
+func (r *Reconciler) Close() {
+	r.Config.reset(r.Log)
+}
+
+// filterApplied returns true if the status of the cr reports the current generation as applied by this pod.
+func (r *Reconciler) filterApplied(cr eventloggerv1.Object) bool {
+	c := meta.FindStatusCondition(cr.GetStatus().Conditions, eventloggerv1.ConditionFilterApplied)
+	return c != nil && c.Status == metav1.ConditionTrue && c.ObservedGeneration == cr.GetGeneration() &&
+		cr.GetStatus().LoggerPod == r.PodName
+}
+
+// activeLogger returns false if the controller runs in logger mode and another pod is the active logger pod of the cr.
+// The elected leader among multiple logger pods is always the active logger.
+func (r *Reconciler) activeLogger(cr eventloggerv1.Object) bool {
+	return !r.LoggerMode || r.LeaderElection || cr.GetStatus().LoggerPod == "" || cr.GetStatus().LoggerPod == r.PodName
+}
+
+// applyFilterStatus sets the FilterApplied condition. If this is not the active logger of the cr,
+// the status is not changed and false is returned.
+func (r *Reconciler) applyFilterStatus(cr eventloggerv1.Object, err error) bool {
+	if !r.activeLogger(cr) {
+		return false
+	}
+	cr.GetStatus().LoggerPod = r.PodName
+	if err != nil {
+		cr.SetCondition(eventloggerv1.ConditionFilterApplied, metav1.ConditionFalse, "ApplyFailed", err.Error())
+	} else {
+		now := metav1.Now()
+		cr.GetStatus().LastFilterApplied = &now
+		cr.SetCondition(eventloggerv1.ConditionFilterApplied, metav1.ConditionTrue, "Applied",
+			"filter applied by logger pod "+r.PodName)
+	}
+	cr.UpdateReadyCondition()
+	return true
 }
 
 func (r *Reconciler) updateCR(
 	ctx context.Context,
-	cr *eventloggerv1.EventLogger,
+	cr eventloggerv1.Object,
 	logger logr.Logger,
 	err error,
 ) (reconcile.Result, error) {
 	if err != nil {
 		logger.Error(err, "")
 	}
-	if r.LoggerMode {
-		// return only, no update
+	if cr.GetResourceVersion() == "" {
+		// the cr could not be read
+		return reconcile.Result{}, err
+	}
+	if !r.activeLogger(cr) {
 		return reconcile.Result{}, err
 	}
-	cr.Apply(err)
-	err = r.Update(ctx, cr)
-	return reconcile.Result{}, err
+	perr := status.Patch(ctx, r.Client, cr, func(el eventloggerv1.Object) {
+		if r.applyFilterStatus(el, err) && !r.LoggerMode {
+			el.Apply(err)
+		}
+	})
+	if r.LoggerMode && perr == nil {
+		// the logger pod retries to apply the config
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, perr
 }
 
 type loggingPredicate struct {
 	predicate.Funcs
+	// mu guards the bookkeeping of the processed events
+	mu   sync.Mutex
+	seen *seenEvents
+	// lastVersion the resource version of the last processed event
 	lastVersion string
-	Config      *Config
+	// lastTimestamp the latest timestamp of the processed events
+	lastTimestamp time.Time
+	// resumeFrom the checkpoint to replay the events from, once the filter is applied
+	resumeFrom *checkpoint
+	// replaying the events of the checkpoint are replayed
+	replaying bool
+	Config    *Config
 }
 
 // Create implements Predicate.
 func (p *loggingPredicate) Create(e event.CreateEvent) bool {
-	if _, ok := e.Object.(*eventloggerv1.EventLogger); ok {
+	if _, ok := e.Object.(eventloggerv1.Object); ok {
 		return p.Config.matches(e.Object)
 	}
 	return p.logEvent(e.Object)
@@ -131,7 +342,7 @@ func (p *loggingPredicate) Create(e event.CreateEvent) bool {
 
 // Update implements Predicate.
 func (p *loggingPredicate) Update(e event.UpdateEvent) bool {
-	if _, ok := e.ObjectNew.(*eventloggerv1.EventLogger); ok {
+	if _, ok := e.ObjectNew.(eventloggerv1.Object); ok {
 		return p.Config.matches(e.ObjectNew)
 	}
 	return p.logEvent(e.ObjectNew)
@@ -139,94 +350,253 @@ func (p *loggingPredicate) Update(e event.UpdateEvent) bool {
 
 // Delete implements Predicate.
 func (p *loggingPredicate) Delete(e event.DeleteEvent) bool {
-	if _, ok := e.Object.(*eventloggerv1.EventLogger); ok {
+	if _, ok := e.Object.(eventloggerv1.Object); ok {
 		return p.Config.matches(e.Object)
 	}
 	return false
 }
 
 func (p *loggingPredicate) logEvent(e runtime.Object) bool {
-	if p.Config == nil || p.Config.filter == nil {
+	evt, ok := toEvent(e)
+	if !ok {
 		return false
 	}
+	if p.replayPending() {
+		// the event is processed with the replay of the checkpoint
+		return false
+	}
+	return p.process(evt)
+}
 
-	evt, ok := e.(*corev1.Event)
-	if !ok {
+// process logs the event if it was not processed yet and matches the filter.
+func (p *loggingPredicate) process(evt *corev1.Event) bool {
+	if p.Config == nil {
 		return false
 	}
-	if evt.ResourceVersion <= p.lastVersion {
+	c, release := p.Config.acquire()
+	defer release()
+	if c.filter == nil {
+		eventsDropped.WithLabelValues(c.name, dropNoFilter).Inc()
+		return false
+	}
+	switch p.observe(evt) {
+	case observedDuplicate:
+		return false
+	case observedStale:
+		eventsDropped.WithLabelValues(c.name, dropStale).Inc()
 		return false
 	}
-	p.lastVersion = evt.ResourceVersion // SA4005:
 
-	if p.Config.filter.Match(evt) {
-		var eventLogger logr.Logger
-		if len(p.Config.logFields) == 0 {
-			ts := evt.LastTimestamp
-			if ts.IsZero() {
-				ts = evt.FirstTimestamp
-			}
-			if ts.IsZero() {
-				ts = metav1.Time{Time: evt.EventTime.Time}
-			}
+	name := c.name
+	eventsSeen.WithLabelValues(name).Inc()
+	if operatorEvent(evt) {
+		eventsFiltered.WithLabelValues(name, clauseOperatorEvent).Inc()
+		return false
+	}
+	if c.excluded(evt) {
+		eventsFiltered.WithLabelValues(name, clauseExcludeNamespace).Inc()
+		return false
+	}
+	if !c.filter.Match(evt) {
+		eventsFiltered.WithLabelValues(name, filteredBy(c.kindClauses, evt)).Inc()
+		return false
+	}
+	eventsMatched.WithLabelValues(name).Inc()
 
-			eventLogger = eventLog.WithValues(
-				"namespace", evt.Namespace,
-				"name", evt.Name,
-				"reason", evt.Reason,
-				"timestamp", ts,
-				"type", evt.Type,
-				"involvedObject", evt.InvolvedObject,
-				"source", evt.Source,
-			)
+	if c.throttle != nil && !c.throttle.Allow(evt) {
+		eventsSuppressed.WithLabelValues(name).Inc()
+		return false
+	}
+	r := &sink.Record{
+		Event:   evt,
+		Message: evt.Message,
+		Fields:  c.eventFields(evt),
+	}
+	c.write(r)
+	countLogged(name, evt)
+	notify(c.notifiers, r)
+	return false
+}
+
+// observe records the event as processed if it was not processed yet and is not older than the watermark.
+// The lock is only held for the bookkeeping, not while the event is written to the sinks.
+func (p *loggingPredicate) observe(evt *corev1.Event) observation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.seen == nil {
+		p.seen = newSeenEvents(defaultSeenEventsSize, time.Time{})
+	}
+	o := p.seen.classify(evt)
+	if o != observedNew {
+		return o
+	}
+	p.lastVersion = evt.ResourceVersion
+	if ts := lastSeen(evt); ts.After(p.lastTimestamp) {
+		p.lastTimestamp = ts
+	}
+	return o
+}
+
+// write writes the record to the sinks or logs it with the event logger if no sinks are defined.
+func (s *snapshot) write(r *sink.Record) {
+	if s.sinks == nil {
+		l := eventLog
+		if len(s.logFields) == 0 {
+			l = l.WithValues(r.Fields...)
 		} else {
-			m := structs.Map(evt)
-			eventLogger = eventLog
-			for _, lf := range p.Config.logFields {
-				if len(lf.Path) > 0 {
-					val, ok, err := unstructured.NestedFieldNoCopy(m, lf.Path...)
-					if ok && err == nil {
-						eventLogger = eventLogger.WithValues(lf.Name, val)
-					}
-				} else if lf.Value != nil {
-					eventLogger = eventLogger.WithValues(lf.Name, *lf.Value)
-				}
+			// the custom log fields are added one by one
+			for i := 0; i+1 < len(r.Fields); i += 2 {
+				l = l.WithValues(r.Fields[i], r.Fields[i+1])
 			}
 		}
-
-		eventLogger.Info(evt.Message)
+		l.Info(r.Message)
+	} else {
+		s.sinks.Send(context.Background(), r)
 	}
-	return false
 }
 
-func getLatestRevision(ctx context.Context, cl client.Client, namespace string) (string, error) {
-	eventList := &corev1.EventList{}
-	opts := []client.ListOption{
-		client.Limit(0),
-		client.InNamespace(namespace),
+// eventFields returns the log fields of the event as alternating key value pairs.
+func (s *snapshot) eventFields(evt *corev1.Event) []any {
+	if len(s.logFields) == 0 {
+		return []any{
+			"namespace", evt.Namespace,
+			"name", evt.Name,
+			"reason", evt.Reason,
+			"timestamp", metav1.Time{Time: eventTimestamp(evt)},
+			"type", evt.Type,
+			"involvedObject", evt.InvolvedObject,
+			"source", evt.Source,
+		}
 	}
 
-	err := cl.List(ctx, eventList, opts...)
-	if err != nil {
-		return "", err
+	var fields []any
+	// the maps of the event are only created if needed, by the json paths and templates or the deprecated paths
+	var eventMap, structMap map[string]any
+	for _, lf := range s.logFields {
+		switch {
+		case lf.extractor != nil:
+			if eventMap == nil {
+				var err error
+				if eventMap, err = logfield.EventMap(evt); err != nil {
+					continue
+				}
+			}
+			if val, ok := lf.extractor.Extract(eventMap); ok {
+				fields = append(fields, lf.name, val)
+			}
+		case len(lf.path) > 0:
+			if structMap == nil {
+				structMap = structs.Map(evt)
+			}
+			val, ok, err := unstructured.NestedFieldNoCopy(structMap, lf.path...)
+			if ok && err == nil {
+				fields = append(fields, lf.name, val)
+			}
+		case lf.value != nil:
+			fields = append(fields, lf.name, *lf.value)
+		}
+	}
+	return fields
+}
+
+// eventTimestamp returns the last timestamp of the event, the first timestamp or the event time if not set.
+func eventTimestamp(evt *corev1.Event) time.Time {
+	ts := evt.LastTimestamp
+	if ts.IsZero() {
+		ts = evt.FirstTimestamp
+	}
+	if ts.IsZero() {
+		return evt.EventTime.Time
 	}
-	return eventList.ResourceVersion, nil
+	return ts.Time
 }
 
-// SetupWithManager setup with manager.
-func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, namespace string) error {
-	cl, err := client.New(mgr.GetConfig(), client.Options{})
+// SetupWithManager setup with manager. The controller is started once the logger is elected as leader, the events
+// before are stale. If a checkpoint is configured, the events since the stored checkpoint are replayed once the
+// filter is applied.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.predicate = &loggingPredicate{Config: r.Config}
+	lc := &leaderController{predicate: r.predicate}
+
+	if r.Checkpoint != nil {
+		cl, err := client.New(mgr.GetConfig(), client.Options{})
+		if err != nil {
+			return err
+		}
+		store := &checkpointStore{
+			client:    cl,
+			key:       client.ObjectKey{Namespace: r.Config.podNamespace, Name: r.Checkpoint.ConfigMap},
+			interval:  r.Checkpoint.Interval,
+			predicate: r.predicate,
+			log:       r.Log.WithName("checkpoint"),
+			loaded:    make(chan struct{}),
+		}
+		lc.loaded = store.loaded
+		if err := mgr.Add(store); err != nil {
+			return err
+		}
+	}
+
+	obj := r.newObject()
+	gvk, err := apiutil.GVKForObject(obj, mgr.GetScheme())
 	if err != nil {
 		return err
 	}
-
-	lv, err := getLatestRevision(context.Background(), cl, namespace)
+	opts := controller.Options{Reconciler: r}
+	opts.DefaultFromConfig(mgr.GetControllerOptions())
+	lc.Controller, err = controller.NewUnmanaged(strings.ToLower(gvk.Kind), opts)
 	if err != nil {
 		return err
 	}
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&eventloggerv1.EventLogger{}).
-		Watches(&corev1.Event{}, &handler.Funcs{}).
-		WithEventFilter(&loggingPredicate{Config: r.Config, lastVersion: lv}).
-		Complete(r)
+	evt, _ := eventObjects(r.EventAPI)
+	if err := lc.Watch(source.Kind(mgr.GetCache(), client.Object(obj), &handler.EnqueueRequestForObject{},
+		r.predicate)); err != nil {
+		return err
+	}
+	if err := lc.Watch(source.Kind(mgr.GetCache(), evt, &handler.Funcs{}, r.predicate)); err != nil {
+		return err
+	}
+	return mgr.Add(lc)
+}
+
+// leaderController starts the controller once the logger is elected as leader. The processed events are tracked
+// from then on, the events before were logged by the previous leader or are stale.
+type leaderController struct {
+	controller.Controller
+	predicate *loggingPredicate
+	// loaded is closed once the checkpoint store loaded the checkpoint, nil if no checkpoint is configured
+	loaded <-chan struct{}
+}
+
+// Start implements manager.Runnable. If a checkpoint is configured, the controller is started once the checkpoint
+// to resume from is loaded.
+func (c *leaderController) Start(ctx context.Context) error {
+	if c.loaded == nil {
+		c.predicate.start(nil)
+	} else {
+		select {
+		case <-c.loaded:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return c.Controller.Start(ctx)
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the active logger logs the events.
+func (*leaderController) NeedLeaderElection() bool {
+	return true
+}
+
+// start resets the processed events when the logger becomes the leader. The events before the current time are
+// stale, or the events before the checkpoint if the logger resumes from one.
+func (p *loggingPredicate) start(from *checkpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	watermark := time.Now()
+	if from != nil {
+		watermark = from.timestamp
+		p.resumeFrom = from
+	}
+	p.seen = newSeenEvents(defaultSeenEventsSize, watermark)
 }
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"time"
@@ -9,6 +10,7 @@ import (
 	gm "go.uber.org/mock/gomock"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -23,6 +25,7 @@ import (
 	"github.com/bakito/k8s-event-logger-operator/pkg/filter"
 	mc "github.com/bakito/k8s-event-logger-operator/pkg/mocks/client"
 	ml "github.com/bakito/k8s-event-logger-operator/pkg/mocks/logr"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -75,20 +78,147 @@ var _ = Describe("Logging", func() {
 		})
 
 		Context("Update", func() {
-			It("should update an existing if LoggerMode is disabled", func() {
+			var sw *mc.MockSubResourceWriter
+			BeforeEach(func() {
+				sw = mc.NewMockSubResourceWriter(mockCtrl)
+				r.PodName = "logger-pod"
+			})
+			It("should patch the status if LoggerMode is disabled", func() {
 				r.LoggerMode = false
-				cl.EXPECT().Get(gm.Any(), gm.Any(), gm.Any())
-				cl.EXPECT().Update(gm.Any(), gm.Any(), gm.Any())
+				cl.EXPECT().Get(gm.Any(), gm.Any(), gm.Any()).DoAndReturn(getEventLogger(""))
+				cl.EXPECT().Status().Return(sw)
+				sw.EXPECT().Patch(gm.Any(), gm.Any(), gm.Any()).
+					DoAndReturn(func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption,
+					) error {
+						el := obj.(*apiv1.EventLogger)
+						Ω(el.Status.OperatorVersion).ShouldNot(BeEmpty())
+						Ω(meta.IsStatusConditionTrue(el.Status.Conditions, apiv1.ConditionFilterApplied)).Should(BeTrue())
+						return nil
+					})
 				_, err := r.Reconcile(ctx, req)
 				Ω(err).ShouldNot(HaveOccurred())
-				Ω(r.Config.filter).ShouldNot(BeNil())
+				Ω(r.Config.load().filter).ShouldNot(BeNil())
 			})
-			It("should not update an existing if LoggerMode is enabled", func() {
+			It("should not update if the cr could not be read", func() {
 				r.LoggerMode = true
 				cl.EXPECT().Get(gm.Any(), gm.Any(), gm.Any())
 				_, err := r.Reconcile(ctx, req)
 				Ω(err).ShouldNot(HaveOccurred())
-				Ω(r.Config.filter).ShouldNot(BeNil())
+				Ω(r.Config.load().filter).ShouldNot(BeNil())
+			})
+			It("should patch the filter status only if LoggerMode is enabled", func() {
+				r.LoggerMode = true
+				cl.EXPECT().Get(gm.Any(), gm.Any(), gm.Any()).DoAndReturn(getEventLogger(""))
+				cl.EXPECT().Status().Return(sw)
+				sw.EXPECT().Patch(gm.Any(), gm.Any(), gm.Any()).
+					DoAndReturn(func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption,
+					) error {
+						el := obj.(*apiv1.EventLogger)
+						Ω(el.Status.LoggerPod).Should(Equal("logger-pod"))
+						Ω(el.Status.LastFilterApplied).ShouldNot(BeNil())
+						Ω(el.Status.OperatorVersion).Should(BeEmpty())
+						c := meta.FindStatusCondition(el.Status.Conditions, apiv1.ConditionFilterApplied)
+						Ω(c).ShouldNot(BeNil())
+						Ω(c.Status).Should(Equal(metav1.ConditionTrue))
+						Ω(c.ObservedGeneration).Should(Equal(int64(2)))
+						return nil
+					})
+				_, err := r.Reconcile(ctx, req)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+			It("should retry the patch on conflicts", func() {
+				r.LoggerMode = true
+				cl.EXPECT().Get(gm.Any(), gm.Any(), gm.Any()).DoAndReturn(getEventLogger("")).Times(2)
+				cl.EXPECT().Status().Return(sw).Times(2)
+				gm.InOrder(
+					sw.EXPECT().Patch(gm.Any(), gm.Any(), gm.Any()).
+						Return(errors.NewConflict(apiv1.GroupVersion.WithResource("eventloggers").GroupResource(), "foo", nil)),
+					sw.EXPECT().Patch(gm.Any(), gm.Any(), gm.Any()),
+				)
+				_, err := r.Reconcile(ctx, req)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+			It("should not update the filter status if another pod is the active logger", func() {
+				r.LoggerMode = true
+				cl.EXPECT().Get(gm.Any(), gm.Any(), gm.Any()).DoAndReturn(getEventLogger("other-pod"))
+				_, err := r.Reconcile(ctx, req)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+			It("should take over the filter status if elected as leader", func() {
+				r.LoggerMode = true
+				r.LeaderElection = true
+				cl.EXPECT().Get(gm.Any(), gm.Any(), gm.Any()).DoAndReturn(getEventLogger("other-pod"))
+				cl.EXPECT().Status().Return(sw)
+				sw.EXPECT().Patch(gm.Any(), gm.Any(), gm.Any()).
+					DoAndReturn(func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption,
+					) error {
+						Ω(obj.(*apiv1.EventLogger).Status.LoggerPod).Should(Equal("logger-pod"))
+						return nil
+					})
+				_, err := r.Reconcile(ctx, req)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+			It("should keep the current filter if the cr is invalid", func() {
+				r.LoggerMode = true
+				current := &snapshot{name: "foo", filter: filter.Always}
+				r.Config = testConfig(current)
+				cl.EXPECT().Get(gm.Any(), gm.Any(), gm.Any()).DoAndReturn(
+					func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+						el := obj.(*apiv1.EventLogger)
+						el.ResourceVersion = "1"
+						el.Spec.Kinds = []apiv1.Kind{{Name: "Pod", MatchingPatterns: []string{"(unclosed"}}}
+						return nil
+					})
+				cl.EXPECT().Status().Return(sw)
+				sw.EXPECT().Patch(gm.Any(), gm.Any(), gm.Any()).
+					DoAndReturn(func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption,
+					) error {
+						c := meta.FindStatusCondition(obj.(*apiv1.EventLogger).Status.Conditions, apiv1.ConditionFilterApplied)
+						Ω(c).ShouldNot(BeNil())
+						Ω(c.Status).Should(Equal(metav1.ConditionFalse))
+						Ω(c.Message).Should(ContainSubstring("spec.kinds[0].matchingPatterns[0]"))
+						return nil
+					})
+				_, err := r.Reconcile(ctx, req)
+				Ω(errors.IsInvalid(err)).Should(BeTrue())
+				Ω(r.Config.load()).Should(BeIdenticalTo(current))
+			})
+			It("should replay the checkpoint with the current filter if the cr is invalid", func() {
+				r.LoggerMode = true
+				var buf bytes.Buffer
+				r.Config = testConfig(&snapshot{
+					name:   "foo",
+					filter: filter.Always,
+					sinks:  sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+				})
+				since := time.Now().Add(-time.Hour)
+				r.predicate = &loggingPredicate{
+					Config:     r.Config,
+					resumeFrom: &checkpoint{resourceVersion: "1", timestamp: since},
+					seen:       newSeenEvents(defaultSeenEventsSize, since),
+				}
+				cl.EXPECT().Get(gm.Any(), gm.Any(), gm.Any()).DoAndReturn(
+					func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+						el := obj.(*apiv1.EventLogger)
+						el.ResourceVersion = "1"
+						el.Spec.Kinds = []apiv1.Kind{{Name: "Pod", MatchingPatterns: []string{"(unclosed"}}}
+						return nil
+					})
+				cl.EXPECT().List(gm.Any(), gm.Any()).DoAndReturn(
+					func(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+						list.(*corev1.EventList).Items = []corev1.Event{{
+							ObjectMeta:    metav1.ObjectMeta{Name: "evt", UID: "evt", ResourceVersion: "2"},
+							Message:       "since the checkpoint",
+							LastTimestamp: metav1.Now(),
+						}}
+						return nil
+					})
+				cl.EXPECT().Status().Return(sw)
+				sw.EXPECT().Patch(gm.Any(), gm.Any(), gm.Any())
+				_, err := r.Reconcile(ctx, req)
+				Ω(errors.IsInvalid(err)).Should(BeTrue())
+				Ω(r.predicate.replayPending()).Should(BeFalse())
+				Ω(buf.String()).Should(ContainSubstring("since the checkpoint"))
 			})
 		})
 
@@ -108,6 +238,10 @@ var _ = Describe("Logging", func() {
 			mockSink = ml.NewMockLogSink(mockCtrl)
 			mockSink.EXPECT().Init(gm.Any())
 			mockSink.EXPECT().Enabled(gm.Any()).AnyTimes().Return(true)
+			orig := eventLog
+			DeferCleanup(func() {
+				eventLog = orig
+			})
 			eventLog = logr.New(mockSink)
 		})
 
@@ -121,25 +255,27 @@ var _ = Describe("Logging", func() {
 			mockSink.EXPECT().WithValues().Times(0)
 
 			lp := &loggingPredicate{
-				lastVersion: "2",
-				Config:      &Config{filter: filter.Always},
+				Config: testConfig(&snapshot{filter: filter.Always}),
 			}
 
 			lp.logEvent(&corev1.Pod{})
 		})
-		It("should log nothing if resource version does not match", func() {
+		It("should log nothing if the event was processed already", func() {
 			mockSink.EXPECT().WithValues().Times(0)
 
-			lp := &loggingPredicate{
-				lastVersion: "2",
-				Config:      &Config{filter: filter.Always},
-			}
-
-			lp.logEvent(&corev1.Event{
+			evt := &corev1.Event{
 				ObjectMeta: metav1.ObjectMeta{
+					UID:             "uid",
 					ResourceVersion: "1",
 				},
-			})
+			}
+			lp := &loggingPredicate{
+				seen:   newSeenEvents(defaultSeenEventsSize, time.Time{}),
+				Config: testConfig(&snapshot{filter: filter.Always}),
+			}
+			lp.seen.observe(evt)
+
+			lp.logEvent(evt)
 		})
 		It("should log one message with 14 fields", func() {
 			childSink := ml.NewMockLogSink(mockCtrl)
@@ -149,8 +285,7 @@ var _ = Describe("Logging", func() {
 			childSink.EXPECT().Info(gm.Any(), gm.Any()).Times(1)
 
 			lp := &loggingPredicate{
-				lastVersion: "2",
-				Config:      &Config{filter: filter.Always},
+				Config: testConfig(&snapshot{filter: filter.Always}),
 			}
 
 			lp.logEvent(&corev1.Event{
@@ -170,15 +305,15 @@ var _ = Describe("Logging", func() {
 			childSink.EXPECT().Info(gm.Any(), gm.Any()).Times(1)
 
 			lp := &loggingPredicate{
-				Config: &Config{
+				Config: testConfig(&snapshot{
 					filter: filter.Always,
-					logFields: []apiv1.LogField{
-						{Name: "type", Path: []string{"Type"}},
-						{Name: "name", Path: []string{"InvolvedObject", "Name"}},
-						{Name: "kind", Path: []string{"InvolvedObject", "Kind"}},
-						{Name: "reason", Path: []string{"Reason"}},
-					},
-				},
+					logFields: mustNewLogFields(
+						apiv1.LogField{Name: "type", Path: []string{"Type"}},
+						apiv1.LogField{Name: "name", Path: []string{"InvolvedObject", "Name"}},
+						apiv1.LogField{Name: "kind", Path: []string{"InvolvedObject", "Kind"}},
+						apiv1.LogField{Name: "reason", Path: []string{"Reason"}},
+					),
+				}),
 			}
 
 			lp.logEvent(&corev1.Event{
@@ -194,6 +329,58 @@ var _ = Describe("Logging", func() {
 				Reason: "",
 			})
 		})
+		It("should log the fields of json paths, templates and values", func() {
+			mockSink.EXPECT().WithValues(gm.Any()).Times(0)
+			var buf bytes.Buffer
+
+			lp := &loggingPredicate{
+				Config: testConfig(&snapshot{
+					filter: filter.Always,
+					logFields: mustNewLogFields(
+						apiv1.LogField{Name: "name", JSONPath: ".involvedObject.name"},
+						apiv1.LogField{Name: "owners", JSONPath: ".metadata.ownerReferences[*].name"},
+						apiv1.LogField{Name: "series", JSONPath: ".series.count"},
+						apiv1.LogField{Name: "object", Template: "{{ .involvedObject.kind }}/{{ .involvedObject.name }}"},
+						apiv1.LogField{Name: "cluster", Value: new("prod")},
+					),
+					sinks: sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+				}),
+			}
+
+			lp.logEvent(&corev1.Event{
+				ObjectMeta: metav1.ObjectMeta{
+					ResourceVersion: "3",
+					OwnerReferences: []metav1.OwnerReference{{Name: "a"}, {Name: "b"}},
+				},
+				InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "test-io-name"},
+			})
+			Ω(buf.String()).Should(ContainSubstring(`"name":"test-io-name"`))
+			Ω(buf.String()).Should(ContainSubstring(`"owners":["a","b"]`))
+			Ω(buf.String()).Should(ContainSubstring(`"object":"Pod/test-io-name"`))
+			Ω(buf.String()).Should(ContainSubstring(`"cluster":"prod"`))
+			Ω(buf.String()).ShouldNot(ContainSubstring(`"series"`))
+		})
+		It("should send the event to the sinks", func() {
+			mockSink.EXPECT().WithValues(gm.Any()).Times(0)
+			var buf bytes.Buffer
+
+			lp := &loggingPredicate{
+				Config: testConfig(&snapshot{
+					filter: filter.Always,
+					sinks:  sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+				}),
+			}
+
+			lp.logEvent(&corev1.Event{
+				ObjectMeta: metav1.ObjectMeta{
+					ResourceVersion: "3",
+					Name:            "test-event-name",
+				},
+				Message: "test-message",
+			})
+			Ω(buf.String()).Should(ContainSubstring(`"msg":"test-message"`))
+			Ω(buf.String()).Should(ContainSubstring(`"name":"test-event-name"`))
+		})
 		It("should resolve timestamp", func() {
 			childSink := ml.NewMockLogSink(mockCtrl)
 			childSink.EXPECT().Init(gm.Any()).AnyTimes()
@@ -209,7 +396,7 @@ var _ = Describe("Logging", func() {
 			childSink.EXPECT().Info(gm.Any(), gm.Any()).Times(3)
 
 			lp := &loggingPredicate{
-				Config: &Config{filter: filter.Always},
+				Config: testConfig(&snapshot{filter: filter.Always}),
 			}
 
 			lp.logEvent(&corev1.Event{
@@ -235,12 +422,12 @@ var _ = Describe("Logging", func() {
 		DescribeTable("the > inequality",
 			func(config apiv1.EventLoggerSpec, event corev1.Event, expected bool, description string) {
 				data := &sld{config, event, expected, description}
-				lp := &loggingPredicate{Config: &Config{filter: newFilter(data.Config)}}
+				lp := &loggingPredicate{Config: testConfig(&snapshot{filter: mustNewFilter(data.Config, nil)})}
 
 				_, err := json.Marshal(&data)
 				Ω(err).ShouldNot(HaveOccurred())
-				Ω(lp.Config.filter.Match(&data.Event)).Should(Equal(expected))
-				Ω(lp.Config.filter.String()).Should(Equal(data.Description))
+				Ω(lp.Config.load().filter.Match(&data.Event)).Should(Equal(expected))
+				Ω(lp.Config.load().filter.String()).Should(Equal(data.Description))
 			},
 			Entry("1",
 				apiv1.EventLoggerSpec{},
@@ -465,6 +652,39 @@ var _ = Describe("Logging", func() {
 				false,
 				"( ( ( Kind == 'Pod' AND Reason NOT in [Created] AND Reason in [Created] ) ) )",
 			),
+			Entry("26",
+				apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{
+					Name:       "Pod",
+					Expression: `event.count > 5 && event.reason.startsWith("Failed")`,
+				}}},
+				corev1.Event{InvolvedObject: corev1.ObjectReference{Kind: "Pod"}, Reason: "FailedMount", Count: 6},
+				true,
+				`( ( ( Kind == 'Pod' AND CEL 'event.count > 5 && event.reason.startsWith("Failed")' ) ) )`,
+			),
+			Entry("27",
+				apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{
+					Name:       "Pod",
+					Expression: `event.count > 5 && event.reason.startsWith("Failed")`,
+				}}},
+				corev1.Event{InvolvedObject: corev1.ObjectReference{Kind: "Pod"}, Reason: "FailedMount", Count: 5},
+				false,
+				`( ( ( Kind == 'Pod' AND CEL 'event.count > 5 && event.reason.startsWith("Failed")' ) ) )`,
+			),
+			Entry("28",
+				apiv1.EventLoggerSpec{Expression: `event.type == "Warning"`},
+				corev1.Event{InvolvedObject: corev1.ObjectReference{Kind: "Pod"}, Type: "Warning"},
+				true,
+				`( true AND CEL 'event.type == "Warning"' )`,
+			),
+			Entry("29",
+				apiv1.EventLoggerSpec{
+					Kinds:      []apiv1.Kind{{Name: "Pod"}},
+					Expression: `event.type == "Warning"`,
+				},
+				corev1.Event{InvolvedObject: corev1.ObjectReference{Kind: "Pod"}, Type: "Normal"},
+				false,
+				`( ( ( ( Kind == 'Pod' ) ) ) AND CEL 'event.type == "Warning"' )`,
+			),
 		)
 	})
 
@@ -485,11 +705,7 @@ var _ = Describe("Logging", func() {
 		)
 		BeforeEach(func() {
 			lp = &loggingPredicate{
-				Config: &Config{
-					watchNamespace: testNamespace,
-					podNamespace:   "",
-					name:           testName,
-				},
+				Config: ConfigFor(testName, "", testNamespace),
 			}
 			el = &apiv1.EventLogger{
 				ObjectMeta: metav1.ObjectMeta{
@@ -507,13 +723,13 @@ var _ = Describe("Logging", func() {
 				Ω(lp.Create(event.CreateEvent{Object: el})).Should(BeFalse())
 			})
 			It("should match for reconciling with podNamespace", func() {
-				lp.Config.watchNamespace = ""
+				lp.Config.watchNamespaces = nil
 				lp.Config.podNamespace = testNamespace
 				Ω(lp.Create(event.CreateEvent{Object: el})).Should(BeTrue())
 			})
 			It("should match for reconciling with podNamespace", func() {
 				el.Name = "foo"
-				lp.Config.watchNamespace = ""
+				lp.Config.watchNamespaces = nil
 				lp.Config.podNamespace = testNamespace
 				Ω(lp.Create(event.CreateEvent{Object: el})).Should(BeFalse())
 			})
@@ -531,13 +747,13 @@ var _ = Describe("Logging", func() {
 				Ω(lp.Update(event.UpdateEvent{ObjectNew: el})).Should(BeFalse())
 			})
 			It("should match for reconciling with podNamespace", func() {
-				lp.Config.watchNamespace = ""
+				lp.Config.watchNamespaces = nil
 				lp.Config.podNamespace = testNamespace
 				Ω(lp.Update(event.UpdateEvent{ObjectNew: el})).Should(BeTrue())
 			})
 			It("should match for reconciling with podNamespace", func() {
 				el.Name = "foo"
-				lp.Config.watchNamespace = ""
+				lp.Config.watchNamespaces = nil
 				lp.Config.podNamespace = testNamespace
 				Ω(lp.Update(event.UpdateEvent{ObjectNew: el})).Should(BeFalse())
 			})
@@ -555,13 +771,13 @@ var _ = Describe("Logging", func() {
 				Ω(lp.Delete(event.DeleteEvent{Object: el})).Should(BeFalse())
 			})
 			It("should match for reconciling with podNamespace", func() {
-				lp.Config.watchNamespace = ""
+				lp.Config.watchNamespaces = nil
 				lp.Config.podNamespace = testNamespace
 				Ω(lp.Delete(event.DeleteEvent{Object: el})).Should(BeTrue())
 			})
 			It("should match for reconciling with podNamespace", func() {
 				el.Name = "foo"
-				lp.Config.watchNamespace = ""
+				lp.Config.watchNamespaces = nil
 				lp.Config.podNamespace = testNamespace
 				Ω(lp.Delete(event.DeleteEvent{Object: el})).Should(BeFalse())
 			})
@@ -571,18 +787,6 @@ var _ = Describe("Logging", func() {
 			})
 		})
 	})
-	Context("getLatestRevision", func() {
-		It("should find the last revision", func() {
-			cl.EXPECT().
-				List(gm.Any(), gm.Any(), gm.Any()).
-				Do(func(_ context.Context, el *corev1.EventList, _ ...client.ListOption) {
-					el.ResourceVersion = "3"
-				})
-			rev, err := getLatestRevision(ctx, cl, "")
-			Ω(err).ShouldNot(HaveOccurred())
-			Ω(rev).Should(Equal("3"))
-		})
-	})
 })
 
 type sld struct {
@@ -592,6 +796,38 @@ type sld struct {
 	Description string                `json:"description"`
 }
 
+// testConfig returns a config with the snapshot applied.
+func testConfig(s *snapshot) *Config {
+	c := &Config{}
+	c.current.Store(s)
+	return c
+}
+
+// mustNewLogFields returns the compiled log fields.
+func mustNewLogFields(specs ...apiv1.LogField) []logField {
+	fields, err := newLogFields(specs)
+	Ω(err).ShouldNot(HaveOccurred())
+	return fields
+}
+
+func mustNewFilter(c apiv1.EventLoggerSpec, ol objectLabels) filter.Filter {
+	f, err := newFilter(c, ol)
+	Ω(err).ShouldNot(HaveOccurred())
+	return f
+}
+
+func mustNewFilterForKind(k apiv1.Kind, ol objectLabels) filter.Filter {
+	f, err := newFilterForKind(k, ol)
+	Ω(err).ShouldNot(HaveOccurred())
+	return f
+}
+
+func mustNewKindClauses(c apiv1.EventLoggerSpec, ol objectLabels) []clauses {
+	kinds, err := newKindClauses(c, ol)
+	Ω(err).ShouldNot(HaveOccurred())
+	return kinds
+}
+
 func repeat(m gm.Matcher, times int) []any {
 	var list []any
 	for range times {
@@ -599,3 +835,13 @@ func repeat(m gm.Matcher, times int) []any {
 	}
 	return list
 }
+
+func getEventLogger(loggerPod string) func(context.Context, types.NamespacedName, client.Object, ...client.GetOption) error {
+	return func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+		el := obj.(*apiv1.EventLogger)
+		el.ResourceVersion = "1"
+		el.Generation = 2
+		el.Status.LoggerPod = loggerPod
+		return nil
+	}
+}
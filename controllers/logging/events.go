@@ -0,0 +1,65 @@
+package logging
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+)
+
+// toEvent returns the object as core event. Events of the events.k8s.io api are normalized into a core event.
+func toEvent(obj runtime.Object) (*corev1.Event, bool) {
+	switch e := obj.(type) {
+	case *corev1.Event:
+		return e, true
+	case *eventsv1.Event:
+		return fromEventsV1(e), true
+	}
+	return nil, false
+}
+
+// fromEventsV1 converts an events.k8s.io event into a core event the same way the api server does.
+func fromEventsV1(e *eventsv1.Event) *corev1.Event {
+	evt := &corev1.Event{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Event",
+		},
+		ObjectMeta:          e.ObjectMeta,
+		InvolvedObject:      e.Regarding,
+		Reason:              e.Reason,
+		Message:             e.Note,
+		Source:              e.DeprecatedSource,
+		FirstTimestamp:      e.DeprecatedFirstTimestamp,
+		LastTimestamp:       e.DeprecatedLastTimestamp,
+		Count:               e.DeprecatedCount,
+		Type:                e.Type,
+		EventTime:           e.EventTime,
+		Action:              e.Action,
+		Related:             e.Related,
+		ReportingController: e.ReportingController,
+		ReportingInstance:   e.ReportingInstance,
+	}
+	if evt.Source.Component == "" {
+		evt.Source.Component = e.ReportingController
+	}
+	if e.Series != nil {
+		evt.Series = &corev1.EventSeries{
+			Count:            e.Series.Count,
+			LastObservedTime: e.Series.LastObservedTime,
+		}
+		evt.Count = e.Series.Count
+	}
+	return evt
+}
+
+// eventObjects returns the object and list to watch the events of the api.
+func eventObjects(api eventloggerv1.EventAPI) (client.Object, client.ObjectList) {
+	if api == eventloggerv1.EventAPIEventsV1 {
+		return &eventsv1.Event{}, &eventsv1.EventList{}
+	}
+	return &corev1.Event{}, &corev1.EventList{}
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/filter"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+	var evt *eventsv1.Event
+	BeforeEach(func() {
+		evt = &eventsv1.Event{
+			ObjectMeta:          metav1.ObjectMeta{Namespace: testNamespace, Name: "evt", ResourceVersion: "3"},
+			EventTime:           metav1.NewMicroTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			Series:              &eventsv1.EventSeries{Count: 5},
+			ReportingController: "example.com/controller",
+			ReportingInstance:   "controller-1",
+			Action:              "Scheduling",
+			Reason:              "FailedScheduling",
+			Regarding:           corev1.ObjectReference{Kind: "Pod", Name: "my-pod"},
+			Related:             &corev1.ObjectReference{Kind: "Node", Name: "my-node"},
+			Note:                "0/3 nodes are available",
+			Type:                corev1.EventTypeWarning,
+		}
+	})
+
+	Context("toEvent", func() {
+		It("should return core events", func() {
+			e := &corev1.Event{}
+			converted, ok := toEvent(e)
+			Ω(ok).Should(BeTrue())
+			Ω(converted).Should(BeIdenticalTo(e))
+		})
+		It("should not convert other objects", func() {
+			_, ok := toEvent(&corev1.Pod{})
+			Ω(ok).Should(BeFalse())
+		})
+		It("should normalize events.k8s.io events", func() {
+			e, ok := toEvent(evt)
+			Ω(ok).Should(BeTrue())
+			Ω(e.Name).Should(Equal("evt"))
+			Ω(e.ResourceVersion).Should(Equal("3"))
+			Ω(e.InvolvedObject).Should(Equal(evt.Regarding))
+			Ω(e.Related).Should(Equal(evt.Related))
+			Ω(e.Message).Should(Equal(evt.Note))
+			Ω(e.Reason).Should(Equal(evt.Reason))
+			Ω(e.Type).Should(Equal(evt.Type))
+			Ω(e.Action).Should(Equal(evt.Action))
+			Ω(e.ReportingController).Should(Equal(evt.ReportingController))
+			Ω(e.ReportingInstance).Should(Equal(evt.ReportingInstance))
+			Ω(e.Source.Component).Should(Equal(evt.ReportingController))
+			Ω(e.EventTime).Should(Equal(evt.EventTime))
+			Ω(e.Series).ShouldNot(BeNil())
+			Ω(e.Series.Count).Should(Equal(int32(5)))
+			Ω(e.Count).Should(Equal(int32(5)))
+		})
+	})
+
+	Context("logEvent", func() {
+		It("should filter and log events.k8s.io events", func() {
+			var buf bytes.Buffer
+			spec := apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{Name: "Pod", Reasons: []string{"FailedScheduling"}}}}
+			lp := &loggingPredicate{
+				Config: testConfig(&snapshot{
+					filter: mustNewFilter(spec, nil),
+					logFields: mustNewLogFields(
+						apiv1.LogField{Name: "controller", Path: []string{"ReportingController"}},
+						apiv1.LogField{Name: "node", Path: []string{"Related", "Name"}},
+						apiv1.LogField{Name: "relatedNode", JSONPath: ".related.name"},
+					),
+					sinks: sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+				}),
+			}
+			lp.logEvent(evt)
+			Ω(buf.String()).Should(ContainSubstring(`"msg":"0/3 nodes are available"`))
+			Ω(buf.String()).Should(ContainSubstring(`"controller":"example.com/controller"`))
+			Ω(buf.String()).Should(ContainSubstring(`"node":"my-node"`))
+			Ω(buf.String()).Should(ContainSubstring(`"relatedNode":"my-node"`))
+		})
+		It("should skip not matching events.k8s.io events", func() {
+			var buf bytes.Buffer
+			lp := &loggingPredicate{
+				Config: testConfig(&snapshot{
+					filter: filter.Never,
+					sinks:  sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+				}),
+			}
+			lp.logEvent(evt)
+			Ω(buf.String()).Should(BeEmpty())
+		})
+	})
+})
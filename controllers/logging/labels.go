@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+)
+
+// labelInformerSyncTimeout the max time to wait for the metadata informer of a kind with a label selector to sync
+const labelInformerSyncTimeout = 30 * time.Second
+
+// objectLabels reads the labels of involved objects.
+type objectLabels interface {
+	// Labels returns the labels of the referenced object, false if the object could not be read
+	Labels(ref corev1.ObjectReference) (labels.Set, bool)
+}
+
+// metadataCache the informer cache the metadata of the involved objects is read from.
+type metadataCache interface {
+	client.Reader
+	GetInformer(ctx context.Context, obj client.Object, opts ...cache.InformerGetOption) (cache.Informer, error)
+}
+
+// newObjectLabels creates a new objectLabels reading the metadata of the involved objects from the cache.
+// Only the metadata of the objects is cached.
+func newObjectLabels(c metadataCache) objectLabels {
+	if c == nil {
+		return nil
+	}
+	return &metadataLabels{cache: c}
+}
+
+type metadataLabels struct {
+	cache metadataCache
+}
+
+// Labels implements objectLabels. The labels are only read from a synced informer, the informer handling the events
+// must not be blocked. The informers of the kinds with a label selector are started when the filter is applied, the
+// first lookup of any other kind or version starts a new metadata informer, the labels are not known until it is
+// synced.
+func (m *metadataLabels) Labels(ref corev1.ObjectReference) (labels.Set, bool) {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(ref.GroupVersionKind())
+	log := eventLog.V(1).WithValues("kind", ref.Kind, "namespace", ref.Namespace, "name", ref.Name)
+
+	ctx := context.Background()
+	inf, err := m.cache.GetInformer(ctx, obj, cache.BlockUntilSynced(false))
+	if err != nil {
+		log.Info("could not read labels of involved object", "error", err.Error())
+		return nil, false
+	}
+	if !inf.HasSynced() {
+		log.Info("labels of involved object are not cached yet")
+		return nil, false
+	}
+	if err := m.cache.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, obj); err != nil {
+		log.Info("could not read labels of involved object", "error", err.Error())
+		return nil, false
+	}
+	return obj.GetLabels(), true
+}
+
+// startLabelInformers starts the metadata informers of the kinds with a label selector and waits until they are
+// synced, so that the labels of the involved objects are known once the filter is applied. The kinds without api
+// group are mapped to the core group, the informers of kinds that can not be mapped are started by the first lookup.
+func startLabelInformers(
+	ctx context.Context,
+	c metadataCache,
+	mapper meta.RESTMapper,
+	kinds []eventloggerv1.Kind,
+	log logr.Logger,
+) {
+	for _, k := range kinds {
+		if k.LabelSelector == nil {
+			continue
+		}
+		gk := schema.GroupKind{Group: ptr.Deref(k.APIGroup, ""), Kind: k.Name}
+		mapping, err := mapper.RESTMapping(gk)
+		if err != nil {
+			log.Info("could not map the kind of the label selector", "kind", gk.String(), "error", err.Error())
+			continue
+		}
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(mapping.GroupVersionKind)
+		sctx, cancel := context.WithTimeout(ctx, labelInformerSyncTimeout)
+		if _, err := c.GetInformer(sctx, obj); err != nil {
+			log.Info("metadata informer of the label selector is not synced", "kind", gk.String(), "error", err.Error())
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,183 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type staticLabels map[string]labels.Set
+
+func (s staticLabels) Labels(ref corev1.ObjectReference) (labels.Set, bool) {
+	l, ok := s[ref.Namespace+"/"+ref.Name]
+	return l, ok
+}
+
+var _ = Describe("Labels", func() {
+	var (
+		deployment = func(namespace, name string) *corev1.Event {
+			return &corev1.Event{InvolvedObject: corev1.ObjectReference{
+				Kind:       "Deployment",
+				APIVersion: "apps/v1",
+				Namespace:  namespace,
+				Name:       name,
+			}}
+		}
+		ol = staticLabels{
+			"shop/payments-api": {"team": "payments"},
+			"shop/checkout":     {"team": "checkout"},
+		}
+	)
+
+	Context("involved object globs", func() {
+		It("should match the name and namespace globs", func() {
+			f := mustNewFilterForKind(apiv1.Kind{
+				Name:                     "Deployment",
+				InvolvedObjectNames:      []string{"payments-*", "checkout"},
+				InvolvedObjectNamespaces: []string{"sho?"},
+			}, nil)
+
+			Ω(f.Match(deployment("shop", "payments-api"))).Should(BeTrue())
+			Ω(f.Match(deployment("shop", "checkout"))).Should(BeTrue())
+			Ω(f.Match(deployment("shop", "cart"))).Should(BeFalse())
+			Ω(f.Match(deployment("shops", "checkout"))).Should(BeFalse())
+			Ω(f.String()).Should(Equal("( Kind == 'Deployment' AND InvolvedObject.Name matches [payments-*, checkout] " +
+				"AND InvolvedObject.Namespace matches [sho?] )"))
+		})
+		It("should report the mismatching clause", func() {
+			spec := apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{
+				Name:                     "Deployment",
+				InvolvedObjectNames:      []string{"payments-*"},
+				InvolvedObjectNamespaces: []string{"shop"},
+			}}}
+			kinds := mustNewKindClauses(spec, nil)
+
+			Ω(filteredBy(kinds, deployment("shop", "cart"))).Should(Equal(clauseObjectName))
+			Ω(filteredBy(kinds, deployment("other", "payments-api"))).Should(Equal(clauseObjectNamespace))
+		})
+	})
+
+	Context("label selector", func() {
+		It("should match the labels of the involved object", func() {
+			f := mustNewFilterForKind(apiv1.Kind{
+				Name:          "Deployment",
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			}, ol)
+
+			Ω(f.Match(deployment("shop", "payments-api"))).Should(BeTrue())
+			Ω(f.Match(deployment("shop", "checkout"))).Should(BeFalse())
+			Ω(f.Match(deployment("shop", "unknown"))).Should(BeFalse())
+			Ω(f.String()).Should(Equal("( Kind == 'Deployment' AND InvolvedObject.Labels matches 'team=payments' )"))
+		})
+		It("should not match without label reader", func() {
+			f := mustNewFilterForKind(apiv1.Kind{
+				Name:          "Deployment",
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			}, nil)
+
+			Ω(f.Match(deployment("shop", "payments-api"))).Should(BeFalse())
+		})
+		It("should report the label selector as mismatching clause", func() {
+			spec := apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{
+				Name:          "Deployment",
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			}}}
+
+			Ω(filteredBy(mustNewKindClauses(spec, ol), deployment("shop", "checkout"))).Should(Equal(clauseLabelSelector))
+		})
+	})
+
+	Context("newObjectLabels", func() {
+		var mc *metadataInformers
+		BeforeEach(func() {
+			mc = &metadataInformers{Reader: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "shop",
+					Name:      "payments-api",
+					Labels:    map[string]string{"team": "payments"},
+				},
+			}).Build()}
+		})
+		It("should read the labels from the object metadata", func() {
+			mc.synced = true
+			l, ok := newObjectLabels(mc).Labels(deployment("shop", "payments-api").InvolvedObject)
+			Ω(ok).Should(BeTrue())
+			Ω(l).Should(Equal(labels.Set{"team": "payments"}))
+
+			_, ok = newObjectLabels(mc).Labels(deployment("shop", "checkout").InvolvedObject)
+			Ω(ok).Should(BeFalse())
+		})
+		It("should not read the labels before the informer is synced", func() {
+			_, ok := newObjectLabels(mc).Labels(deployment("shop", "payments-api").InvolvedObject)
+			Ω(ok).Should(BeFalse())
+			Ω(mc.informers).Should(Equal(1))
+		})
+		It("should return nil without cache", func() {
+			Ω(newObjectLabels(nil)).Should(BeNil())
+		})
+	})
+
+	Context("startLabelInformers", func() {
+		It("should start the informers of the kinds with a label selector", func() {
+			mc := &metadataInformers{}
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{appsv1.SchemeGroupVersion, corev1.SchemeGroupVersion})
+			mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+			mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+			selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}
+
+			startLabelInformers(context.Background(), mc, mapper, []apiv1.Kind{
+				{Name: "Deployment", APIGroup: new("apps"), LabelSelector: selector},
+				{Name: "Pod", LabelSelector: selector},
+				{Name: "Node"},
+				{Name: "Unknown", LabelSelector: selector},
+			}, logr.Discard())
+			Ω(mc.gvks).Should(ConsistOf(
+				appsv1.SchemeGroupVersion.WithKind("Deployment"),
+				corev1.SchemeGroupVersion.WithKind("Pod"),
+			))
+		})
+	})
+})
+
+// metadataInformers a metadataCache with informers that are synced if synced is true.
+type metadataInformers struct {
+	client.Reader
+	synced    bool
+	informers int
+	gvks      []schema.GroupVersionKind
+}
+
+func (m *metadataInformers) GetInformer(
+	_ context.Context,
+	obj client.Object,
+	_ ...cache.InformerGetOption,
+) (cache.Informer, error) {
+	m.informers++
+	m.gvks = append(m.gvks, obj.GetObjectKind().GroupVersionKind())
+	return &syncedInformer{synced: m.synced}, nil
+}
+
+// syncedInformer an informer only reporting its sync state.
+type syncedInformer struct {
+	cache.Informer
+	synced bool
+}
+
+func (s *syncedInformer) HasSynced() bool {
+	return s.synced
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"fmt"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/logfield"
+)
+
+// logField a log field with its compiled json path or template.
+type logField struct {
+	name string
+	// path the deprecated path of go field names
+	path      []string
+	extractor logfield.Extractor
+	value     *string
+}
+
+// newLogFields compiles the json paths and templates of the log fields.
+func newLogFields(specs []eventloggerv1.LogField) ([]logField, error) {
+	var fields []logField
+	for _, lf := range specs {
+		f := logField{name: lf.Name, path: lf.Path, value: lf.Value}
+		var err error
+		switch {
+		case lf.JSONPath != "":
+			f.extractor, err = logfield.NewJSONPath(lf.JSONPath)
+		case lf.Template != "":
+			f.extractor, err = logfield.NewTemplate(lf.Template)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error compiling log field %q: %w", lf.Name, err)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "eventlogger"
+	labelEventLogger = "eventlogger"
+
+	// dropStale the event was not processed yet but is older than the watermark of the processed events
+	dropStale = "stale"
+	// dropNoFilter the event was received before a config was applied
+	dropNoFilter = "noFilter"
+)
+
+var (
+	eventsSeen = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_seen_total",
+		Help:      "Number of new events seen by the logger",
+	}, []string{labelEventLogger})
+	eventsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_dropped_total",
+		Help:      "Number of events dropped before they were seen, per reason",
+	}, []string{labelEventLogger, "reason"})
+	eventsMatched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_matched_total",
+		Help:      "Number of events matching the filter",
+	}, []string{labelEventLogger})
+	eventsFiltered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_filtered_total",
+		Help:      "Number of events filtered out, per filter clause",
+	}, []string{labelEventLogger, "clause"})
+	eventsSuppressed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_suppressed_total",
+		Help:      "Number of matching events suppressed by deduplication or rate limits",
+	}, []string{labelEventLogger})
+	eventsLogged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_logged_total",
+		Help:      "Number of logged events per kind, reason and type",
+	}, []string{labelEventLogger, "kind", "reason", "type"})
+	sinkErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "sink_errors_total",
+		Help:      "Number of events that could not be sent to a sink",
+	}, []string{labelEventLogger, "sink"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		eventsSeen, eventsDropped, eventsMatched, eventsFiltered, eventsSuppressed, eventsLogged, sinkErrors,
+	)
+}
+
+func countLogged(name string, evt *corev1.Event) {
+	eventsLogged.WithLabelValues(name, evt.InvolvedObject.Kind, evt.Reason, evt.Type).Inc()
+}
+
+func countSinkErrors(name string) func(sink string, events int) {
+	return func(sink string, events int) {
+		sinkErrors.WithLabelValues(name, sink).Add(float64(events))
+	}
+}
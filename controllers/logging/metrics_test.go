@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	cnst "github.com/bakito/k8s-event-logger-operator/pkg/constants"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	const name = "metrics-test"
+	var lp *loggingPredicate
+	BeforeEach(func() {
+		for _, c := range []interface{ Reset() }{
+			eventsSeen, eventsDropped, eventsMatched, eventsFiltered, eventsLogged, sinkErrors,
+		} {
+			c.Reset()
+		}
+		spec := apiv1.EventLoggerSpec{
+			Kinds: []apiv1.Kind{
+				{Name: "Pod", EventTypes: []string{"Warning"}, SkipReasons: []string{"Pulled"}},
+				{Name: "Deployment"},
+			},
+		}
+		lp = &loggingPredicate{
+			Config: testConfig(&snapshot{
+				name:        name,
+				filter:      mustNewFilter(spec, nil),
+				kindClauses: mustNewKindClauses(spec, nil),
+			}),
+		}
+	})
+
+	It("should count the seen, matched, filtered and logged events", func() {
+		lp.logEvent(newMetricsEvent("1", "Pod", "Warning", "BackOff"))
+		lp.logEvent(newMetricsEvent("2", "Pod", "Normal", "BackOff"))
+		lp.logEvent(newMetricsEvent("3", "Pod", "Warning", "Pulled"))
+		lp.logEvent(newMetricsEvent("4", "Service", "Warning", "BackOff"))
+		lp.logEvent(newMetricsEvent("5", "Deployment", "Normal", "ScalingReplicaSet"))
+
+		Ω(testutil.ToFloat64(eventsSeen.WithLabelValues(name))).Should(Equal(5.0))
+		Ω(testutil.ToFloat64(eventsMatched.WithLabelValues(name))).Should(Equal(2.0))
+		Ω(testutil.ToFloat64(eventsFiltered.WithLabelValues(name, clauseEventType))).Should(Equal(1.0))
+		Ω(testutil.ToFloat64(eventsFiltered.WithLabelValues(name, clauseSkipReason))).Should(Equal(1.0))
+		Ω(testutil.ToFloat64(eventsFiltered.WithLabelValues(name, clauseKind))).Should(Equal(1.0))
+		Ω(testutil.ToFloat64(eventsLogged.WithLabelValues(name, "Pod", "BackOff", "Warning"))).Should(Equal(1.0))
+		Ω(testutil.ToFloat64(eventsLogged.WithLabelValues(name, "Deployment", "ScalingReplicaSet", "Normal"))).
+			Should(Equal(1.0))
+	})
+
+	It("should count the stale events", func() {
+		lp.seen = newSeenEvents(defaultSeenEventsSize, time.Now())
+		evt := newMetricsEvent("1", "Pod", "Warning", "BackOff")
+		evt.LastTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+		lp.logEvent(evt)
+
+		Ω(testutil.ToFloat64(eventsDropped.WithLabelValues(name, dropStale))).Should(Equal(1.0))
+		Ω(testutil.ToFloat64(eventsSeen.WithLabelValues(name))).Should(Equal(0.0))
+	})
+
+	It("should count the events received before a filter was applied", func() {
+		lp.Config.swap(&snapshot{name: name})
+		lp.logEvent(newMetricsEvent("1", "Pod", "Warning", "BackOff"))
+
+		Ω(testutil.ToFloat64(eventsDropped.WithLabelValues(name, dropNoFilter))).Should(Equal(1.0))
+		Ω(testutil.ToFloat64(eventsSeen.WithLabelValues(name))).Should(Equal(0.0))
+	})
+
+	It("should count the events filtered by expression", func() {
+		spec := apiv1.EventLoggerSpec{
+			Kinds:      []apiv1.Kind{{Name: "Pod", Expression: `event.reason.startsWith("Failed")`}},
+			Expression: `event.type == "Warning"`,
+		}
+		lp.Config.swap(&snapshot{name: name, filter: mustNewFilter(spec, nil), kindClauses: mustNewKindClauses(spec, nil)})
+
+		lp.logEvent(newMetricsEvent("1", "Pod", "Warning", "BackOff"))
+		lp.logEvent(newMetricsEvent("2", "Pod", "Normal", "FailedMount"))
+		lp.logEvent(newMetricsEvent("3", "Pod", "Warning", "FailedMount"))
+
+		Ω(testutil.ToFloat64(eventsFiltered.WithLabelValues(name, clauseExpression))).Should(Equal(2.0))
+		Ω(testutil.ToFloat64(eventsMatched.WithLabelValues(name))).Should(Equal(1.0))
+	})
+
+	It("should count the events filtered by the spec expression without kinds", func() {
+		spec := apiv1.EventLoggerSpec{Expression: `event.type == "Warning"`}
+		lp.Config.swap(&snapshot{name: name, filter: mustNewFilter(spec, nil), kindClauses: mustNewKindClauses(spec, nil)})
+
+		lp.logEvent(newMetricsEvent("1", "Pod", "Normal", "BackOff"))
+
+		Ω(testutil.ToFloat64(eventsFiltered.WithLabelValues(name, clauseExpression))).Should(Equal(1.0))
+	})
+
+	It("should count the events of excluded namespaces", func() {
+		s := *lp.Config.load()
+		s.excludeNamespaces = []string{"kube-*"}
+		lp.Config.swap(&s)
+
+		excluded := newMetricsEvent("1", "Pod", "Warning", "BackOff")
+		excluded.Namespace = "kube-system"
+		included := newMetricsEvent("2", "Pod", "Warning", "BackOff")
+		included.Namespace = "shop"
+		lp.logEvent(excluded)
+		lp.logEvent(included)
+
+		Ω(testutil.ToFloat64(eventsSeen.WithLabelValues(name))).Should(Equal(2.0))
+		Ω(testutil.ToFloat64(eventsFiltered.WithLabelValues(name, clauseExcludeNamespace))).Should(Equal(1.0))
+		Ω(testutil.ToFloat64(eventsMatched.WithLabelValues(name))).Should(Equal(1.0))
+	})
+
+	It("should never log the events of the operator", func() {
+		recorded := newMetricsEvent("1", "EventLogger", "Normal", "ConfigApplied")
+		recorded.ReportingController = cnst.EventReportingController
+		legacy := newMetricsEvent("2", "EventLogger", "Warning", "ValidationFailed")
+		legacy.Source.Component = cnst.EventReportingController
+		lp.logEvent(recorded)
+		lp.logEvent(legacy)
+
+		Ω(testutil.ToFloat64(eventsSeen.WithLabelValues(name))).Should(Equal(2.0))
+		Ω(testutil.ToFloat64(eventsFiltered.WithLabelValues(name, clauseOperatorEvent))).Should(Equal(2.0))
+		Ω(testutil.ToFloat64(eventsMatched.WithLabelValues(name))).Should(Equal(0.0))
+	})
+
+	It("should count the sink errors", func() {
+		countSinkErrors(name)("webhook", 3)
+		Ω(testutil.ToFloat64(sinkErrors.WithLabelValues(name, "webhook"))).Should(Equal(3.0))
+	})
+})
+
+func newMetricsEvent(rv, kind, eventType, reason string) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{ResourceVersion: rv},
+		InvolvedObject: corev1.ObjectReference{Kind: kind},
+		Type:           eventType,
+		Reason:         reason,
+	}
+}
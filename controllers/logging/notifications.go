@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/filter"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+)
+
+// notifier sends a chat notification for events matching the filter of its kind.
+type notifier struct {
+	filter  filter.Filter
+	sink    sink.Async
+	onError func(sink string, events int)
+}
+
+// notificationKinds returns the kinds of the spec with a notification. The event types of the spec are applied to
+// kinds without event types.
+func notificationKinds(c eventloggerv1.EventLoggerSpec) []eventloggerv1.Kind {
+	var kinds []eventloggerv1.Kind
+	for _, k := range c.Kinds {
+		if k.Notification != nil {
+			if len(k.EventTypes) == 0 {
+				k.EventTypes = c.EventTypes
+			}
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds
+}
+
+// newNotifiers creates a notifier for each kind. The webhook urls are read from the secrets in the given namespace.
+// onError is called with the number of events that could not be sent as notification.
+func newNotifiers(
+	ctx context.Context,
+	reader client.Reader,
+	namespace string,
+	kinds []eventloggerv1.Kind,
+	ol objectLabels,
+	onError func(sink string, events int),
+) ([]notifier, error) {
+	var notifiers []notifier
+	for _, k := range kinds {
+		n, err := newNotifier(ctx, reader, namespace, k, ol, onError)
+		if err != nil {
+			closeNotifiers(notifiers, logr.Discard())
+			return nil, fmt.Errorf("error creating notification for kind %q: %w", k.Name, err)
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+func newNotifier(
+	ctx context.Context,
+	reader client.Reader,
+	namespace string,
+	k eventloggerv1.Kind,
+	ol objectLabels,
+	onError func(sink string, events int),
+) (notifier, error) {
+	tmpl, err := sink.ParseTemplate(k.Notification.Template)
+	if err != nil {
+		return notifier{}, err
+	}
+
+	ref := k.Notification.WebhookSecretRef
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return notifier{}, err
+	}
+	url, ok := secret.Data[ref.Key]
+	if !ok {
+		return notifier{}, fmt.Errorf("secret %q has no key %q", ref.Name, ref.Key)
+	}
+
+	chatType := sink.ChatGeneric
+	if k.Notification.Type != "" {
+		chatType = sink.ChatType(k.Notification.Type)
+	}
+
+	f, err := newFilterForKind(k, ol)
+	if err != nil {
+		return notifier{}, err
+	}
+	name := "notification-" + k.Name
+	s := sink.NewChat(name, chatType, string(url), tmpl)
+	n := notifier{filter: f, sink: s, onError: onError}
+	s.OnError(func(records []*sink.Record, err error) {
+		n.countError(len(records))
+		eventLog.WithName("notification").Error(err, "error sending notification", "kind", k.Name, "events", len(records))
+	})
+	return n, nil
+}
+
+func (n notifier) countError(events int) {
+	if n.onError != nil {
+		n.onError(n.sink.String(), events)
+	}
+}
+
+// notify sends the record to all notifiers matching the event.
+func notify(notifiers []notifier, r *sink.Record) {
+	for _, n := range notifiers {
+		if n.filter.Match(r.Event) {
+			if err := n.sink.Send(context.Background(), r); err != nil {
+				n.countError(1)
+				eventLog.WithName("notification").Error(err, "error sending notification", "notification", n.sink.String())
+			}
+		}
+	}
+}
+
+func closeNotifiers(notifiers []notifier, log logr.Logger) {
+	for _, n := range notifiers {
+		if err := n.sink.Close(); err != nil {
+			log.Error(err, "error closing notification", "notification", n.sink.String())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Notifications", func() {
+	var (
+		spec   apiv1.EventLoggerSpec
+		secret *corev1.Secret
+	)
+	BeforeEach(func() {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "chat"},
+			Data:       map[string][]byte{"url": []byte("http://localhost")},
+		}
+		spec = apiv1.EventLoggerSpec{
+			EventTypes: []string{"Warning"},
+			Kinds: []apiv1.Kind{
+				{Name: "Pod"},
+				{
+					Name:    "Deployment",
+					Reasons: []string{"FailedCreate"},
+					Notification: &apiv1.Notification{
+						Type:             apiv1.NotificationTypeSlack,
+						WebhookSecretRef: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "chat"}, Key: "url"},
+					},
+				},
+			},
+		}
+	})
+
+	Context("notificationKinds", func() {
+		It("should return the kinds with notification", func() {
+			kinds := notificationKinds(spec)
+			Ω(kinds).Should(HaveLen(1))
+			Ω(kinds[0].Name).Should(Equal("Deployment"))
+			Ω(kinds[0].EventTypes).Should(Equal([]string{"Warning"}))
+		})
+	})
+
+	Context("newNotifiers", func() {
+		It("should notify for matching events only", func() {
+			notified := make(chan struct{}, 10)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				notified <- struct{}{}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+			secret.Data["url"] = []byte(server.URL)
+
+			cl := fake.NewClientBuilder().WithObjects(secret).Build()
+			notifiers, err := newNotifiers(context.Background(), cl, testNamespace, notificationKinds(spec), nil, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(notifiers).Should(HaveLen(1))
+
+			notify(notifiers, &sink.Record{Event: &corev1.Event{
+				Type:           "Warning",
+				Reason:         "FailedCreate",
+				InvolvedObject: corev1.ObjectReference{Kind: "Deployment"},
+			}})
+			notify(notifiers, &sink.Record{Event: &corev1.Event{
+				Type:           "Warning",
+				Reason:         "FailedCreate",
+				InvolvedObject: corev1.ObjectReference{Kind: "Pod"},
+			}})
+			closeNotifiers(notifiers, GinkgoLogr)
+
+			Ω(notified).Should(HaveLen(1))
+		})
+		It("should count the failed notifications", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			}))
+			defer server.Close()
+			secret.Data["url"] = []byte(server.URL)
+
+			failed := make(chan string, 10)
+			cl := fake.NewClientBuilder().WithObjects(secret).Build()
+			notifiers, err := newNotifiers(context.Background(), cl, testNamespace, notificationKinds(spec), nil,
+				func(sink string, events int) {
+					for range events {
+						failed <- sink
+					}
+				})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			notify(notifiers, &sink.Record{Event: &corev1.Event{
+				Type:           "Warning",
+				Reason:         "FailedCreate",
+				InvolvedObject: corev1.ObjectReference{Kind: "Deployment"},
+			}})
+			closeNotifiers(notifiers, GinkgoLogr)
+
+			Ω(failed).Should(HaveLen(1))
+			Ω(<-failed).Should(Equal("notification-Deployment"))
+		})
+		It("should fail if the secret key does not exist", func() {
+			secret.Data = map[string][]byte{}
+			cl := fake.NewClientBuilder().WithObjects(secret).Build()
+			_, err := newNotifiers(context.Background(), cl, testNamespace, notificationKinds(spec), nil, nil)
+			Ω(err).Should(MatchError(ContainSubstring(`secret "chat" has no key "url"`)))
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/lru"
+)
+
+// defaultSeenEventsSize the max number of processed events remembered.
+const defaultSeenEventsSize = 10000
+
+// seenEvents remembers the resource versions of the processed events in a bounded lru cache. Resource versions are
+// opaque, they are only compared for equality: an event is new if its resource version differs from the processed
+// one. Events that are not remembered are stale if their timestamp is before the watermark. The watermark is the
+// time the logger became the leader or the checkpoint it resumes from, it is raised to the timestamps of the evicted
+// events, so that a relist of the watch does not log them again. The timestamps are set by the reporters of the
+// events, the watermark is never raised beyond the current time of the logger, so that an event with a timestamp in
+// the future does not make the following events stale.
+type seenEvents struct {
+	cache     *lru.Cache
+	watermark time.Time
+	clock     clock.PassiveClock
+}
+
+// observation the result of observing an event.
+type observation int
+
+const (
+	// observedNew the event was not processed yet
+	observedNew observation = iota
+	// observedDuplicate the event was already processed with the same resource version
+	observedDuplicate
+	// observedStale the event is not remembered and before the watermark
+	observedStale
+)
+
+type seenEvent struct {
+	resourceVersion string
+	timestamp       time.Time
+}
+
+// newSeenEvents returns the seen events of the given size, the events before the watermark are stale.
+// The timestamps of core events have a precision of seconds, events of the same second are not stale.
+func newSeenEvents(size int, watermark time.Time) *seenEvents {
+	s := &seenEvents{watermark: watermark.Truncate(time.Second), clock: clock.RealClock{}}
+	s.cache = lru.NewWithEvictionFunc(size, func(_ lru.Key, value any) {
+		ts := value.(seenEvent).timestamp
+		if now := s.clock.Now(); ts.After(now) {
+			ts = now
+		}
+		if ts.After(s.watermark) {
+			s.watermark = ts
+		}
+	})
+	return s
+}
+
+// observe returns true if the event is new and remembers it as processed.
+func (s *seenEvents) observe(evt *corev1.Event) bool {
+	return s.classify(evt) == observedNew
+}
+
+// classify returns whether the event is new, a duplicate or stale and remembers new events as processed. Events
+// without any timestamp are never stale.
+func (s *seenEvents) classify(evt *corev1.Event) observation {
+	ts := lastSeen(evt)
+	if v, ok := s.cache.Get(evt.UID); ok {
+		if v.(seenEvent).resourceVersion == evt.ResourceVersion {
+			return observedDuplicate
+		}
+	} else if !ts.IsZero() && ts.Before(s.watermark) {
+		return observedStale
+	}
+	s.cache.Add(evt.UID, seenEvent{resourceVersion: evt.ResourceVersion, timestamp: ts})
+	return observedNew
+}
+
+// lastSeen returns the timestamp of the event or its creation timestamp if it has no timestamp.
+func lastSeen(evt *corev1.Event) time.Time {
+	if ts := eventTimestamp(evt); !ts.IsZero() {
+		return ts
+	}
+	return evt.CreationTimestamp.Time
+}
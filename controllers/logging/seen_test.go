@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	testingclock "k8s.io/utils/clock/testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Seen events", func() {
+	now := time.Now().Truncate(time.Second)
+
+	newEvent := func(uid string, rv string, ts time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:    metav1.ObjectMeta{UID: types.UID(uid), ResourceVersion: rv},
+			LastTimestamp: metav1.NewTime(ts),
+		}
+	}
+
+	It("should process the events after the resource version crossed a digit boundary", func() {
+		s := newSeenEvents(defaultSeenEventsSize, now)
+		Ω(s.observe(newEvent("a", "9", now))).Should(BeTrue())
+		Ω(s.observe(newEvent("b", "10", now))).Should(BeTrue())
+		Ω(s.observe(newEvent("c", "99999", now))).Should(BeTrue())
+		Ω(s.observe(newEvent("d", "100000", now))).Should(BeTrue())
+	})
+
+	It("should process the events after the resource versions wrapped", func() {
+		s := newSeenEvents(defaultSeenEventsSize, now)
+		Ω(s.observe(newEvent("a", "18446744073709551615", now))).Should(BeTrue())
+		Ω(s.observe(newEvent("b", "1", now))).Should(BeTrue())
+		Ω(s.observe(newEvent("c", "opaque", now))).Should(BeTrue())
+	})
+
+	It("should process the updates of an event", func() {
+		s := newSeenEvents(defaultSeenEventsSize, now)
+		Ω(s.observe(newEvent("a", "5", now))).Should(BeTrue())
+		Ω(s.observe(newEvent("a", "5", now))).Should(BeFalse())
+		Ω(s.observe(newEvent("a", "3", now.Add(time.Second)))).Should(BeTrue())
+	})
+
+	It("should not process the events again after a relist of the watch", func() {
+		s := newSeenEvents(defaultSeenEventsSize, now)
+		events := []*corev1.Event{
+			newEvent("a", "100", now),
+			newEvent("b", "101", now.Add(time.Second)),
+			newEvent("c", "102", now.Add(2*time.Second)),
+		}
+		for _, evt := range events {
+			Ω(s.observe(evt)).Should(BeTrue())
+		}
+		// the watch expired, the relist delivers all existing events again
+		for _, evt := range events {
+			Ω(s.observe(evt)).Should(BeFalse())
+		}
+		Ω(s.observe(newEvent("d", "103", now.Add(3*time.Second)))).Should(BeTrue())
+	})
+
+	It("should not process the evicted events again after a relist caused by a compaction", func() {
+		s := newSeenEvents(2, now)
+		s.clock = testingclock.NewFakePassiveClock(now.Add(time.Hour))
+		Ω(s.observe(newEvent("a", "1", now.Add(time.Second)))).Should(BeTrue())
+		Ω(s.observe(newEvent("b", "2", now.Add(2*time.Second)))).Should(BeTrue())
+		Ω(s.observe(newEvent("c", "3", now.Add(3*time.Second)))).Should(BeTrue())
+		Ω(s.watermark).Should(BeTemporally("==", now.Add(time.Second)))
+
+		// the watch resumes after the compaction with a relist
+		Ω(s.observe(newEvent("b", "2", now.Add(2*time.Second)))).Should(BeFalse())
+		Ω(s.observe(newEvent("c", "3", now.Add(3*time.Second)))).Should(BeFalse())
+		// evicted in the meantime
+		Ω(s.observe(newEvent("a", "1", now))).Should(BeFalse())
+		Ω(s.observe(newEvent("d", "4", now.Add(4*time.Second)))).Should(BeTrue())
+	})
+
+	It("should not raise the watermark beyond the current time", func() {
+		s := newSeenEvents(2, now)
+		s.clock = testingclock.NewFakePassiveClock(now.Add(time.Minute))
+		// the clock of the reporter is ahead
+		Ω(s.observe(newEvent("a", "1", now.Add(time.Hour)))).Should(BeTrue())
+		Ω(s.observe(newEvent("b", "2", now.Add(time.Second)))).Should(BeTrue())
+		Ω(s.observe(newEvent("c", "3", now.Add(2*time.Second)))).Should(BeTrue())
+		Ω(s.watermark).Should(BeTemporally("==", now.Add(time.Minute)))
+
+		Ω(s.observe(newEvent("d", "4", now.Add(time.Minute)))).Should(BeTrue())
+		Ω(s.classify(newEvent("e", "5", now.Add(30*time.Second)))).Should(Equal(observedStale))
+	})
+
+	It("should classify duplicates and stale events", func() {
+		s := newSeenEvents(defaultSeenEventsSize, now)
+		Ω(s.classify(newEvent("a", "1", now))).Should(Equal(observedNew))
+		Ω(s.classify(newEvent("a", "1", now))).Should(Equal(observedDuplicate))
+		Ω(s.classify(newEvent("b", "2", now.Add(-time.Second)))).Should(Equal(observedStale))
+	})
+
+	It("should not process the events before the watermark", func() {
+		s := newSeenEvents(defaultSeenEventsSize, now.Add(500*time.Millisecond))
+		Ω(s.observe(newEvent("a", "1", now.Add(-time.Second)))).Should(BeFalse())
+		// the same second
+		Ω(s.observe(newEvent("b", "2", now))).Should(BeTrue())
+		// no timestamp at all
+		Ω(s.observe(&corev1.Event{ObjectMeta: metav1.ObjectMeta{UID: "c", ResourceVersion: "3"}})).Should(BeTrue())
+	})
+
+	It("should use the creation timestamp if the event has no timestamp", func() {
+		s := newSeenEvents(defaultSeenEventsSize, now)
+		evt := &corev1.Event{ObjectMeta: metav1.ObjectMeta{
+			UID:               "a",
+			ResourceVersion:   "1",
+			CreationTimestamp: metav1.NewTime(now.Add(-time.Minute)),
+		}}
+		Ω(s.observe(evt)).Should(BeFalse())
+	})
+})
@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+)
+
+const (
+	defaultSyslogTag = "event-logger"
+	// headersTimeout the max time to read the headers secret of a webhook sink again
+	headersTimeout = 10 * time.Second
+)
+
+// newSinks creates the sinks for the given spec. If no sinks are defined, nil is returned and the events are logged
+// with the event logger. Secrets referenced by the sinks are read from the given namespace. onError is called with the
+// number of events that could not be sent to a sink.
+func newSinks(
+	ctx context.Context,
+	reader client.Reader,
+	namespace string,
+	specs []eventloggerv1.Sink,
+	onError func(sink string, events int),
+) (*sink.Fanout, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	var targets []sink.Target
+	for _, s := range specs {
+		snk, err := newSink(ctx, reader, namespace, s)
+		if err != nil {
+			for _, t := range targets {
+				_ = t.Close()
+			}
+			return nil, fmt.Errorf("error creating sink %q: %w", s.Name, err)
+		}
+		onFailure := sink.FailureLog
+		if s.OnFailure != "" {
+			onFailure = sink.FailurePolicy(s.OnFailure)
+		}
+		targets = append(targets, sink.Target{Sink: snk, OnFailure: onFailure})
+	}
+	return sink.NewFanout(eventLog.WithName("sink"), sink.NewLogr("fallback", eventLog), onError, targets...), nil
+}
+
+func newSink(ctx context.Context, reader client.Reader, namespace string, s eventloggerv1.Sink) (sink.Sink, error) {
+	enc := sink.JSON
+	if s.Encoding == eventloggerv1.SinkEncodingText {
+		enc = sink.Text
+	}
+
+	switch s.Type {
+	case eventloggerv1.SinkTypeLog:
+		return sink.NewLogr(s.Name, eventLog), nil
+	case eventloggerv1.SinkTypeStdout:
+		return sink.NewWriter(s.Name, os.Stdout, enc), nil
+	case eventloggerv1.SinkTypeFile:
+		if s.File == nil {
+			return nil, fmt.Errorf("sink of type %q requires the file config", s.Type)
+		}
+		return sink.NewFile(s.Name, s.File.Path, enc)
+	case eventloggerv1.SinkTypeSyslog:
+		if s.Syslog == nil {
+			return nil, fmt.Errorf("sink of type %q requires the syslog config", s.Type)
+		}
+		tag := s.Syslog.Tag
+		if tag == "" {
+			tag = defaultSyslogTag
+		}
+		return sink.NewSyslog(s.Name, s.Syslog.Network, s.Syslog.Address, tag, enc)
+	case eventloggerv1.SinkTypeWebhook:
+		if s.Webhook == nil {
+			return nil, fmt.Errorf("sink of type %q requires the webhook config", s.Type)
+		}
+		return newWebhookSink(ctx, reader, namespace, s.Name, s.Webhook)
+	}
+	return nil, fmt.Errorf("unknown sink type %q", s.Type)
+}
+
+func newWebhookSink(
+	ctx context.Context,
+	reader client.Reader,
+	namespace, name string,
+	wh *eventloggerv1.WebhookSink,
+) (sink.Sink, error) {
+	header := http.Header{}
+	var refreshHeader func() (http.Header, error)
+	if wh.HeadersSecretRef != nil {
+		key := types.NamespacedName{Namespace: namespace, Name: wh.HeadersSecretRef.Name}
+		var err error
+		if header, err = readHeaders(ctx, reader, key); err != nil {
+			return nil, err
+		}
+		// the secret may have been rotated if the endpoint rejects the headers
+		refreshHeader = func() (http.Header, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), headersTimeout)
+			defer cancel()
+			return readHeaders(ctx, reader, key)
+		}
+	}
+
+	opts := sink.WebhookOptions{
+		URL:           wh.URL,
+		Header:        header,
+		RefreshHeader: refreshHeader,
+		BatchSize:     wh.BatchSize,
+		QueueSize:     wh.QueueSize,
+		MaxRetries:    ptr.Deref(wh.MaxRetries, sink.DefaultMaxRetries),
+	}
+	if wh.FlushInterval != nil {
+		opts.FlushInterval = wh.FlushInterval.Duration
+	}
+	return sink.NewWebhook(name, opts), nil
+}
+
+// readHeaders reads the headers of the webhook requests from the secret.
+func readHeaders(ctx context.Context, reader client.Reader, key types.NamespacedName) (http.Header, error) {
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, key, secret); err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	for k, v := range secret.Data {
+		header.Set(k, string(v))
+	}
+	return header, nil
+}
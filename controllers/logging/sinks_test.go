@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sinks", func() {
+	Context("newSinks", func() {
+		It("should return nil if no sinks are defined", func() {
+			s, err := newSinks(context.Background(), nil, testNamespace, nil, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(s).Should(BeNil())
+		})
+		It("should create the sinks", func() {
+			s, err := newSinks(context.Background(), nil, testNamespace, []apiv1.Sink{
+				{Name: "log", Type: apiv1.SinkTypeLog},
+				{Name: "stdout", Type: apiv1.SinkTypeStdout, Encoding: apiv1.SinkEncodingText},
+				{
+					Name: "file", Type: apiv1.SinkTypeFile, OnFailure: apiv1.FailurePolicyIgnore,
+					File: &apiv1.FileSink{Path: filepath.Join(GinkgoT().TempDir(), "events.log")},
+				},
+			}, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(s).ShouldNot(BeNil())
+			Ω(s.Close()).ShouldNot(HaveOccurred())
+		})
+		It("should fail if the sink config is missing", func() {
+			_, err := newSinks(
+				context.Background(), nil, testNamespace, []apiv1.Sink{{Name: "file", Type: apiv1.SinkTypeFile}}, nil,
+			)
+			Ω(err).Should(MatchError(ContainSubstring(`error creating sink "file"`)))
+		})
+		It("should fail for an unknown sink type", func() {
+			_, err := newSinks(context.Background(), nil, testNamespace, []apiv1.Sink{{Name: "foo", Type: "foo"}}, nil)
+			Ω(err).Should(MatchError(ContainSubstring(`unknown sink type "foo"`)))
+		})
+		It("should read the webhook headers from the secret", func() {
+			header := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header <- r.Header.Get("Authorization")
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			cl := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "headers"},
+				Data:       map[string][]byte{"Authorization": []byte("Bearer token")},
+			}).Build()
+			s, err := newSinks(context.Background(), cl, testNamespace, []apiv1.Sink{{
+				Name: "webhook",
+				Type: apiv1.SinkTypeWebhook,
+				Webhook: &apiv1.WebhookSink{
+					URL:              server.URL,
+					HeadersSecretRef: &corev1.LocalObjectReference{Name: "headers"},
+				},
+			}}, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			s.Send(context.Background(), &sink.Record{Message: "msg"})
+			Ω(s.Close()).ShouldNot(HaveOccurred())
+			Ω(<-header).Should(Equal("Bearer token"))
+		})
+		It("should read the webhook headers again if they are rejected", func() {
+			header := make(chan string, 2)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header <- r.Header.Get("Authorization")
+				if r.Header.Get("Authorization") != "Bearer rotated" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "headers"},
+				Data:       map[string][]byte{"Authorization": []byte("Bearer token")},
+			}
+			cl := fake.NewClientBuilder().WithObjects(secret).Build()
+			s, err := newSinks(context.Background(), cl, testNamespace, []apiv1.Sink{{
+				Name: "webhook",
+				Type: apiv1.SinkTypeWebhook,
+				Webhook: &apiv1.WebhookSink{
+					URL:              server.URL,
+					HeadersSecretRef: &corev1.LocalObjectReference{Name: "headers"},
+				},
+			}}, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			secret.Data["Authorization"] = []byte("Bearer rotated")
+			Ω(cl.Update(context.Background(), secret)).ShouldNot(HaveOccurred())
+			s.Send(context.Background(), &sink.Record{Message: "msg"})
+			Ω(s.Close()).ShouldNot(HaveOccurred())
+			Ω(<-header).Should(Equal("Bearer token"))
+			Ω(<-header).Should(Equal("Bearer rotated"))
+		})
+		It("should fail if the webhook headers secret does not exist", func() {
+			cl := fake.NewClientBuilder().Build()
+			_, err := newSinks(context.Background(), cl, testNamespace, []apiv1.Sink{{
+				Name: "webhook",
+				Type: apiv1.SinkTypeWebhook,
+				Webhook: &apiv1.WebhookSink{
+					URL:              "http://localhost",
+					HeadersSecretRef: &corev1.LocalObjectReference{Name: "headers"},
+				},
+			}}, nil)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
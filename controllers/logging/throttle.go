@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+	"github.com/bakito/k8s-event-logger-operator/pkg/throttle"
+)
+
+// throttleSpec the parts of the spec the throttle is created from.
+type throttleSpec struct {
+	deduplication *eventloggerv1.Deduplication
+	rateLimit     *eventloggerv1.RateLimit
+}
+
+// newThrottle creates a new throttle for the spec. If neither deduplication nor rate limits are defined, nil is
+// returned. The summary of suppressed events is written to the sinks of the current snapshot of the config, they are
+// not closed while the summary is written.
+func (c *Config) newThrottle(ts throttleSpec) *throttle.Throttle {
+	if ts.deduplication == nil && ts.rateLimit == nil {
+		return nil
+	}
+
+	opts := throttle.Options{
+		OnSummary: func(evt *corev1.Event, suppressed int) {
+			s, release := c.acquire()
+			defer release()
+			s.write(&sink.Record{
+				Event:   evt,
+				Message: fmt.Sprintf("suppressed %d repeats", suppressed),
+				Fields:  append(s.eventFields(evt), "message", evt.Message, "suppressed", suppressed),
+			})
+		},
+	}
+	if ts.deduplication != nil {
+		opts.Window = ts.deduplication.Window.Duration
+	}
+	if ts.rateLimit != nil {
+		opts.PerKey = newLimit(ts.rateLimit.PerKey)
+		opts.Global = newLimit(ts.rateLimit.Global)
+	}
+
+	t := throttle.New(opts)
+	t.Start()
+	return t
+}
+
+func newLimit(l *eventloggerv1.Limit) *throttle.Limit {
+	if l == nil {
+		return nil
+	}
+	interval := time.Second
+	if l.Interval != nil && l.Interval.Duration > 0 {
+		interval = l.Interval.Duration
+	}
+	burst := l.Events
+	if l.Burst != nil {
+		burst = *l.Burst
+	}
+	return &throttle.Limit{
+		Rate:  rate.Limit(float64(l.Events) / interval.Seconds()),
+		Burst: int(burst),
+	}
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/filter"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Throttle", func() {
+	Context("newLimit", func() {
+		It("should return nil", func() {
+			Ω(newLimit(nil)).Should(BeNil())
+		})
+		It("should default interval and burst", func() {
+			l := newLimit(&apiv1.Limit{Events: 5})
+			Ω(l.Rate).Should(Equal(rate.Limit(5)))
+			Ω(l.Burst).Should(Equal(5))
+		})
+		It("should use interval and burst", func() {
+			l := newLimit(&apiv1.Limit{
+				Events:   30,
+				Interval: &metav1.Duration{Duration: time.Minute},
+				Burst:    new(int32(3)),
+			})
+			Ω(l.Rate).Should(Equal(rate.Limit(0.5)))
+			Ω(l.Burst).Should(Equal(3))
+		})
+	})
+
+	Context("newThrottle", func() {
+		It("should return nil if not configured", func() {
+			c := &Config{}
+			Ω(c.newThrottle(throttleSpec{})).Should(BeNil())
+		})
+		It("should suppress repeated events and write a summary", func() {
+			var buf bytes.Buffer
+			c := testConfig(&snapshot{
+				filter: filter.Always,
+				sinks:  sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+			})
+			s := *c.load()
+			s.throttle = c.newThrottle(throttleSpec{
+				deduplication: &apiv1.Deduplication{Window: metav1.Duration{Duration: time.Hour}},
+			})
+			c.swap(&s)
+			lp := &loggingPredicate{Config: c}
+
+			for i := range 3 {
+				lp.logEvent(&corev1.Event{
+					ObjectMeta: metav1.ObjectMeta{
+						ResourceVersion: strconv.Itoa(i + 3),
+						Name:            "test-event-name",
+					},
+					Reason:  "BackOff",
+					Message: "test-message",
+					Count:   int32(i + 1),
+				})
+			}
+			Ω(strings.Count(buf.String(), `"msg":"test-message"`)).Should(Equal(1))
+
+			c.reset(logr.Discard())
+			Ω(c.load().throttle).Should(BeNil())
+			Ω(buf.String()).Should(ContainSubstring(`"msg":"suppressed 2 repeats"`))
+			Ω(buf.String()).Should(ContainSubstring(`"suppressed":2`))
+		})
+		It("should flush the summaries when the logger stops", func() {
+			var buf bytes.Buffer
+			c := testConfig(&snapshot{
+				filter: filter.Always,
+				sinks:  sink.NewFanout(logr.Discard(), nil, nil, sink.Target{Sink: sink.NewWriter("buf", &buf, sink.JSON)}),
+			})
+			s := *c.load()
+			s.throttle = c.newThrottle(throttleSpec{
+				deduplication: &apiv1.Deduplication{Window: metav1.Duration{Duration: time.Hour}},
+			})
+			c.swap(&s)
+			lp := &loggingPredicate{Config: c}
+			for i := range 2 {
+				lp.logEvent(&corev1.Event{
+					ObjectMeta: metav1.ObjectMeta{ResourceVersion: strconv.Itoa(i + 3), Name: "test-event-name"},
+					Reason:     "BackOff",
+					Message:    "test-message",
+				})
+			}
+
+			(&Reconciler{Config: c, Log: logr.Discard()}).Close()
+			Ω(c.load().throttle).Should(BeNil())
+			Ω(c.load().sinks).Should(BeNil())
+			Ω(buf.String()).Should(ContainSubstring(`"msg":"suppressed 1 repeats"`))
+		})
+	})
+})
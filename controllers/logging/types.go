@@ -2,25 +2,74 @@ package logging
 
 import (
 	"fmt"
+	"path"
 	"regexp"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 
 	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	cnst "github.com/bakito/k8s-event-logger-operator/pkg/constants"
 	"github.com/bakito/k8s-event-logger-operator/pkg/filter"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+	"github.com/bakito/k8s-event-logger-operator/pkg/throttle"
 )
 
-func newFilter(c eventloggerv1.EventLoggerSpec) filter.Filter {
+const (
+	clauseEventType       = "eventType"
+	clauseKind            = "kind"
+	clauseAPIGroup        = "apiGroup"
+	clauseSkipReason      = "skipReason"
+	clauseReason          = "reason"
+	clauseMatchingPattern = "matchingPattern"
+	clauseObjectName      = "involvedObjectName"
+	clauseObjectNamespace = "involvedObjectNamespace"
+	clauseLabelSelector   = "labelSelector"
+	clauseExpression      = "expression"
+	// clauseExcludeNamespace the namespace of the event is excluded by a ClusterEventLogger
+	clauseExcludeNamespace = "excludeNamespace"
+	// clauseOperatorEvent the event was recorded by the operator, it is never logged to avoid loops
+	clauseOperatorEvent = "operatorEvent"
+)
+
+// clause is a named part of a filter, the name is used to report which part filtered out an event.
+type clause struct {
+	name   string
+	filter filter.Filter
+}
+
+// clauses are the parts of a filter that all have to match.
+type clauses []clause
+
+func (c clauses) all() filter.Filter {
+	filters := filter.Slice{}
+	for _, cl := range c {
+		filters = append(filters, cl.filter)
+	}
+	return filters.All()
+}
+
+// mismatch returns the name of the first clause not matching the event or an empty string if all clauses match.
+func (c clauses) mismatch(e *corev1.Event) string {
+	for _, cl := range c {
+		if !cl.filter.Match(e) {
+			return cl.name
+		}
+	}
+	return ""
+}
+
+func newFilter(c eventloggerv1.EventLoggerSpec, ol objectLabels) (filter.Filter, error) {
 	filters := filter.Slice{}
 
 	if len(c.EventTypes) > 0 {
-		filters = append(filters, filter.New(func(e *corev1.Event) bool {
-			return contains(c.EventTypes, e.Type)
-		}, fmt.Sprintf("EventType in [%s]", strings.Join(c.EventTypes, ", "))))
+		filters = append(filters, newFilterForEventTypes(c.EventTypes))
 	}
 
 	if len(c.Kinds) > 0 {
@@ -29,62 +78,192 @@ func newFilter(c eventloggerv1.EventLoggerSpec) filter.Filter {
 			if len(k.EventTypes) == 0 {
 				k.EventTypes = c.EventTypes
 			}
-
-			filterForKinds = append(filterForKinds, newFilterForKind(k))
+			f, err := newFilterForKind(k, ol)
+			if err != nil {
+				return nil, err
+			}
+			filterForKinds = append(filterForKinds, f)
 		}
 
 		filters = append(filters, filterForKinds.Any())
 	}
 
-	if len(filters) == 0 {
-		return filter.Always
+	var f filter.Filter = filter.Always
+	if len(filters) > 0 {
+		f = filters.Any()
 	}
 
-	return filters.Any()
+	if c.Expression != "" {
+		return filter.Slice{f, newFilterForExpression(c.Expression)}.All(), nil
+	}
+	return f, nil
 }
 
-func newFilterForKind(k eventloggerv1.Kind) filter.Filter {
-	filters := filter.Slice{}
+// newKindClauses returns the clauses of each kind of the spec. The expression of the spec is added to the clauses
+// of each kind, without kinds a single clauses matching any kind is returned.
+func newKindClauses(c eventloggerv1.EventLoggerSpec, ol objectLabels) ([]clauses, error) {
+	var kinds []clauses
+	for _, k := range c.Kinds {
+		if len(k.EventTypes) == 0 {
+			k.EventTypes = c.EventTypes
+		}
+		kc, err := newClausesForKind(k, ol)
+		if err != nil {
+			return nil, err
+		}
+		if c.Expression != "" {
+			kc = append(kc, clause{name: clauseExpression, filter: newFilterForExpression(c.Expression)})
+		}
+		kinds = append(kinds, kc)
+	}
+	if len(kinds) == 0 && c.Expression != "" {
+		kc := clauses{{name: clauseKind, filter: filter.Always}}
+		if len(c.EventTypes) > 0 {
+			kc = append(kc, clause{name: clauseEventType, filter: newFilterForEventTypes(c.EventTypes)})
+		}
+		kinds = append(kinds, append(kc, clause{name: clauseExpression, filter: newFilterForExpression(c.Expression)}))
+	}
+	return kinds, nil
+}
+
+// filteredBy returns the name of the clause that filtered out the event. If the kind of the event is defined,
+// the first mismatching clause of the kind is returned.
+func filteredBy(kinds []clauses, e *corev1.Event) string {
+	if len(kinds) == 0 {
+		return clauseEventType
+	}
+	for _, kc := range kinds {
+		if kc[0].filter.Match(e) {
+			if name := kc.mismatch(e); name != "" {
+				return name
+			}
+		}
+	}
+	return clauseKind
+}
+
+func newFilterForEventTypes(eventTypes []string) filter.Filter {
+	return filter.New(func(e *corev1.Event) bool {
+		return contains(eventTypes, e.Type)
+	}, fmt.Sprintf("EventType in [%s]", strings.Join(eventTypes, ", ")))
+}
 
-	filters = append(filters, filter.New(func(e *corev1.Event) bool {
+func newFilterForKind(k eventloggerv1.Kind, ol objectLabels) (filter.Filter, error) {
+	c, err := newClausesForKind(k, ol)
+	if err != nil {
+		return nil, err
+	}
+	return c.all(), nil
+}
+
+// newClausesForKind returns the clauses of the kind, the first clause always matches the kind name.
+// The labels of the involved objects are read with ol. An error is returned if a matching pattern is invalid.
+func newClausesForKind(k eventloggerv1.Kind, ol objectLabels) (clauses, error) {
+	c := clauses{}
+
+	c = append(c, clause{name: clauseKind, filter: filter.New(func(e *corev1.Event) bool {
 		return k.Name == e.InvolvedObject.Kind
-	}, fmt.Sprintf("Kind == '%s'", k.Name)))
+	}, fmt.Sprintf("Kind == '%s'", k.Name))})
 
 	if k.APIGroup != nil {
-		filters = append(filters, filter.New(func(e *corev1.Event) bool {
+		c = append(c, clause{name: clauseAPIGroup, filter: filter.New(func(e *corev1.Event) bool {
 			return *k.APIGroup == e.InvolvedObject.GroupVersionKind().Group
-		}, fmt.Sprintf("APIGroup == '%s'", *k.APIGroup)))
+		}, fmt.Sprintf("APIGroup == '%s'", *k.APIGroup))})
 	}
 
 	if len(k.EventTypes) > 0 {
-		filters = append(filters, filter.New(func(e *corev1.Event) bool {
-			return contains(k.EventTypes, e.Type)
-		}, fmt.Sprintf("EventType in [%s]", strings.Join(k.EventTypes, ", "))))
+		c = append(c, clause{name: clauseEventType, filter: newFilterForEventTypes(k.EventTypes)})
 	}
 
 	if len(k.SkipReasons) > 0 {
-		filters = append(filters, filter.New(func(e *corev1.Event) bool {
+		c = append(c, clause{name: clauseSkipReason, filter: filter.New(func(e *corev1.Event) bool {
 			return !contains(k.SkipReasons, e.Reason)
-		}, fmt.Sprintf("Reason NOT in [%s]", strings.Join(k.SkipReasons, ", "))))
+		}, fmt.Sprintf("Reason NOT in [%s]", strings.Join(k.SkipReasons, ", ")))})
 	}
 
 	if len(k.Reasons) > 0 {
-		filters = append(filters, filter.New(func(e *corev1.Event) bool {
+		c = append(c, clause{name: clauseReason, filter: filter.New(func(e *corev1.Event) bool {
 			return contains(k.Reasons, e.Reason)
-		}, fmt.Sprintf("Reason in [%s]", strings.Join(k.Reasons, ", "))))
+		}, fmt.Sprintf("Reason in [%s]", strings.Join(k.Reasons, ", ")))})
+	}
+
+	if len(k.InvolvedObjectNames) > 0 {
+		c = append(c, clause{name: clauseObjectName, filter: filter.New(func(e *corev1.Event) bool {
+			return matchesGlob(k.InvolvedObjectNames, e.InvolvedObject.Name)
+		}, fmt.Sprintf("InvolvedObject.Name matches [%s]", strings.Join(k.InvolvedObjectNames, ", ")))})
+	}
+
+	if len(k.InvolvedObjectNamespaces) > 0 {
+		c = append(c, clause{name: clauseObjectNamespace, filter: filter.New(func(e *corev1.Event) bool {
+			return matchesGlob(k.InvolvedObjectNamespaces, e.InvolvedObject.Namespace)
+		}, fmt.Sprintf("InvolvedObject.Namespace matches [%s]", strings.Join(k.InvolvedObjectNamespaces, ", ")))})
 	}
 
 	if k.MatchingPatterns != nil {
-		filters = append(filters, newFilterForMatchingPatterns(k.MatchingPatterns, ptr.Deref(k.SkipOnMatch, false)))
+		f, err := newFilterForMatchingPatterns(k.MatchingPatterns, ptr.Deref(k.SkipOnMatch, false))
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, clause{name: clauseMatchingPattern, filter: f})
 	}
 
-	return filters.All()
+	if k.Expression != "" {
+		c = append(c, clause{name: clauseExpression, filter: newFilterForExpression(k.Expression)})
+	}
+
+	if k.LabelSelector != nil {
+		// the label selector is the last clause, the labels are only read if all other clauses match
+		c = append(c, clause{name: clauseLabelSelector, filter: newFilterForLabelSelector(k.LabelSelector, ol)})
+	}
+
+	return c, nil
+}
+
+// matchesGlob returns true if the value matches one of the glob patterns.
+func matchesGlob(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newFilterForExpression compiles the CEL expression, invalid expressions never match.
+func newFilterForExpression(expression string) filter.Filter {
+	f, err := filter.NewCEL(expression)
+	if err != nil {
+		// invalid expressions are rejected by the validation
+		return filter.New(func(_ *corev1.Event) bool {
+			return false
+		}, fmt.Sprintf("invalid CEL '%s'", expression))
+	}
+	return f
+}
+
+func newFilterForLabelSelector(ls *metav1.LabelSelector, ol objectLabels) filter.Filter {
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil {
+		// invalid selectors are rejected by the validation
+		return filter.Never
+	}
+	return filter.New(func(e *corev1.Event) bool {
+		if ol == nil {
+			return false
+		}
+		l, ok := ol.Labels(e.InvolvedObject)
+		return ok && selector.Matches(l)
+	}, fmt.Sprintf("InvolvedObject.Labels matches '%s'", selector.String()))
 }
 
-func newFilterForMatchingPatterns(patterns []string, skipOnMatch bool) filter.Filter {
+// newFilterForMatchingPatterns returns an error if a pattern is no valid regular expression.
+func newFilterForMatchingPatterns(patterns []string, skipOnMatch bool) (filter.Filter, error) {
 	filters := filter.Slice{}
 	for _, mp := range patterns {
-		matcher := regexp.MustCompile(mp)
+		matcher, err := regexp.Compile(mp)
+		if err != nil {
+			return nil, err
+		}
 		filters = append(filters, filter.New(func(e *corev1.Event) bool {
 			return matcher.MatchString(e.Message)
 		}, fmt.Sprintf("Message matches /%s/", mp)))
@@ -93,32 +272,136 @@ func newFilterForMatchingPatterns(patterns []string, skipOnMatch bool) filter.Fi
 	f := filters.Any()
 	return filter.New(func(e *corev1.Event) bool {
 		return skipOnMatch != f.Match(e)
-	}, fmt.Sprintf("( %v XOR %s )", skipOnMatch, f.String()))
+	}, fmt.Sprintf("( %v XOR %s )", skipOnMatch, f.String())), nil
+}
+
+// ConfigFor get config for namespace and name. The EventLogger is read from one of the watched namespaces, or from
+// the namespace of the pod if no namespaces are watched.
+func ConfigFor(name, podNamespace string, watchNamespaces ...string) *Config {
+	c := &Config{
+		podNamespace:    podNamespace,
+		watchNamespaces: watchNamespaces,
+	}
+	c.current.Store(&snapshot{name: name})
+	return c
 }
 
-// ConfigFor get config for namespace and name.
-func ConfigFor(name, podNamespace, watchNamespace string) *Config {
-	return &Config{
-		name:           name,
-		podNamespace:   podNamespace,
-		watchNamespace: watchNamespace,
+// ClusterConfigFor get config for the ClusterEventLogger with the given name.
+func ClusterConfigFor(name, podNamespace string) *Config {
+	c := &Config{
+		podNamespace: podNamespace,
+		cluster:      true,
 	}
+	c.current.Store(&snapshot{name: name})
+	return c
 }
 
-// Config event config.
+// Config event config. The parts applied from the cr are held in an immutable snapshot, that is swapped atomically
+// by the reconciler, so that the events are always processed with a consistent config.
 type Config struct {
-	podNamespace   string
-	watchNamespace string
-	name           string
-	logFields      []eventloggerv1.LogField
-	filter         filter.Filter
+	podNamespace    string
+	watchNamespaces []string
+	// cluster the config is a ClusterEventLogger
+	cluster bool
+	current atomic.Pointer[snapshot]
+	// inUse is held for reading while an event is processed with a snapshot, the resources of a replaced snapshot
+	// are closed once it is not in use anymore
+	inUse sync.RWMutex
+}
+
+// snapshot is the immutable state of the config applied from the cr.
+type snapshot struct {
+	name              string
+	excludeNamespaces []string
+	logFieldSpecs     []eventloggerv1.LogField
+	logFields         []logField
+	filter            filter.Filter
+	kindClauses       []clauses
+	sinkSpecs         []eventloggerv1.Sink
+	sinks             *sink.Fanout
+	notifyKinds       []eventloggerv1.Kind
+	notifiers         []notifier
+	throttleSpec      throttleSpec
+	throttle          *throttle.Throttle
 }
 
-func (c Config) matches(meta metav1.Object) bool {
-	if c.watchNamespace == "" {
-		return c.podNamespace == meta.GetNamespace() && (c.name == meta.GetName())
+// emptySnapshot the snapshot of a config that was not applied yet.
+var emptySnapshot = &snapshot{}
+
+// load returns the current snapshot.
+func (c *Config) load() *snapshot {
+	if s := c.current.Load(); s != nil {
+		return s
+	}
+	return emptySnapshot
+}
+
+// acquire returns the current snapshot, its resources are not closed until release is called.
+func (c *Config) acquire() (s *snapshot, release func()) {
+	c.inUse.RLock()
+	return c.load(), c.inUse.RUnlock
+}
+
+// swap stores the next snapshot and returns the previous one, once it is not in use anymore.
+func (c *Config) swap(next *snapshot) *snapshot {
+	prev := c.current.Swap(next)
+	// wait for the events processed with the previous snapshot
+	c.inUse.Lock()
+	defer c.inUse.Unlock()
+	if prev == nil {
+		return emptySnapshot
+	}
+	return prev
+}
+
+// reset removes the applied config, keeping only the name. The throttle is closed once the events processed with
+// the applied config are done, its pending summaries are written to the sinks before they are closed.
+func (c *Config) reset(log logr.Logger) {
+	cur := c.load()
+	// the sinks are kept until the throttle is closed, no events are logged without a filter
+	prev := c.swap(&snapshot{name: cur.name, sinks: cur.sinks})
+	closeThrottle(prev.throttle)
+	c.swap(&snapshot{name: cur.name})
+	closeSinks(prev.sinks, log)
+	closeNotifiers(prev.notifiers, log)
+}
+
+func closeSinks(sinks *sink.Fanout, log logr.Logger) {
+	if sinks == nil {
+		return
+	}
+	if err := sinks.Close(); err != nil {
+		log.Error(err, "error closing sinks")
 	}
-	return c.watchNamespace == meta.GetNamespace() && (c.name == meta.GetName())
+}
+
+func closeThrottle(t *throttle.Throttle) {
+	if t == nil {
+		return
+	}
+	// report the pending summaries before the throttle is removed
+	t.Close()
+}
+
+func (c *Config) matches(meta metav1.Object) bool {
+	name := c.load().name
+	if c.cluster {
+		return meta.GetNamespace() == "" && name == meta.GetName()
+	}
+	if len(c.watchNamespaces) == 0 {
+		return c.podNamespace == meta.GetNamespace() && (name == meta.GetName())
+	}
+	return slices.Contains(c.watchNamespaces, meta.GetNamespace()) && (name == meta.GetName())
+}
+
+// excluded returns true if the namespace of the event is excluded by a ClusterEventLogger.
+func (s *snapshot) excluded(e *corev1.Event) bool {
+	return matchesGlob(s.excludeNamespaces, e.Namespace)
+}
+
+// operatorEvent returns true if the event was recorded by the operator.
+func operatorEvent(e *corev1.Event) bool {
+	return e.ReportingController == cnst.EventReportingController || e.Source.Component == cnst.EventReportingController
 }
 
 // contains check if a string in a []string exists.
@@ -2,6 +2,9 @@ package logging
 
 import (
 	"github.com/google/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -14,9 +17,34 @@ var _ = Describe("Logging", func() {
 			podNs := uuid.NewString()
 			watchNs := uuid.NewString()
 			cfg := ConfigFor(name, podNs, watchNs)
-			Ω(cfg.name).Should(Equal(name))
+			Ω(cfg.load().name).Should(Equal(name))
 			Ω(cfg.podNamespace).Should(Equal(podNs))
-			Ω(cfg.watchNamespace).Should(Equal(watchNs))
+			Ω(cfg.watchNamespaces).Should(Equal([]string{watchNs}))
+		})
+		It("should match the event logger in any of the watched namespaces", func() {
+			cfg := ConfigFor("logger", "operator", "a", "b")
+			Ω(cfg.matches(&metav1.ObjectMeta{Name: "logger", Namespace: "a"})).Should(BeTrue())
+			Ω(cfg.matches(&metav1.ObjectMeta{Name: "logger", Namespace: "b"})).Should(BeTrue())
+			Ω(cfg.matches(&metav1.ObjectMeta{Name: "logger", Namespace: "operator"})).Should(BeFalse())
+			Ω(cfg.matches(&metav1.ObjectMeta{Name: "other", Namespace: "a"})).Should(BeFalse())
+		})
+	})
+	Context("ClusterConfigFor", func() {
+		It("should match the cluster event logger only", func() {
+			cfg := ClusterConfigFor("cluster", "operator")
+			Ω(cfg.cluster).Should(BeTrue())
+			Ω(cfg.matches(&metav1.ObjectMeta{Name: "cluster"})).Should(BeTrue())
+			Ω(cfg.matches(&metav1.ObjectMeta{Name: "other"})).Should(BeFalse())
+			Ω(cfg.matches(&metav1.ObjectMeta{Name: "cluster", Namespace: "operator"})).Should(BeFalse())
+		})
+	})
+	Context("newFilter", func() {
+		It("should return an error for an invalid matching pattern", func() {
+			spec := apiv1.EventLoggerSpec{Kinds: []apiv1.Kind{{Name: "Pod", MatchingPatterns: []string{"(unclosed"}}}}
+			_, err := newFilter(spec, nil)
+			Ω(err).Should(HaveOccurred())
+			_, err = newKindClauses(spec, nil)
+			Ω(err).Should(HaveOccurred())
 		})
 	})
 })
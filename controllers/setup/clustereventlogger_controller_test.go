@@ -0,0 +1,123 @@
+package setup
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	c "github.com/bakito/k8s-event-logger-operator/pkg/constants"
+	"github.com/bakito/k8s-event-logger-operator/version"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClusterEventLogger", func() {
+	var (
+		cel *apiv1.ClusterEventLogger
+		r   *Reconciler
+	)
+
+	BeforeEach(func() {
+		cel = &apiv1.ClusterEventLogger{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Spec: apiv1.ClusterEventLoggerSpec{
+				EventLoggerSpec: apiv1.EventLoggerSpec{
+					EventAPI: apiv1.EventAPIEventsV1,
+					Sinks: []apiv1.Sink{{
+						Name: "webhook",
+						Type: apiv1.SinkTypeWebhook,
+						Webhook: &apiv1.WebhookSink{
+							URL:              "https://example.com",
+							HeadersSecretRef: &corev1.LocalObjectReference{Name: "headers"},
+						},
+					}},
+				},
+				ExcludeNamespaces: []string{"kube-*"},
+			},
+		}
+		r = &Reconciler{Cluster: true, Namespace: testNamespace}
+	})
+
+	reconcileCluster := func(initialObjects ...client.Object) client.Client {
+		cl, _ := testReconcileWith(r, types.NamespacedName{Name: cel.Name}, initialObjects...)
+		return cl
+	}
+
+	It("should create the logger deployment in the namespace of the operator", func() {
+		cl := reconcileCluster(cel)
+
+		deploy := loggerDeployment(cl, cel)
+		Ω(deploy.Namespace).Should(Equal(testNamespace))
+		Ω(deploy.Name).Should(Equal("cluster-event-logger-cluster"))
+		Ω(deploy.OwnerReferences).Should(HaveLen(1))
+		Ω(deploy.OwnerReferences[0].Kind).Should(Equal("ClusterEventLogger"))
+		pod := deploy.Spec.Template
+		Ω(pod.Spec.ServiceAccountName).Should(Equal("cluster-event-logger-cluster"))
+		Ω(podEnv(&pod.Spec, c.EnvWatchNamespace)).Should(BeEmpty())
+		flags := parseLoggerArgs(pod.Spec.Containers[0].Args)
+		Ω(flags.configName).Should(Equal("cluster"))
+		Ω(flags.loggerMode).Should(BeTrue())
+		Ω(flags.clusterConfig).Should(BeTrue())
+	})
+
+	It("should grant the events of all namespaces with a cluster role", func() {
+		cl := reconcileCluster(cel)
+
+		assertEntrySize(cl, cel, &corev1.ServiceAccountList{}, 1)
+
+		role := &rbacv1.Role{}
+		Ω(cl.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: "cluster-event-logger-cluster"}, role)).
+			ShouldNot(HaveOccurred())
+		Ω(role.Rules).Should(HaveLen(2))
+		Ω(role.Rules[0].Resources).Should(Equal([]string{"pods"}))
+		Ω(role.Rules[1].Resources).Should(Equal([]string{"secrets"}))
+		Ω(role.Rules[1].ResourceNames).Should(Equal([]string{"headers"}))
+
+		clusterRole := &rbacv1.ClusterRole{}
+		Ω(cl.Get(context.TODO(), client.ObjectKey{Name: "cluster-event-logger-cluster"}, clusterRole)).
+			ShouldNot(HaveOccurred())
+		Ω(clusterRole.Rules).Should(HaveLen(4))
+		Ω(clusterRole.Rules[0].Resources).Should(Equal([]string{"events"}))
+		Ω(clusterRole.Rules[1].APIGroups).Should(Equal([]string{"events.k8s.io"}))
+		Ω(clusterRole.Rules[2].Resources).Should(Equal([]string{"clustereventloggers"}))
+		Ω(clusterRole.Rules[2].ResourceNames).Should(BeEmpty())
+		Ω(clusterRole.Rules[2].Verbs).Should(Equal([]string{"list", "watch"}))
+		Ω(clusterRole.Rules[3].Resources).Should(Equal([]string{"clustereventloggers", "clustereventloggers/status"}))
+		Ω(clusterRole.Rules[3].ResourceNames).Should(Equal([]string{"cluster"}))
+		Ω(clusterRole.Rules[3].Verbs).Should(Equal([]string{"get", "patch", "update"}))
+
+		crb := &rbacv1.ClusterRoleBinding{}
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(clusterRole), crb)).ShouldNot(HaveOccurred())
+		Ω(crb.RoleRef.Kind).Should(Equal("ClusterRole"))
+		Ω(crb.RoleRef.Name).Should(Equal(clusterRole.Name))
+		Ω(crb.Subjects).Should(HaveLen(1))
+		Ω(crb.Subjects[0].Name).Should(Equal("cluster-event-logger-cluster"))
+		Ω(crb.Subjects[0].Namespace).Should(Equal(testNamespace))
+	})
+
+	It("should update the status", func() {
+		cl := reconcileCluster(cel)
+
+		updated := &apiv1.ClusterEventLogger{}
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(cel), updated)).ShouldNot(HaveOccurred())
+		Ω(updated.Status.Hash).Should(Equal(cel.Hash()))
+		Ω(updated.Status.OperatorVersion).Should(Equal(version.Version))
+	})
+
+	It("should not be valid with a namespace", func() {
+		cel.Spec.Namespace = new("shop")
+		cl := reconcileCluster(cel)
+
+		updated := &apiv1.ClusterEventLogger{}
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(cel), updated)).ShouldNot(HaveOccurred())
+		Ω(updated.Status.Error).Should(ContainSubstring("is not supported by a ClusterEventLogger"))
+		assertEntrySize(cl, cel, &appsv1.DeploymentList{}, 0)
+	})
+})
@@ -18,22 +18,31 @@ package setup
 
 import (
 	"context"
+	"strings"
 
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/status"
 	"github.com/bakito/k8s-event-logger-operator/version"
 )
 
-var gracePeriod int64
-
 // Reconciler reconciles a Pod object.
 type Reconciler struct {
 	client.Client
@@ -41,54 +50,145 @@ type Reconciler struct {
 	Scheme *runtime.Scheme
 
 	ConfigCtx context.Context //nolint:containedctx
+	// Cluster if enabled, the controller reconciles ClusterEventLoggers. Their logger pods run in Namespace.
+	Cluster bool
+	// Namespace the namespace of the operator
+	Namespace string
+	// Recorder records the lifecycle events of the crs
+	Recorder events.EventRecorder
+	// Discovery resolves the api groups of the kinds with a label selector but without api group, they are not
+	// granted if nil
+	Discovery discovery.CachedDiscoveryInterface
 }
 
 // +kubebuilder:rbac:groups=eventlogger.bakito.ch,resources=eventloggers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=eventlogger.bakito.ch,resources=eventloggers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=eventlogger.bakito.ch,resources=clustereventloggers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=eventlogger.bakito.ch,resources=clustereventloggers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=eventlogger.bakito.ch,resources=eventloggers/finalizers;clustereventloggers/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=pods;serviceaccounts,verbs=*
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=*
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;clusterrolebindings,verbs=*
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=*
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=*
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 
-// Reconcile EventLogger to setup event logger pods.
+// Reconcile EventLogger or ClusterEventLogger to setup event logger pods.
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reqLogger := r.Log.WithValues("namespace", req.Namespace, "name", req.Name)
 
 	// Fetch the EventLogger cr
-	cr := &eventloggerv1.EventLogger{}
+	cr := r.newObject()
 	err := r.Get(ctx, req.NamespacedName, cr)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile req.
-			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// The objects are deleted by the finalizer, crs created without finalizer rely on the garbage collection
+			// of the owned objects and the rbac of watched namespaces is deleted by label.
 			// Return and don't requeue
-			return reconcile.Result{}, nil
+			cr.SetNamespace(req.Namespace)
+			cr.SetName(req.Name)
+			_, err = r.cleanupNamespaceRbac(ctx, cr, nil)
+			return reconcile.Result{}, err
 		}
 		// Error reading the object - requeue the req.
-		return r.updateCR(ctx, cr, reqLogger, err)
+		reqLogger.Error(err, "")
+		return reconcile.Result{}, err
+	}
+
+	if cr.GetDeletionTimestamp() != nil {
+		return r.finalize(ctx, cr, reqLogger)
+	}
+	if err = r.ensureFinalizer(ctx, cr); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	su := &statusUpdate{}
+
+	if err = cr.Validate(); err != nil {
+		su.setCondition(eventloggerv1.ConditionConfigValid, metav1.ConditionFalse, "ValidationFailed", err.Error())
+		if cr.HasChanged() {
+			r.Recorder.Eventf(cr, nil, corev1.EventTypeWarning, "ValidationFailed", "Validate", "%v", err)
+		}
+		return r.updateCR(ctx, cr, reqLogger, su, err)
 	}
+	su.setCondition(eventloggerv1.ConditionConfigValid, metav1.ConditionTrue, "Valid", "")
 
-	if err = cr.Spec.Validate(); err != nil {
-		return r.updateCR(ctx, cr, reqLogger, err)
+	watchNamespaces, err := r.watchNamespaces(ctx, cr)
+	if err != nil {
+		su.setCondition(eventloggerv1.ConditionRBACReady, metav1.ConditionFalse, "NamespacesNotSelected", err.Error())
+		return r.updateCR(ctx, cr, reqLogger, su, err)
 	}
 
 	saccChanged, roleChanged, rbChanged, err := r.setupRbac(ctx, cr)
 	if err != nil {
-		return r.updateCR(ctx, cr, reqLogger, err)
+		su.setCondition(eventloggerv1.ConditionRBACReady, metav1.ConditionFalse, "ProvisioningFailed", err.Error())
+		r.Recorder.Eventf(cr, nil, corev1.EventTypeWarning, "ProvisioningFailed", "ProvisionRBAC", "%v", err)
+		return r.updateCR(ctx, cr, reqLogger, su, err)
+	}
+	nsRbacChanged, err := r.setupNamespaceRbac(ctx, cr, watchNamespaces)
+	if err != nil {
+		su.setCondition(eventloggerv1.ConditionRBACReady, metav1.ConditionFalse, "ProvisioningFailed", err.Error())
+		r.Recorder.Eventf(cr, nil, corev1.EventTypeWarning, "ProvisioningFailed", "ProvisionRBAC", "%v", err)
+		return r.updateCR(ctx, cr, reqLogger, su, err)
+	}
+	su.setCondition(eventloggerv1.ConditionRBACReady, metav1.ConditionTrue, "Provisioned", "")
+	if saccChanged || roleChanged || rbChanged || nsRbacChanged {
+		r.Recorder.Eventf(cr, nil, corev1.EventTypeNormal, "RBACUpdated", "ProvisionRBAC",
+			"Updated the rbac of the logger, watched namespaces: %s", watchNamespacesNote(watchNamespaces))
 	}
 
-	// Define a new Pod object
-	pod := r.podForCR(cr)
+	// Define the pod template of the logger deployment
+	template, err := r.podTemplateForCR(cr, watchNamespaces)
+	if err != nil {
+		su.setCondition(eventloggerv1.ConditionPodRunning, metav1.ConditionFalse, "InvalidPodTemplate", err.Error())
+		return r.updateCR(ctx, cr, reqLogger, su, err)
+	}
 
-	// set owner reference for pod
-	if err := ctrl.SetControllerReference(cr, pod, r.Scheme); err != nil {
-		return r.updateCR(ctx, cr, reqLogger, err)
+	checkpointChanged, err := r.setupCheckpoint(ctx, cr)
+	if err != nil {
+		su.setCondition(eventloggerv1.ConditionPodRunning, metav1.ConditionFalse, "CreationFailed", err.Error())
+		r.Recorder.Eventf(cr, nil, corev1.EventTypeWarning, "CreationFailed", "RolloutLogger", "%v", err)
+		return r.updateCR(ctx, cr, reqLogger, su, err)
 	}
 
-	// Check if this Pod already exists
-	podChanged, err := r.createOrReplacePod(ctx, cr, pod, reqLogger)
+	deploy, rollout, err := r.createOrUpdateDeployment(ctx, cr, template, reqLogger)
+	if err != nil {
+		su.setCondition(eventloggerv1.ConditionPodRunning, metav1.ConditionFalse, "CreationFailed", err.Error())
+		r.Recorder.Eventf(cr, nil, corev1.EventTypeWarning, "CreationFailed", "RolloutLogger", "%v", err)
+		return r.updateCR(ctx, cr, reqLogger, su, err)
+	}
+	if rollout != nil {
+		r.recordRollout(cr, deploy, rollout)
+	}
+	pdbChanged, err := r.setupPodDisruptionBudget(ctx, cr)
 	if err != nil {
-		return r.updateCR(ctx, cr, reqLogger, err)
+		su.setCondition(eventloggerv1.ConditionPodRunning, metav1.ConditionFalse, "CreationFailed", err.Error())
+		r.Recorder.Eventf(cr, nil, corev1.EventTypeWarning, "CreationFailed", "RolloutLogger", "%v", err)
+		return r.updateCR(ctx, cr, reqLogger, su, err)
 	}
+	podsDeleted, err := r.deleteLegacyPods(ctx, cr, reqLogger)
+	if err != nil {
+		return r.updateCR(ctx, cr, reqLogger, su, err)
+	}
+	if su.loggerPod, err = r.activeLoggerPod(ctx, cr); err != nil {
+		return r.updateCR(ctx, cr, reqLogger, su, err)
+	}
+	su.rollout = rollout
+	podStatus, reason, message := deploymentCondition(deploy, rollout)
+	su.setCondition(eventloggerv1.ConditionPodRunning, podStatus, reason, message)
 
-	if cr.HasChanged() || saccChanged || roleChanged || rbChanged || podChanged {
+	if cr.HasChanged() {
+		r.Recorder.Eventf(cr, nil, corev1.EventTypeNormal, "ConfigApplied", "Reconcile",
+			"Applied the config of generation %d", cr.GetGeneration())
+	}
+
+	if cr.HasChanged() || saccChanged || roleChanged || rbChanged || nsRbacChanged || rollout != nil || pdbChanged ||
+		checkpointChanged || podsDeleted || su.changes(cr) {
 		reqLogger.Info("Reconciling event logger")
-		return r.updateCR(ctx, cr, reqLogger, nil)
+		return r.updateCR(ctx, cr, reqLogger, su, nil)
 	}
 
 	return reconcile.Result{}, nil
@@ -96,20 +196,92 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 func (r *Reconciler) updateCR(
 	ctx context.Context,
-	cr *eventloggerv1.EventLogger,
+	cr eventloggerv1.Object,
 	logger logr.Logger,
+	su *statusUpdate,
 	err error,
 ) (reconcile.Result, error) {
 	if err != nil {
 		logger.Error(err, "")
 	}
-	cr.Apply(err)
-	cr.Status.Hash = cr.Spec.Hash()
-	cr.Status.OperatorVersion = version.Version
-	err = r.Update(ctx, cr)
+	err = status.Patch(ctx, r.Client, cr, func(el eventloggerv1.Object) {
+		su.apply(el, err)
+	})
 	return reconcile.Result{}, err
 }
 
+// statusUpdate collects the status changes of a reconcile, to be applied to the latest version of the cr.
+type statusUpdate struct {
+	conditions []metav1.Condition
+	loggerPod  string
+	// rollout the rollout of the pod template of the logger deployment, nil if not rolled out
+	rollout *eventloggerv1.Rollout
+}
+
+func (su *statusUpdate) setCondition(
+	conditionType string,
+	conditionStatus metav1.ConditionStatus,
+	reason, message string,
+) {
+	su.conditions = append(su.conditions, metav1.Condition{
+		Type:    conditionType,
+		Status:  conditionStatus,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// apply applies the status changes to the cr.
+func (su *statusUpdate) apply(cr eventloggerv1.Object, err error) {
+	for _, c := range su.conditions {
+		cr.SetCondition(c.Type, c.Status, c.Reason, c.Message)
+	}
+	st := cr.GetStatus()
+	if su.rollout != nil {
+		// the rolled out pods have to apply the filter first
+		st.LastRollout = su.rollout
+		cr.SetCondition(eventloggerv1.ConditionFilterApplied, metav1.ConditionUnknown, "WaitingForLogger",
+			"waiting for the rolled out logger pods to apply the filter")
+	}
+	if su.loggerPod != "" && st.LoggerPod != su.loggerPod {
+		// the new pod has to apply the filter first
+		st.LoggerPod = su.loggerPod
+		cr.SetCondition(eventloggerv1.ConditionFilterApplied, metav1.ConditionUnknown, "WaitingForLogger",
+			"waiting for logger pod "+su.loggerPod+" to apply the filter")
+	}
+	cr.Apply(err)
+	st.Hash = cr.Hash()
+	st.OperatorVersion = version.Version
+}
+
+// changes returns true if applying the status changes would change more than the processing timestamp.
+func (su *statusUpdate) changes(cr eventloggerv1.Object) bool {
+	updated := cr.DeepCopyObject().(eventloggerv1.Object)
+	su.apply(updated, nil)
+	updated.GetStatus().LastProcessed = cr.GetStatus().LastProcessed
+	return !equality.Semantic.DeepEqual(cr.GetStatus(), updated.GetStatus())
+}
+
+// recordRollout records an event for the rollout of the logger pods.
+func (r *Reconciler) recordRollout(cr eventloggerv1.Object, deploy *appsv1.Deployment, rollout *eventloggerv1.Rollout) {
+	if rollout.Reason == eventloggerv1.RolloutReasonCreated {
+		r.Recorder.Eventf(cr, deploy, corev1.EventTypeNormal, "LoggerCreated", "RolloutLogger",
+			"Created logger deployment %s", deploy.Name)
+		return
+	}
+	r.Recorder.Eventf(cr, deploy, corev1.EventTypeNormal, "LoggerReplaced", "RolloutLogger",
+		"Replacing the logger pods of deployment %s, changed fields: %s", deploy.Name,
+		strings.Join(rollout.ChangedFields, ", "))
+}
+
+// watchNamespacesNote returns the watched namespaces for an event note.
+func watchNamespacesNote(watchNamespaces string) string {
+	if watchNamespaces == "" {
+		return "all"
+	}
+	return watchNamespaces
+}
+
 func (r *Reconciler) saveDelete(ctx context.Context, obj client.Object) error {
 	err := r.Delete(ctx, obj)
 	if err != nil {
@@ -120,38 +292,49 @@ func (r *Reconciler) saveDelete(ctx context.Context, obj client.Object) error {
 	return nil
 }
 
-func loggerName(cr *eventloggerv1.EventLogger) string {
-	return "event-logger-" + cr.Name
+// newObject returns a new empty cr of the kind reconciled.
+func (r *Reconciler) newObject() eventloggerv1.Object {
+	if r.Cluster {
+		return &eventloggerv1.ClusterEventLogger{}
+	}
+	return &eventloggerv1.EventLogger{}
 }
 
-func podChanged(oldPod, newPod *corev1.Pod) bool {
-	if oldPod.Spec.ServiceAccountName != newPod.Spec.ServiceAccountName {
-		return true
-	}
-	if len(oldPod.Spec.Containers) > 0 && len(newPod.Spec.Containers) > 0 &&
-		oldPod.Spec.Containers[0].Image != newPod.Spec.Containers[0].Image {
-		return true
+// loggerNamespace returns the namespace of the logger pod, cluster event loggers run in the namespace of the operator.
+func (r *Reconciler) loggerNamespace(cr eventloggerv1.Object) string {
+	if r.Cluster {
+		return r.Namespace
 	}
-
-	return podEnv(oldPod, "WATCH_NAMESPACE") != podEnv(newPod, "WATCH_NAMESPACE")
+	return cr.GetNamespace()
 }
 
-func podEnv(pod *corev1.Pod, name string) string {
-	for _, env := range pod.Spec.Containers[0].Env {
-		if env.Name == name {
-			return env.Value
-		}
+func loggerName(cr eventloggerv1.Object) string {
+	if _, ok := cr.(*eventloggerv1.ClusterEventLogger); ok {
+		return "cluster-event-logger-" + cr.GetName()
 	}
-	return "N/A"
+	return "event-logger-" + cr.GetName()
 }
 
 // SetupWithManager setup with manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&eventloggerv1.EventLogger{}).
-		Owns(&corev1.Pod{}).
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(r.newObject()).
+		Owns(&appsv1.Deployment{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
 		Owns(&corev1.ServiceAccount{}).
 		Owns(&rbacv1.Role{}).
-		Owns(&rbacv1.RoleBinding{}).
+		Owns(&rbacv1.RoleBinding{})
+	if r.Cluster {
+		return b.
+			Owns(&rbacv1.ClusterRole{}).
+			Owns(&rbacv1.ClusterRoleBinding{}).
+			Complete(r)
+	}
+	return b.
+		Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.loggersForNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
 		Complete(r)
 }
@@ -0,0 +1,71 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package setup
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+)
+
+// defaultCheckpointInterval the default interval the logger stores its checkpoint in.
+const defaultCheckpointInterval = 10 * time.Second
+
+// setupCheckpoint creates the config map the logger stores its checkpoint in, if the checkpoint is enabled.
+// The data is written by the logger, only the labels and the owner are managed. If the checkpoint is disabled,
+// the config map is deleted.
+func (r *Reconciler) setupCheckpoint(ctx context.Context, cr eventloggerv1.Object) (bool, error) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      checkpointName(cr),
+			Namespace: r.loggerNamespace(cr),
+		},
+	}
+	if cr.GetSpec().Checkpoint == nil {
+		err := r.Get(ctx, client.ObjectKeyFromObject(cm), cm)
+		if err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return true, r.saveDelete(ctx, cm)
+	}
+
+	res, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = copyLabels(cr)
+		return ctrl.SetControllerReference(cr, cm, r.Scheme)
+	})
+	return res != controllerutil.OperationResultNone, err
+}
+
+// checkpointName returns the name of the config map the logger stores its checkpoint in.
+func checkpointName(cr eventloggerv1.Object) string {
+	return loggerName(cr) + "-checkpoint"
+}
+
+// checkpointInterval returns the interval the logger stores its checkpoint in.
+func checkpointInterval(cr eventloggerv1.Object) time.Duration {
+	if cp := cr.GetSpec().Checkpoint; cp != nil && cp.Interval != nil && cp.Interval.Duration > 0 {
+		return cp.Interval.Duration
+	}
+	return defaultCheckpointInterval
+}
@@ -0,0 +1,84 @@
+package setup
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	c "github.com/bakito/k8s-event-logger-operator/pkg/constants"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checkpoint", func() {
+	var el *apiv1.EventLogger
+
+	BeforeEach(func() {
+		el = &apiv1.EventLogger{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "eventlogger",
+				Namespace: testNamespace,
+			},
+			Spec: apiv1.EventLoggerSpec{
+				Checkpoint: &apiv1.Checkpoint{Interval: &metav1.Duration{Duration: time.Minute}},
+			},
+		}
+	})
+
+	It("should create the config map of the checkpoint", func() {
+		cl, _ := testReconcile(el)
+
+		cms := &corev1.ConfigMapList{}
+		assertEntrySize(cl, el, cms, 1)
+		Ω(cms.Items[0].Name).Should(Equal(checkpointName(el)))
+		Ω(cms.Items[0].OwnerReferences).Should(HaveLen(1))
+
+		deploy := loggerDeployment(cl, el)
+		Ω(podEnv(&deploy.Spec.Template.Spec, c.EnvCheckpointConfigMap)).Should(Equal(checkpointName(el)))
+		Ω(podEnv(&deploy.Spec.Template.Spec, c.EnvCheckpointInterval)).Should(Equal("1m0s"))
+
+		role := &rbacv1.Role{}
+		Ω(cl.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: loggerName(el)}, role)).
+			ShouldNot(HaveOccurred())
+		rule := role.Rules[len(role.Rules)-1]
+		Ω(rule.Resources).Should(Equal([]string{"configmaps"}))
+		Ω(rule.ResourceNames).Should(Equal([]string{checkpointName(el)}))
+	})
+
+	It("should keep the stored checkpoint", func() {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: checkpointName(el), Namespace: testNamespace},
+			Data:       map[string]string{"resourceVersion": "42"},
+		}
+		cl, _ := testReconcile(el, cm)
+
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(cm), cm)).ShouldNot(HaveOccurred())
+		Ω(cm.Data).Should(HaveKeyWithValue("resourceVersion", "42"))
+		Ω(cm.Labels).Should(Equal(copyLabels(el)))
+	})
+
+	It("should default the interval", func() {
+		el.Spec.Checkpoint.Interval = nil
+		Ω(checkpointInterval(el)).Should(Equal(defaultCheckpointInterval))
+	})
+
+	It("should delete the config map if the checkpoint is disabled", func() {
+		el.Spec.Checkpoint = nil
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      checkpointName(el),
+			Namespace: testNamespace,
+			Labels:    copyLabels(el),
+		}}
+		cl, _ := testReconcile(el, cm)
+
+		assertEntrySize(cl, el, &corev1.ConfigMapList{}, 0)
+		deploy := loggerDeployment(cl, el)
+		Ω(podEnv(&deploy.Spec.Template.Spec, c.EnvCheckpointConfigMap)).Should(Equal("N/A"))
+	})
+})
@@ -0,0 +1,547 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package setup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/controllers/config"
+	cnst "github.com/bakito/k8s-event-logger-operator/pkg/constants"
+)
+
+const (
+	labelComponent = "app.kubernetes.io/component"
+	labelManagedBy = "app.kubernetes.io/managed-by"
+	// annotationPodTemplateHash the hash of the pod template the deployment was last rolled out with.
+	annotationPodTemplateHash = "eventlogger.bakito.ch/pod-template-hash"
+	// annotationPodTemplateFieldHashes the hashes of the fields of the pod template the deployment was last rolled out
+	// with, to report the changed fields of a rollout.
+	annotationPodTemplateFieldHashes = "eventlogger.bakito.ch/pod-template-field-hashes"
+	// annotationLivePodTemplateFieldHashes the hashes of the fields of the pod template as stored by the api server
+	// after the last rollout, to detect manual changes of the pod template.
+	annotationLivePodTemplateFieldHashes = "eventlogger.bakito.ch/live-pod-template-field-hashes"
+	// loggerContainerName the name of the logger container, the containers of pod templates are merged by name.
+	loggerContainerName = "event-logger"
+)
+
+// createOrUpdateDeployment creates the logger deployment or updates it. The pod template is only replaced if its
+// hash differs from the hash the deployment was rolled out with, or if the pod template of the deployment was changed
+// manually, which triggers a rolling update of the logger pods. Returns the rollout if the pod template was rolled out.
+func (r *Reconciler) createOrUpdateDeployment(
+	ctx context.Context,
+	cr eventloggerv1.Object,
+	template corev1.PodTemplateSpec,
+	reqLogger logr.Logger,
+) (*appsv1.Deployment, *eventloggerv1.Rollout, error) {
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      loggerName(cr),
+			Namespace: r.loggerNamespace(cr),
+		},
+	}
+	hash := podTemplateHash(template)
+	fieldHashes := podTemplateFieldHashes(template)
+	var rollout *eventloggerv1.Rollout
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, deploy, func() error {
+		deploy.Labels = copyLabels(cr)
+		deploy.Spec.Replicas = ptr.To(replicas(cr))
+		deploy.Spec.Strategy = deploymentStrategy(replicas(cr))
+		if deploy.Spec.Selector == nil {
+			// the selector is immutable
+			deploy.Spec.Selector = &metav1.LabelSelector{MatchLabels: defaultLabels(cr)}
+		}
+		if deploy.Annotations == nil {
+			deploy.Annotations = make(map[string]string)
+		}
+		if deploy.Annotations[annotationPodTemplateHash] != hash {
+			rollout = &eventloggerv1.Rollout{
+				Time:            metav1.Now(),
+				Reason:          eventloggerv1.RolloutReasonCreated,
+				PodTemplateHash: hash,
+			}
+			if deploy.Annotations[annotationPodTemplateHash] != "" {
+				rollout.Reason = eventloggerv1.RolloutReasonPodTemplateChanged
+				rollout.ChangedFields = changedFields(deploy.Annotations[annotationPodTemplateFieldHashes], fieldHashes)
+			}
+			deploy.Annotations[annotationPodTemplateHash] = hash
+			deploy.Spec.Template = template
+		} else if drifted := driftedFields(deploy); len(drifted) > 0 {
+			rollout = &eventloggerv1.Rollout{
+				Time:            metav1.Now(),
+				Reason:          eventloggerv1.RolloutReasonPodTemplateDrifted,
+				ChangedFields:   drifted,
+				PodTemplateHash: hash,
+			}
+			deploy.Spec.Template = template
+		}
+		deploy.Annotations[annotationPodTemplateFieldHashes] = fieldHashes
+		return ctrl.SetControllerReference(cr, deploy, r.Scheme)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// the pod template is defaulted by the api server, manual changes are detected with the hashes of its live fields
+	if live := podTemplateFieldHashes(deploy.Spec.Template); deploy.Annotations[annotationLivePodTemplateFieldHashes] != live {
+		patch := client.MergeFrom(deploy.DeepCopy())
+		deploy.Annotations[annotationLivePodTemplateFieldHashes] = live
+		if err := r.Patch(ctx, deploy, patch); err != nil {
+			return nil, nil, err
+		}
+	}
+	if rollout != nil {
+		reqLogger.Info("Rolling out logger pods", "namespace", deploy.Namespace, "name", deploy.Name,
+			"podTemplateHash", hash, "reason", rollout.Reason, "changedFields", rollout.ChangedFields)
+	}
+	return deploy, rollout, nil
+}
+
+// deploymentStrategy returns the strategy of the logger deployment. A single logger pod is recreated, so that never
+// two pods log the same events. Multiple replicas elect a leader and are updated one by one.
+func deploymentStrategy(replicas int32) appsv1.DeploymentStrategy {
+	if replicas <= 1 {
+		return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+			MaxSurge:       ptr.To(intstr.FromInt32(1)),
+		},
+	}
+}
+
+// setupPodDisruptionBudget keeps one logger pod available during voluntary disruptions if the logger has multiple
+// replicas. With a single replica the budget would block node drains, it is deleted.
+func (r *Reconciler) setupPodDisruptionBudget(ctx context.Context, cr eventloggerv1.Object) (bool, error) {
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      loggerName(cr),
+			Namespace: r.loggerNamespace(cr),
+		},
+	}
+	if replicas(cr) <= 1 {
+		err := r.Get(ctx, client.ObjectKeyFromObject(pdb), pdb)
+		if err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		return true, r.saveDelete(ctx, pdb)
+	}
+
+	res, err := controllerutil.CreateOrUpdate(ctx, r.Client, pdb, func() error {
+		pdb.Labels = copyLabels(cr)
+		pdb.Spec.MinAvailable = ptr.To(intstr.FromInt32(1))
+		pdb.Spec.Selector = &metav1.LabelSelector{MatchLabels: defaultLabels(cr)}
+		return ctrl.SetControllerReference(cr, pdb, r.Scheme)
+	})
+	return res != controllerutil.OperationResultNone, err
+}
+
+// deleteLegacyPods deletes the bare logger pods created before the logger was run as deployment.
+func (r *Reconciler) deleteLegacyPods(ctx context.Context, cr eventloggerv1.Object, reqLogger logr.Logger) (bool, error) {
+	podList, err := r.findPods(ctx, cr, defaultLabels(cr))
+	if err != nil {
+		return false, err
+	}
+	// old labels
+	oldPods, err := r.findPods(ctx, cr, map[string]string{
+		"app":        loggerName(cr),
+		"created-by": "eventlogger",
+	})
+	if err != nil {
+		return false, err
+	}
+
+	deleted := false
+	for _, p := range append(podList.Items, oldPods.Items...) {
+		if owner := metav1.GetControllerOf(&p); owner != nil && owner.Kind == "ReplicaSet" {
+			continue
+		}
+		reqLogger.Info("Deleting legacy logger pod", "namespace", p.GetNamespace(), "name", p.GetName())
+		if err := r.saveDelete(ctx, &p); err != nil {
+			return false, err
+		}
+		deleted = true
+	}
+	return deleted, nil
+}
+
+// activeLoggerPod returns the name of the logger pod of a logger with a single replica, or an empty string if
+// there is no such pod. With multiple replicas, the elected leader reports itself as logger pod.
+func (r *Reconciler) activeLoggerPod(ctx context.Context, cr eventloggerv1.Object) (string, error) {
+	if replicas(cr) > 1 {
+		return "", nil
+	}
+	podList, err := r.findPods(ctx, cr, defaultLabels(cr))
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, p := range podList.Items {
+		owner := metav1.GetControllerOf(&p)
+		if p.DeletionTimestamp == nil && owner != nil && owner.Kind == "ReplicaSet" {
+			names = append(names, p.Name)
+		}
+	}
+	if len(names) != 1 {
+		return "", nil
+	}
+	return names[0], nil
+}
+
+// deploymentCondition returns the status, reason and message of the PodRunning condition for the deployment.
+func deploymentCondition(deploy *appsv1.Deployment, rollout *eventloggerv1.Rollout) (metav1.ConditionStatus, string, string) {
+	if rollout != nil {
+		msg := "logger pods of deployment " + deploy.Name + " are rolled out"
+		if len(rollout.ChangedFields) > 0 {
+			msg += ", changed fields: " + strings.Join(rollout.ChangedFields, ", ")
+		}
+		return metav1.ConditionFalse, "RollingOut", msg
+	}
+	replicas := ptr.Deref(deploy.Spec.Replicas, 1)
+	if deploy.Status.ObservedGeneration < deploy.Generation || deploy.Status.UpdatedReplicas < replicas {
+		return metav1.ConditionFalse, "Progressing", "logger pods of deployment " + deploy.Name + " are rolled out"
+	}
+	if deploy.Status.AvailableReplicas < 1 {
+		return metav1.ConditionFalse, "Unavailable", "no logger pod of deployment " + deploy.Name + " is available"
+	}
+	return metav1.ConditionTrue, "Available", fmt.Sprintf("%d/%d logger pods of deployment %s are available",
+		deploy.Status.AvailableReplicas, replicas, deploy.Name)
+}
+
+func (r *Reconciler) findPods(
+	ctx context.Context,
+	cr eventloggerv1.Object,
+	matchLabels map[string]string,
+) (*corev1.PodList, error) {
+	podList := &corev1.PodList{}
+	opts := []client.ListOption{
+		client.InNamespace(r.loggerNamespace(cr)),
+		client.MatchingLabels(matchLabels),
+	}
+	return podList, r.List(ctx, podList, opts...)
+}
+
+// podTemplateForCR returns the pod template of the logger deployment, watching the given comma separated namespaces.
+// The pod template of the cr is strategically merged on top of the pod based on the container template of the config.
+// The pods of a cluster event logger run in the namespace of the operator.
+func (r *Reconciler) podTemplateForCR(cr eventloggerv1.Object, watchNamespace string) (corev1.PodTemplateSpec, error) {
+	metricsAddrFlag := flag.Lookup(cnst.ArgMetricsAddr)
+	var metricsAddr string
+	if metricsAddrFlag != nil {
+		metricsAddr = metricsAddrFlag.Value.String()
+	}
+	if metricsAddr == "" {
+		metricsAddr = cnst.DefaultMetricsAddr
+	}
+	metricsPort := metricsAddr[:1]
+
+	annotations := make(map[string]string)
+	maps.Copy(annotations, cr.GetSpec().Annotations)
+	if cr.GetSpec().ScrapeMetrics != nil && *cr.GetSpec().ScrapeMetrics {
+		annotations["prometheus.io/port"] = metricsPort
+		annotations["prometheus.io/scrape"] = "true"
+	}
+
+	saccName := serviceAccountName(cr)
+
+	container := config.GetCfg(r.ConfigCtx).ContainerTemplate
+	container.Name = loggerContainerName
+
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      copyLabels(cr),
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				container,
+			},
+			ImagePullSecrets: cr.GetSpec().ImagePullSecrets,
+			NodeSelector:     cr.GetSpec().NodeSelector,
+		},
+	}
+
+	if cr.GetSpec().PodTemplate != nil {
+		var err error
+		if template, err = mergePodTemplate(template, cr.GetSpec().PodTemplate); err != nil {
+			return template, fmt.Errorf("could not merge the pod template: %w", err)
+		}
+	}
+
+	// the fields managed by the operator can not be overridden
+	applyDefaultLabels(cr, template.Labels)
+	template.Spec.ServiceAccountName = saccName
+
+	idx := slices.IndexFunc(template.Spec.Containers, func(c corev1.Container) bool {
+		return c.Name == loggerContainerName
+	})
+	if idx < 0 {
+		return template, fmt.Errorf("the pod template must contain the container %q", loggerContainerName)
+	}
+	logger := &template.Spec.Containers[idx]
+	logger.Command = []string{"/opt/go/k8s-event-logger"}
+	// bool flags do not read a separate value, a bare value would stop the parsing of the following flags
+	logger.Args = []string{
+		"--" + cnst.ArgConfigName, cr.GetName(),
+		"--" + cnst.ArgMetricsAddr, metricsAddr,
+		"--" + cnst.ArgEnableLoggerMode + "=true",
+	}
+	if r.Cluster {
+		logger.Args = append(logger.Args, "--"+cnst.ArgClusterConfig+"=true")
+	}
+	if replicas(cr) > 1 {
+		logger.Args = append(logger.Args, "--"+cnst.ArgEnableLeaderElection+"=true")
+	}
+	env := []corev1.EnvVar{
+		{Name: cnst.EnvWatchNamespace, Value: watchNamespace},
+		{Name: cnst.EnvEventAPI, Value: string(eventAPI(cr))},
+		{Name: cnst.EnvPodName, ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				APIVersion: "v1",
+				FieldPath:  "metadata.name",
+			},
+		}},
+		{Name: cnst.EnvPodNamespace, ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				APIVersion: "v1",
+				FieldPath:  "metadata.namespace",
+			},
+		}},
+	}
+	if cr.GetSpec().Checkpoint != nil {
+		env = append(env,
+			corev1.EnvVar{Name: cnst.EnvCheckpointConfigMap, Value: checkpointName(cr)},
+			corev1.EnvVar{Name: cnst.EnvCheckpointInterval, Value: checkpointInterval(cr).String()},
+		)
+	}
+	// additional env variables of the pod template are kept
+	for _, e := range logger.Env {
+		if !slices.ContainsFunc(env, func(o corev1.EnvVar) bool { return o.Name == e.Name }) {
+			env = append(env, e)
+		}
+	}
+	logger.Env = env
+
+	// the directories of the file sinks are backed by an emptyDir, unless the pod template mounts a volume there
+	for i, dir := range fileSinkDirs(cr, logger.VolumeMounts) {
+		name := fmt.Sprintf("file-sink-%d", i)
+		template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+			Name:         name,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+		logger.VolumeMounts = append(logger.VolumeMounts, corev1.VolumeMount{Name: name, MountPath: dir})
+	}
+
+	return template, nil
+}
+
+// fileSinkDirs returns the directories of the absolute paths of the file sinks, that are not within a mounted volume.
+func fileSinkDirs(cr eventloggerv1.Object, mounts []corev1.VolumeMount) []string {
+	var dirs []string
+	for _, s := range cr.GetSpec().Sinks {
+		if s.Type != eventloggerv1.SinkTypeFile || s.File == nil || !path.IsAbs(s.File.Path) {
+			continue
+		}
+		dir := path.Dir(path.Clean(s.File.Path))
+		mounted := slices.ContainsFunc(mounts, func(m corev1.VolumeMount) bool {
+			return isWithin(dir, m.MountPath)
+		})
+		if dir == "/" || mounted || slices.ContainsFunc(dirs, func(d string) bool { return isWithin(dir, d) }) {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// isWithin returns true if the directory is the parent directory or below it.
+func isWithin(dir, parent string) bool {
+	parent = path.Clean(parent)
+	return dir == parent || strings.HasPrefix(dir, strings.TrimSuffix(parent, "/")+"/")
+}
+
+// mergePodTemplate strategically merges the overlay on top of the pod template. Fields not set in the overlay
+// are serialized as null, they are removed from the patch, so that they do not delete the fields of the template.
+func mergePodTemplate(template corev1.PodTemplateSpec, overlay *corev1.PodTemplateSpec) (corev1.PodTemplateSpec, error) {
+	original, err := json.Marshal(template)
+	if err != nil {
+		return template, err
+	}
+	patch, err := runtime.DefaultUnstructuredConverter.ToUnstructured(overlay)
+	if err != nil {
+		return template, err
+	}
+	patchBytes, err := json.Marshal(removeNulls(patch))
+	if err != nil {
+		return template, err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, patchBytes, corev1.PodTemplateSpec{})
+	if err != nil {
+		return template, err
+	}
+	result := corev1.PodTemplateSpec{}
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return template, err
+	}
+	return result, nil
+}
+
+// removeNulls removes all null values of the map and its nested maps and lists.
+func removeNulls(m map[string]any) map[string]any {
+	for k, v := range m {
+		switch val := v.(type) {
+		case nil:
+			delete(m, k)
+		case map[string]any:
+			removeNulls(val)
+		case []any:
+			for _, item := range val {
+				if im, ok := item.(map[string]any); ok {
+					removeNulls(im)
+				}
+			}
+		}
+	}
+	return m
+}
+
+// podTemplateHash returns the hash of the pod template.
+func podTemplateHash(template corev1.PodTemplateSpec) string {
+	return hashOf(template)[:16]
+}
+
+// podTemplateFieldHashes returns the json encoded hashes of the fields of the pod template, the fields of the containers
+// are hashed per container. Comparing them with the hashes of the rolled out template reports the changed fields.
+func podTemplateFieldHashes(template corev1.PodTemplateSpec) string {
+	hashes := make(map[string]string)
+	addFieldHashes(hashes, "metadata.", template.ObjectMeta)
+	spec := template.Spec
+	spec.Containers = nil
+	addFieldHashes(hashes, "", spec)
+	for _, c := range template.Spec.Containers {
+		addFieldHashes(hashes, "containers["+c.Name+"].", c)
+	}
+	bytes, _ := json.Marshal(hashes)
+	return string(bytes)
+}
+
+// addFieldHashes adds the hash of each field of the json representation of the object that is set.
+func addFieldHashes(hashes map[string]string, prefix string, obj any) {
+	bytes, _ := json.Marshal(obj)
+	fields := make(map[string]any)
+	_ = json.Unmarshal(bytes, &fields)
+	for name, value := range fields {
+		if value != nil {
+			hashes[prefix+name] = hashOf(value)[:8]
+		}
+	}
+}
+
+// driftedFields returns the fields of the pod template of the deployment that were changed since the last rollout.
+// No fields are reported if the deployment was not rolled out by this version of the operator yet.
+func driftedFields(deploy *appsv1.Deployment) []string {
+	live := deploy.Annotations[annotationLivePodTemplateFieldHashes]
+	if live == "" {
+		return nil
+	}
+	return changedFields(live, podTemplateFieldHashes(deploy.Spec.Template))
+}
+
+// changedFields returns the sorted names of the fields whose hashes differ. If the previous hashes are unknown,
+// no fields are reported.
+func changedFields(previous, current string) []string {
+	prev := make(map[string]string)
+	curr := make(map[string]string)
+	if json.Unmarshal([]byte(previous), &prev) != nil || json.Unmarshal([]byte(current), &curr) != nil {
+		return nil
+	}
+	var changed []string
+	for name, hash := range curr {
+		if prev[name] != hash {
+			changed = append(changed, name)
+		}
+	}
+	for name := range prev {
+		if _, ok := curr[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+func hashOf(obj any) string {
+	h := sha256.New()
+	bytes, _ := json.Marshal(obj)
+	_, _ = h.Write(bytes)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func copyLabels(cr eventloggerv1.Object) map[string]string {
+	labels := make(map[string]string)
+	maps.Copy(labels, cr.GetSpec().Labels)
+	applyDefaultLabels(cr, labels)
+	return labels
+}
+
+func defaultLabels(cr eventloggerv1.Object) map[string]string {
+	labels := make(map[string]string)
+	applyDefaultLabels(cr, labels)
+	return labels
+}
+
+func applyDefaultLabels(cr eventloggerv1.Object, labels map[string]string) {
+	labels[labelComponent] = loggerName(cr)
+	labels[labelManagedBy] = "eventlogger"
+}
+
+// eventAPI returns the api version of the events the logger pod watches.
+func eventAPI(cr eventloggerv1.Object) eventloggerv1.EventAPI {
+	if cr.GetSpec().EventAPI == "" {
+		return eventloggerv1.EventAPICoreV1
+	}
+	return cr.GetSpec().EventAPI
+}
+
+// replicas returns the number of logger pods.
+func replicas(cr eventloggerv1.Object) int32 {
+	return ptr.Deref(cr.GetSpec().Replicas, 1)
+}
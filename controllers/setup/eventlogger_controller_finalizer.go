@@ -0,0 +1,193 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package setup
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+)
+
+const (
+	// finalizerCleanup the finalizer to stop the logger pods and to delete the objects managed for the cr.
+	finalizerCleanup = "eventlogger.bakito.ch/cleanup"
+	// gracefulStopTimeout the max time to wait for the logger pods to stop, before the objects are deleted anyway.
+	gracefulStopTimeout = 2 * time.Minute
+	// stopPollInterval the interval to check if the logger pods stopped.
+	stopPollInterval = 2 * time.Second
+)
+
+// ensureFinalizer adds the cleanup finalizer to the cr if missing.
+func (r *Reconciler) ensureFinalizer(ctx context.Context, cr eventloggerv1.Object) error {
+	if !controllerutil.AddFinalizer(cr, finalizerCleanup) {
+		return nil
+	}
+	return r.Update(ctx, cr)
+}
+
+// finalize stops the logger pods gracefully, so that they can flush their sinks, deletes all objects labelled as
+// managed for the cr and removes the finalizer. The deleted objects are logged as summary.
+func (r *Reconciler) finalize(
+	ctx context.Context,
+	cr eventloggerv1.Object,
+	reqLogger logr.Logger,
+) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(cr, finalizerCleanup) {
+		return reconcile.Result{}, nil
+	}
+
+	stopped, err := r.stopLogger(ctx, cr, reqLogger)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	waited := time.Since(cr.GetDeletionTimestamp().Time)
+	if !stopped {
+		if waited < gracefulStopTimeout {
+			return reconcile.Result{RequeueAfter: stopPollInterval}, nil
+		}
+		reqLogger.Info("Logger pods did not stop in time, deleting them", "timeout", gracefulStopTimeout)
+	}
+
+	deleted, err := r.deleteManagedObjects(ctx, cr)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	controllerutil.RemoveFinalizer(cr, finalizerCleanup)
+	if err := r.Update(ctx, cr); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	reqLogger.Info("Event logger deleted",
+		"gracefulStop", stopped,
+		"stopDuration", waited.Round(time.Second).String(),
+		"deletedObjects", len(deleted),
+		"objects", deleted,
+	)
+	return reconcile.Result{}, nil
+}
+
+// stopLogger scales the logger deployment down and deletes the legacy logger pods.
+// Returns true if no logger pod is left.
+func (r *Reconciler) stopLogger(ctx context.Context, cr eventloggerv1.Object, reqLogger logr.Logger) (bool, error) {
+	deploy := &appsv1.Deployment{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: r.loggerNamespace(cr), Name: loggerName(cr)}, deploy)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		return false, err
+	}
+	if err == nil && ptr.Deref(deploy.Spec.Replicas, 1) != 0 {
+		reqLogger.Info("Stopping logger pods", "namespace", deploy.Namespace, "name", deploy.Name)
+		r.Recorder.Eventf(cr, deploy, corev1.EventTypeNormal, "StoppingLogger", "Delete",
+			"Stopping the logger pods of deployment %s", deploy.Name)
+		deploy.Spec.Replicas = ptr.To(int32(0))
+		if err := r.Update(ctx, deploy); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := r.deleteLegacyPods(ctx, cr, reqLogger); err != nil {
+		return false, err
+	}
+
+	podList, err := r.findPods(ctx, cr, defaultLabels(cr))
+	if err != nil {
+		return false, err
+	}
+	return len(podList.Items) == 0, nil
+}
+
+// deleteManagedObjects deletes the objects labelled as managed for the cr, even if they are not owned by the cr
+// anymore. Returns the kind, namespace and name of the deleted objects.
+func (r *Reconciler) deleteManagedObjects(ctx context.Context, cr eventloggerv1.Object) ([]string, error) {
+	inLoggerNamespace := []client.ListOption{
+		client.InNamespace(r.loggerNamespace(cr)),
+		client.MatchingLabels(defaultLabels(cr)),
+	}
+	type selection struct {
+		list client.ObjectList
+		opts []client.ListOption
+	}
+	selections := []selection{
+		{list: &appsv1.DeploymentList{}, opts: inLoggerNamespace},
+		{list: &policyv1.PodDisruptionBudgetList{}, opts: inLoggerNamespace},
+		{list: &corev1.PodList{}, opts: inLoggerNamespace},
+		{list: &corev1.ConfigMapList{}, opts: inLoggerNamespace},
+		{list: &corev1.ServiceAccountList{}, opts: inLoggerNamespace},
+		{list: &rbacv1.RoleList{}, opts: inLoggerNamespace},
+		{list: &rbacv1.RoleBindingList{}, opts: inLoggerNamespace},
+	}
+	if r.Cluster {
+		clusterScoped := []client.ListOption{client.MatchingLabels(defaultLabels(cr))}
+		selections = append(selections,
+			selection{list: &rbacv1.ClusterRoleList{}, opts: clusterScoped},
+			selection{list: &rbacv1.ClusterRoleBindingList{}, opts: clusterScoped},
+		)
+	} else {
+		// the rbac in the watched namespaces
+		inWatchedNamespaces := client.MatchingLabels{labelNamespace: cr.GetNamespace()}
+		applyDefaultLabels(cr, inWatchedNamespaces)
+		selections = append(selections,
+			selection{list: &rbacv1.RoleList{}, opts: []client.ListOption{inWatchedNamespaces}},
+			selection{list: &rbacv1.RoleBindingList{}, opts: []client.ListOption{inWatchedNamespaces}},
+		)
+	}
+
+	var deleted []string
+	for _, s := range selections {
+		if err := r.List(ctx, s.list, s.opts...); err != nil {
+			return deleted, err
+		}
+		items, err := meta.ExtractList(s.list)
+		if err != nil {
+			return deleted, err
+		}
+		for _, item := range items {
+			obj := item.(client.Object)
+			if ns, ok := obj.GetLabels()[labelNamespace]; ok && ns != cr.GetNamespace() {
+				// the rbac of a logger with the same name in another namespace watching this namespace
+				continue
+			}
+			if err := r.saveDelete(ctx, obj); err != nil {
+				return deleted, err
+			}
+			deleted = append(deleted, objectName(obj))
+		}
+	}
+	return deleted, nil
+}
+
+// objectName returns the kind, namespace and name of the object.
+func objectName(obj client.Object) string {
+	kind := reflect.TypeOf(obj).Elem().Name()
+	if obj.GetNamespace() == "" {
+		return kind + " " + obj.GetName()
+	}
+	return kind + " " + obj.GetNamespace() + "/" + obj.GetName()
+}
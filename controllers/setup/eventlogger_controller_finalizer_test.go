@@ -0,0 +1,149 @@
+package setup
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Finalizer", func() {
+	var el *apiv1.EventLogger
+
+	BeforeEach(func() {
+		el = &apiv1.EventLogger{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "eventlogger",
+				Namespace: testNamespace,
+			},
+		}
+	})
+
+	deleting := func(since time.Duration) {
+		el.Finalizers = []string{finalizerCleanup}
+		el.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-since)}
+	}
+
+	labelled := func(obj client.Object, namespace string) client.Object {
+		obj.SetNamespace(namespace)
+		obj.SetLabels(defaultLabels(el))
+		return obj
+	}
+
+	It("should add the finalizer", func() {
+		cl, _ := testReconcile(el)
+
+		updated := &apiv1.EventLogger{}
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+		Ω(updated.Finalizers).Should(ContainElement(finalizerCleanup))
+	})
+
+	It("should scale the logger down and wait for the pods to stop", func() {
+		deleting(time.Second)
+		deploy := labelled(&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: loggerName(el)},
+			Spec:       appsv1.DeploymentSpec{Replicas: new(int32(1))},
+		}, testNamespace)
+		pod := newPod()
+		pod.Name = "logger"
+		pod.Labels = defaultLabels(el)
+		pod.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs", UID: "rs", Controller: new(true),
+		}}
+
+		cl, res := testReconcile(el, deploy, pod)
+		Ω(res.RequeueAfter).Should(Equal(stopPollInterval))
+
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(deploy), deploy)).ShouldNot(HaveOccurred())
+		Ω(*deploy.(*appsv1.Deployment).Spec.Replicas).Should(Equal(int32(0)))
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), &apiv1.EventLogger{})).ShouldNot(HaveOccurred())
+	})
+
+	It("should delete the managed objects and remove the finalizer", func() {
+		deleting(time.Second)
+		el.Spec.ServiceAccount = "custom"
+		objects := []client.Object{
+			el,
+			labelled(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: loggerName(el)},
+				Spec: appsv1.DeploymentSpec{Replicas: new(int32(0))}}, testNamespace),
+			labelled(&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: loggerName(el)}}, testNamespace),
+			// lost its owner reference
+			labelled(&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "event-logger-renamed"}}, testNamespace),
+			labelled(&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: loggerName(el)}}, testNamespace),
+			&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "custom", Namespace: testNamespace}},
+		}
+		watched := labelled(&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "watched"}}, "shop")
+		watched.GetLabels()[labelNamespace] = testNamespace
+		other := labelled(&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "other"}}, testNamespace)
+		other.GetLabels()[labelNamespace] = "other"
+		objects = append(objects, watched, other)
+
+		cl, res := testReconcile(objects...)
+		Ω(res.RequeueAfter).Should(Equal(time.Duration(0)))
+
+		err := cl.Get(context.TODO(), client.ObjectKeyFromObject(el), &apiv1.EventLogger{})
+		Ω(errors.IsNotFound(err)).Should(BeTrue())
+		for _, obj := range objects[1:5] {
+			err := cl.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj)
+			Ω(errors.IsNotFound(err)).Should(BeTrue(), objectName(obj))
+		}
+		err = cl.Get(context.TODO(), client.ObjectKeyFromObject(watched), watched)
+		Ω(errors.IsNotFound(err)).Should(BeTrue())
+
+		// not managed for the cr
+		Ω(cl.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: "custom"}, &corev1.ServiceAccount{})).
+			ShouldNot(HaveOccurred())
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(other), other)).ShouldNot(HaveOccurred())
+	})
+
+	It("should delete the pods that did not stop in time", func() {
+		deleting(gracefulStopTimeout + time.Second)
+		pod := newPod()
+		pod.Name = "logger"
+		pod.Labels = defaultLabels(el)
+		pod.Finalizers = []string{"test"}
+		pod.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs", UID: "rs", Controller: new(true),
+		}}
+
+		cl, res := testReconcile(el, pod)
+		Ω(res.RequeueAfter).Should(Equal(time.Duration(0)))
+
+		err := cl.Get(context.TODO(), client.ObjectKeyFromObject(el), &apiv1.EventLogger{})
+		Ω(errors.IsNotFound(err)).Should(BeTrue())
+	})
+
+	It("should delete the cluster rbac of a cluster event logger", func() {
+		cel := &apiv1.ClusterEventLogger{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "cluster",
+				Finalizers:        []string{finalizerCleanup},
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+		}
+		clusterRole, crb := clusterRbacForCR(cel)
+		clusterRole.Labels = defaultLabels(cel)
+		crb.Labels = defaultLabels(cel)
+
+		r := &Reconciler{Cluster: true, Namespace: testNamespace}
+		cl, _ := testReconcileWith(r, types.NamespacedName{Name: cel.Name}, cel, clusterRole, crb)
+
+		err := cl.Get(context.TODO(), client.ObjectKeyFromObject(clusterRole), clusterRole)
+		Ω(errors.IsNotFound(err)).Should(BeTrue())
+		err = cl.Get(context.TODO(), client.ObjectKeyFromObject(crb), crb)
+		Ω(errors.IsNotFound(err)).Should(BeTrue())
+		err = cl.Get(context.TODO(), client.ObjectKeyFromObject(cel), &apiv1.ClusterEventLogger{})
+		Ω(errors.IsNotFound(err)).Should(BeTrue())
+	})
+})
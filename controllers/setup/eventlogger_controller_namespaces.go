@@ -0,0 +1,222 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package setup
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+)
+
+// labelNamespace the namespace of the EventLogger the role and role binding in a watched namespace belong to.
+// Owner references across namespaces are not possible, these objects are cleaned up by label.
+const labelNamespace = "eventlogger.bakito.ch/namespace"
+
+var errNoNamespaces = errors.New("no existing namespace matches the namespaces and namespace selector")
+
+// watchNamespaces returns the namespaces the logger pod watches as comma separated list. An empty string watches
+// all namespaces.
+func (r *Reconciler) watchNamespaces(ctx context.Context, cr eventloggerv1.Object) (string, error) {
+	if !cr.GetSpec().WatchesNamespaces() {
+		if cr.GetSpec().Namespace != nil {
+			return *cr.GetSpec().Namespace, nil
+		}
+		return cr.GetNamespace(), nil
+	}
+	namespaces, err := r.selectedNamespaces(ctx, cr)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(namespaces, ","), nil
+}
+
+// selectedNamespaces returns the sorted names of the existing namespaces listed in Namespaces or matching
+// the NamespaceSelector.
+func (r *Reconciler) selectedNamespaces(ctx context.Context, cr eventloggerv1.Object) ([]string, error) {
+	selector := labels.Nothing()
+	if cr.GetSpec().NamespaceSelector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(cr.GetSpec().NamespaceSelector); err != nil {
+			return nil, err
+		}
+	}
+
+	nsList := &corev1.NamespaceList{}
+	if err := r.List(ctx, nsList); err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	for _, ns := range nsList.Items {
+		if slices.Contains(cr.GetSpec().Namespaces, ns.Name) || selector.Matches(labels.Set(ns.Labels)) {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+	if len(namespaces) == 0 {
+		return nil, errNoNamespaces
+	}
+	slices.Sort(namespaces)
+	return namespaces, nil
+}
+
+// setupNamespaceRbac creates a role and role binding for the logger service account in each watched namespace
+// other than the namespace of the cr, and deletes them in the namespaces that are no longer watched. A custom
+// service account is granted the events of the watched namespaces too, as the selected namespaces change with
+// their labels.
+func (r *Reconciler) setupNamespaceRbac(
+	ctx context.Context,
+	cr eventloggerv1.Object,
+	watchNamespaces string,
+) (bool, error) {
+	var namespaces []string
+	if cr.GetSpec().WatchesNamespaces() {
+		for ns := range strings.SplitSeq(watchNamespaces, ",") {
+			if ns != cr.GetNamespace() {
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+
+	changed := false
+	for _, ns := range namespaces {
+		role, rb := namespaceRbacForCR(cr, ns)
+
+		roleRes, err := controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+			role.Labels = namespaceLabels(cr)
+			role.Rules = r.eventRules(cr)
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+
+		rbRes, err := controllerutil.CreateOrUpdate(ctx, r.Client, rb, func() error {
+			rb.Labels = namespaceLabels(cr)
+			rb.Subjects = []rbacv1.Subject{
+				{
+					Kind:      "ServiceAccount",
+					Name:      serviceAccountName(cr),
+					Namespace: cr.GetNamespace(),
+				},
+			}
+			rb.RoleRef = rbacv1.RoleRef{
+				Kind:     "Role",
+				APIGroup: "rbac.authorization.k8s.io",
+				Name:     role.Name,
+			}
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+		changed = changed || roleRes != controllerutil.OperationResultNone || rbRes != controllerutil.OperationResultNone
+	}
+
+	deleted, err := r.cleanupNamespaceRbac(ctx, cr, namespaces)
+	return changed || deleted, err
+}
+
+// cleanupNamespaceRbac deletes the roles and role bindings of the cr in all namespaces not to keep.
+func (r *Reconciler) cleanupNamespaceRbac(ctx context.Context, cr eventloggerv1.Object, keep []string) (bool, error) {
+	matchLabels := client.MatchingLabels{labelNamespace: cr.GetNamespace()}
+	applyDefaultLabels(cr, matchLabels)
+	opts := []client.ListOption{matchLabels}
+
+	roles := &rbacv1.RoleList{}
+	if err := r.List(ctx, roles, opts...); err != nil {
+		return false, err
+	}
+	rbs := &rbacv1.RoleBindingList{}
+	if err := r.List(ctx, rbs, opts...); err != nil {
+		return false, err
+	}
+
+	var objects []client.Object
+	for i := range roles.Items {
+		objects = append(objects, &roles.Items[i])
+	}
+	for i := range rbs.Items {
+		objects = append(objects, &rbs.Items[i])
+	}
+
+	deleted := false
+	for _, obj := range objects {
+		if slices.Contains(keep, obj.GetNamespace()) {
+			continue
+		}
+		r.Log.Info("Deleting rbac of namespace no longer watched", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		if err := r.saveDelete(ctx, obj); err != nil {
+			return false, err
+		}
+		deleted = true
+	}
+	return deleted, nil
+}
+
+// loggersForNamespace returns the EventLoggers that select namespaces, to reconcile them when a namespace changes.
+func (r *Reconciler) loggersForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &eventloggerv1.EventLoggerList{}
+	if err := r.List(ctx, list); err != nil {
+		r.Log.Error(err, "could not list event loggers")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, el := range list.Items {
+		if el.Spec.NamespaceSelector != nil || slices.Contains(el.Spec.Namespaces, obj.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: el.Namespace, Name: el.Name},
+			})
+		}
+	}
+	return requests
+}
+
+func namespaceRbacForCR(cr eventloggerv1.Object, namespace string) (*rbacv1.Role, *rbacv1.RoleBinding) {
+	// the name contains the namespace of the cr, to be unique across EventLoggers with the same name
+	name := "event-logger-" + cr.GetNamespace() + "-" + cr.GetName()
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	rb := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	return role, rb
+}
+
+func namespaceLabels(cr eventloggerv1.Object) map[string]string {
+	labels := copyLabels(cr)
+	labels[labelNamespace] = cr.GetNamespace()
+	return labels
+}
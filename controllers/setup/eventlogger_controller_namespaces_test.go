@@ -0,0 +1,163 @@
+package setup
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	c "github.com/bakito/k8s-event-logger-operator/pkg/constants"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Namespaces", func() {
+	var (
+		el         *apiv1.EventLogger
+		namespaces []client.Object
+	)
+
+	BeforeEach(func() {
+		el = &apiv1.EventLogger{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "eventlogger",
+				Namespace: testNamespace,
+			},
+			Spec: apiv1.EventLoggerSpec{
+				Namespaces: []string{"shop", "missing"},
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"team": "payments"},
+				},
+			},
+		}
+		namespaces = []client.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNamespace}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "payments-b",
+				Labels: map[string]string{"team": "payments"},
+			}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "payments-a",
+				Labels: map[string]string{"team": "payments"},
+			}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "checkout",
+				Labels: map[string]string{"team": "checkout"},
+			}},
+		}
+	})
+
+	It("should watch the listed and selected namespaces", func() {
+		cl, _ := testReconcile(append(namespaces, el)...)
+
+		deploy := loggerDeployment(cl, el)
+		Ω(podEnv(&deploy.Spec.Template.Spec, c.EnvWatchNamespace)).Should(Equal("payments-a,payments-b,shop"))
+	})
+
+	It("should create the rbac in the watched namespaces", func() {
+		el.Spec.EventAPI = apiv1.EventAPIEventsV1
+		cl, _ := testReconcile(append(namespaces, el)...)
+
+		roles := &rbacv1.RoleList{}
+		assertEntrySize(cl, el, roles, 4)
+		rbs := &rbacv1.RoleBindingList{}
+		assertEntrySize(cl, el, rbs, 4)
+
+		role := &rbacv1.Role{}
+		Ω(cl.Get(context.TODO(), client.ObjectKey{
+			Namespace: "shop",
+			Name:      "event-logger-" + testNamespace + "-eventlogger",
+		}, role)).ShouldNot(HaveOccurred())
+		Ω(role.Labels).Should(HaveKeyWithValue(labelNamespace, testNamespace))
+		Ω(role.OwnerReferences).Should(BeEmpty())
+		Ω(role.Rules).Should(HaveLen(2))
+		Ω(role.Rules[0].Resources).Should(Equal([]string{"events"}))
+		Ω(role.Rules[1].APIGroups).Should(Equal([]string{"events.k8s.io"}))
+
+		rb := &rbacv1.RoleBinding{}
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(role), rb)).ShouldNot(HaveOccurred())
+		Ω(rb.RoleRef.Name).Should(Equal(role.Name))
+		Ω(rb.Subjects).Should(HaveLen(1))
+		Ω(rb.Subjects[0].Name).Should(Equal(loggerName(el)))
+		Ω(rb.Subjects[0].Namespace).Should(Equal(testNamespace))
+	})
+
+	It("should bind the custom service account in the watched namespaces", func() {
+		el.Spec.ServiceAccount = "custom"
+		cl, _ := testReconcile(append(namespaces, el)...)
+
+		rbs := &rbacv1.RoleBindingList{}
+		assertEntrySize(cl, el, rbs, 3)
+		for _, rb := range rbs.Items {
+			Ω(rb.Namespace).ShouldNot(Equal(testNamespace))
+			Ω(rb.Subjects).Should(HaveLen(1))
+			Ω(rb.Subjects[0].Name).Should(Equal("custom"))
+			Ω(rb.Subjects[0].Namespace).Should(Equal(testNamespace))
+		}
+	})
+
+	It("should delete the rbac of namespaces no longer watched", func() {
+		role, rb := namespaceRbacForCR(el, "checkout")
+		role.Labels = namespaceLabels(el)
+		rb.Labels = namespaceLabels(el)
+		cl, _ := testReconcile(append(namespaces, el, role, rb)...)
+
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(role), &rbacv1.Role{})).Should(HaveOccurred())
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(rb), &rbacv1.RoleBinding{})).Should(HaveOccurred())
+	})
+
+	It("should delete the rbac of the watched namespaces if the cr was deleted", func() {
+		role, rb := namespaceRbacForCR(el, "shop")
+		role.Labels = namespaceLabels(el)
+		rb.Labels = namespaceLabels(el)
+		cl, _ := testReconcile(append(namespaces, role, rb)...)
+
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(role), &rbacv1.Role{})).Should(HaveOccurred())
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(rb), &rbacv1.RoleBinding{})).Should(HaveOccurred())
+	})
+
+	It("should not be ready if no namespace is selected", func() {
+		el.Spec.Namespaces = []string{"missing"}
+		el.Spec.NamespaceSelector = nil
+		cl, _ := testReconcile(append(namespaces, el)...)
+
+		updated := &apiv1.EventLogger{}
+		Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+		cond := meta.FindStatusCondition(updated.Status.Conditions, apiv1.ConditionRBACReady)
+		Ω(cond).ShouldNot(BeNil())
+		Ω(cond.Status).Should(Equal(metav1.ConditionFalse))
+		Ω(cond.Reason).Should(Equal("NamespacesNotSelected"))
+		Ω(updated.Status.Error).Should(Equal(errNoNamespaces.Error()))
+	})
+
+	It("should reconcile the event loggers selecting a namespace", func() {
+		other := &apiv1.EventLogger{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testNamespace},
+			Spec:       apiv1.EventLoggerSpec{Namespaces: []string{"checkout"}},
+		}
+		legacy := &apiv1.EventLogger{ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: testNamespace}}
+		s := scheme.Scheme
+		Ω(apiv1.SchemeBuilder.AddToScheme(s)).ShouldNot(HaveOccurred())
+		r := &Reconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(el, other, legacy).Build(),
+			Log:    ctrl.Log.WithName("controllers").WithName("Pod"),
+			Scheme: s,
+		}
+
+		requests := r.loggersForNamespace(context.TODO(), namespaces[1])
+		Ω(requests).Should(HaveLen(1))
+		Ω(requests[0].Name).Should(Equal(el.Name))
+
+		requests = r.loggersForNamespace(context.TODO(), namespaces[4])
+		Ω(requests).Should(HaveLen(2))
+	})
+})
@@ -17,11 +17,18 @@ limitations under the License.
 package setup
 
 import (
+	"cmp"
 	"context"
+	"slices"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -30,11 +37,11 @@ import (
 
 func (r *Reconciler) setupRbac(
 	ctx context.Context,
-	cr *eventloggerv1.EventLogger,
+	cr eventloggerv1.Object,
 ) (saccChanged, roleChanged, rbChanged bool, err error) {
-	sacc, role, rb := rbacForCR(cr)
+	sacc, role, rb := r.rbacForCR(cr)
 
-	if cr.Spec.ServiceAccount == "" {
+	if cr.GetSpec().ServiceAccount == "" {
 		saccRes, err := controllerutil.CreateOrUpdate(ctx, r.Client, sacc, r.mutateServiceAccount(sacc, cr))
 		if err != nil {
 			return false, false, false, err
@@ -49,11 +56,29 @@ func (r *Reconciler) setupRbac(
 		if err != nil {
 			return false, false, false, err
 		}
-		return saccRes != controllerutil.OperationResultNone, roleRes != controllerutil.OperationResultNone, rbRes != controllerutil.OperationResultNone, nil
+		saccChanged = saccRes != controllerutil.OperationResultNone
+		roleChanged = roleRes != controllerutil.OperationResultNone
+		rbChanged = rbRes != controllerutil.OperationResultNone
+
+		if r.Cluster {
+			// cluster event loggers watch the events of all namespaces
+			clusterRole, crb := clusterRbacForCR(cr)
+			crRes, err := controllerutil.CreateOrUpdate(ctx, r.Client, clusterRole, r.mutateClusterRole(clusterRole, cr))
+			if err != nil {
+				return false, false, false, err
+			}
+			crbRes, err := controllerutil.CreateOrUpdate(ctx, r.Client, crb, r.mutateClusterRoleBinding(crb, cr))
+			if err != nil {
+				return false, false, false, err
+			}
+			roleChanged = roleChanged || crRes != controllerutil.OperationResultNone
+			rbChanged = rbChanged || crbRes != controllerutil.OperationResultNone
+		}
+		return saccChanged, roleChanged, rbChanged, nil
 	}
 
 	// Only delete sa if the name is different from the configured
-	if cr.Spec.ServiceAccount != sacc.GetName() {
+	if cr.GetSpec().ServiceAccount != sacc.GetName() {
 		err = r.saveDelete(ctx, sacc)
 		if err != nil {
 			return false, false, false, err
@@ -67,46 +92,240 @@ func (r *Reconciler) setupRbac(
 	if err != nil {
 		return false, false, false, err
 	}
+	if r.Cluster {
+		clusterRole, crb := clusterRbacForCR(cr)
+		if err = r.saveDelete(ctx, clusterRole); err != nil {
+			return false, false, false, err
+		}
+		if err = r.saveDelete(ctx, crb); err != nil {
+			return false, false, false, err
+		}
+	}
 	return false, false, false, nil
 }
 
-func (r *Reconciler) mutateServiceAccount(sacc *corev1.ServiceAccount, cr *eventloggerv1.EventLogger) func() error {
+func (r *Reconciler) mutateServiceAccount(sacc *corev1.ServiceAccount, cr eventloggerv1.Object) func() error {
 	return func() error {
 		sacc.Labels = copyLabels(cr)
 		return ctrl.SetControllerReference(cr, sacc, r.Scheme)
 	}
 }
 
-func (r *Reconciler) mutateRole(role *rbacv1.Role, cr *eventloggerv1.EventLogger) func() error {
+func (r *Reconciler) mutateRole(role *rbacv1.Role, cr eventloggerv1.Object) func() error {
 	return func() error {
 		role.Labels = copyLabels(cr)
-		role.Rules = []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{""},
-				Resources: []string{"events", "pods"},
+		if r.Cluster {
+			// the events and the cr are granted by the cluster role
+			role.Rules = []rbacv1.PolicyRule{
+				{
+					APIGroups: []string{""},
+					Resources: []string{"pods"},
+					Verbs:     []string{"watch", "get", "list"},
+				},
+			}
+		} else {
+			role.Rules = []rbacv1.PolicyRule{
+				{
+					APIGroups: []string{""},
+					Resources: []string{"events", "pods"},
+					Verbs:     []string{"watch", "get", "list"},
+				},
+				{
+					APIGroups: []string{"eventlogger.bakito.ch"},
+					Resources: []string{"eventloggers"},
+					Verbs:     []string{"get", "list", "patch", "update", "watch"},
+				},
+				{
+					APIGroups: []string{"eventlogger.bakito.ch"},
+					Resources: []string{"eventloggers/status"},
+					Verbs:     []string{"get", "patch", "update"},
+				},
+			}
+			role.Rules = append(role.Rules, eventsV1Rules(cr)...)
+			role.Rules = append(role.Rules, r.involvedObjectRules(cr)...)
+		}
+		if secrets := cr.GetSpec().SecretNames(); len(secrets) > 0 {
+			role.Rules = append(role.Rules, rbacv1.PolicyRule{
+				APIGroups:     []string{""},
+				Resources:     []string{"secrets"},
+				ResourceNames: secrets,
+				Verbs:         []string{"get"},
+			})
+		}
+		if cr.GetSpec().Checkpoint != nil {
+			role.Rules = append(role.Rules, rbacv1.PolicyRule{
+				APIGroups:     []string{""},
+				Resources:     []string{"configmaps"},
+				ResourceNames: []string{checkpointName(cr)},
+				Verbs:         []string{"get", "patch", "update"},
+			})
+		}
+		if replicas(cr) > 1 {
+			// leader election among the logger pods
+			role.Rules = append(role.Rules, rbacv1.PolicyRule{
+				APIGroups: []string{"coordination.k8s.io"},
+				Resources: []string{"leases"},
+				Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+			})
+		}
+		return ctrl.SetControllerReference(cr, role, r.Scheme)
+	}
+}
+
+// eventRules returns the rules to watch the events and read the involved objects in a watched namespace.
+func (r *Reconciler) eventRules(cr eventloggerv1.Object) []rbacv1.PolicyRule {
+	rules := []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"events"},
+			Verbs:     []string{"watch", "get", "list"},
+		},
+	}
+	rules = append(rules, eventsV1Rules(cr)...)
+	return append(rules, r.involvedObjectRules(cr)...)
+}
+
+// eventsV1Rules returns the rules to watch the events.k8s.io events if the logger watches them.
+func eventsV1Rules(cr eventloggerv1.Object) []rbacv1.PolicyRule {
+	if eventAPI(cr) != eventloggerv1.EventAPIEventsV1 {
+		return nil
+	}
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{"events.k8s.io"},
+			Resources: []string{"events"},
+			Verbs:     []string{"watch", "get", "list"},
+		},
+	}
+}
+
+// involvedObjectRules returns the rules to read the involved objects of the kinds with a label selector.
+// Kinds without api group are granted in the api groups serving them.
+func (r *Reconciler) involvedObjectRules(cr eventloggerv1.Object) []rbacv1.PolicyRule {
+	var rules []rbacv1.PolicyRule
+	for _, k := range cr.GetSpec().Kinds {
+		if k.LabelSelector == nil {
+			continue
+		}
+		var resources []schema.GroupResource
+		if k.APIGroup != nil {
+			resource, _ := meta.UnsafeGuessKindToResource(schema.GroupVersionKind{Group: *k.APIGroup, Kind: k.Name})
+			resources = append(resources, resource.GroupResource())
+		} else {
+			resources = r.servedResources(k.Name)
+		}
+		for _, gr := range resources {
+			rule := rbacv1.PolicyRule{
+				APIGroups: []string{gr.Group},
+				Resources: []string{gr.Resource},
 				Verbs:     []string{"watch", "get", "list"},
-			},
-			{
+			}
+			if !slices.ContainsFunc(rules, func(r rbacv1.PolicyRule) bool { return equality.Semantic.DeepEqual(r, rule) }) {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+// servedResources returns the resources serving the kind in any api group, none if there is no discovery. The cached
+// discovery is refreshed once if the kind is not served, e.g. if the crd of the kind was installed in the meantime.
+func (r *Reconciler) servedResources(kind string) []schema.GroupResource {
+	if r.Discovery == nil {
+		return nil
+	}
+	resources := resourcesOfKind(r.Discovery, kind)
+	if len(resources) == 0 {
+		r.Discovery.Invalidate()
+		resources = resourcesOfKind(r.Discovery, kind)
+	}
+	return resources
+}
+
+// resourcesOfKind returns the resources serving the kind sorted by group, the discovery returns the groups in random
+// order.
+func resourcesOfKind(d discovery.DiscoveryInterface, kind string) []schema.GroupResource {
+	// the resources of the other groups are returned if the discovery of a group failed
+	lists, _ := d.ServerPreferredResources()
+	var resources []schema.GroupResource
+	for _, l := range lists {
+		gv, err := schema.ParseGroupVersion(l.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, res := range l.APIResources {
+			gr := gv.WithResource(res.Name).GroupResource()
+			if res.Kind == kind && !strings.Contains(res.Name, "/") && !slices.Contains(resources, gr) {
+				resources = append(resources, gr)
+			}
+		}
+	}
+	slices.SortFunc(resources, func(a, b schema.GroupResource) int {
+		return cmp.Or(strings.Compare(a.Group, b.Group), strings.Compare(a.Resource, b.Resource))
+	})
+	return resources
+}
+
+func (r *Reconciler) mutateClusterRole(clusterRole *rbacv1.ClusterRole, cr eventloggerv1.Object) func() error {
+	return func() error {
+		clusterRole.Labels = copyLabels(cr)
+		clusterRole.Rules = r.eventRules(cr)
+		// the cr and its status can only be written for the own cr, list and watch are not restricted by resource name
+		clusterRole.Rules = append(clusterRole.Rules,
+			rbacv1.PolicyRule{
 				APIGroups: []string{"eventlogger.bakito.ch"},
-				Resources: []string{"eventloggers"},
-				Verbs:     []string{"get", "list", "patch", "update", "watch"},
+				Resources: []string{"clustereventloggers"},
+				Verbs:     []string{"list", "watch"},
+			},
+			rbacv1.PolicyRule{
+				APIGroups:     []string{"eventlogger.bakito.ch"},
+				Resources:     []string{"clustereventloggers", "clustereventloggers/status"},
+				ResourceNames: []string{cr.GetName()},
+				Verbs:         []string{"get", "patch", "update"},
 			},
+		)
+		return ctrl.SetControllerReference(cr, clusterRole, r.Scheme)
+	}
+}
+
+func (r *Reconciler) mutateClusterRoleBinding(crb *rbacv1.ClusterRoleBinding, cr eventloggerv1.Object) func() error {
+	return func() error {
+		crb.Labels = copyLabels(cr)
+		crb.Subjects = r.loggerSubjects(cr)
+		crb.RoleRef = rbacv1.RoleRef{
+			Kind:     "ClusterRole",
+			APIGroup: "rbac.authorization.k8s.io",
+			Name:     loggerName(cr),
 		}
-		return ctrl.SetControllerReference(cr, role, r.Scheme)
+		return ctrl.SetControllerReference(cr, crb, r.Scheme)
+	}
+}
+
+// serviceAccountName returns the name of the service account of the logger pod, the custom service account if
+// defined.
+func serviceAccountName(cr eventloggerv1.Object) string {
+	if cr.GetSpec().ServiceAccount != "" {
+		return cr.GetSpec().ServiceAccount
 	}
+	return loggerName(cr)
 }
 
-func (r *Reconciler) mutateRoleBinding(rb *rbacv1.RoleBinding, cr *eventloggerv1.EventLogger) func() error {
+// loggerSubjects returns the service account of the logger pod as rbac subject.
+func (r *Reconciler) loggerSubjects(cr eventloggerv1.Object) []rbacv1.Subject {
+	return []rbacv1.Subject{
+		{
+			Kind:      "ServiceAccount",
+			Name:      loggerName(cr),
+			Namespace: r.loggerNamespace(cr),
+		},
+	}
+}
+
+func (r *Reconciler) mutateRoleBinding(rb *rbacv1.RoleBinding, cr eventloggerv1.Object) func() error {
 	return func() error {
 		rb.Labels = copyLabels(cr)
 
-		rb.Subjects = []rbacv1.Subject{
-			{
-				Kind:      "ServiceAccount",
-				Name:      loggerName(cr),
-				Namespace: cr.Namespace,
-			},
-		}
+		rb.Subjects = r.loggerSubjects(cr)
 		rb.RoleRef = rbacv1.RoleRef{
 			Kind:     "Role",
 			APIGroup: "rbac.authorization.k8s.io",
@@ -116,26 +335,40 @@ func (r *Reconciler) mutateRoleBinding(rb *rbacv1.RoleBinding, cr *eventloggerv1
 	}
 }
 
-func rbacForCR(cr *eventloggerv1.EventLogger) (*corev1.ServiceAccount, *rbacv1.Role, *rbacv1.RoleBinding) {
+func (r *Reconciler) rbacForCR(cr eventloggerv1.Object) (*corev1.ServiceAccount, *rbacv1.Role, *rbacv1.RoleBinding) {
 	sacc := &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      loggerName(cr),
-			Namespace: cr.Namespace,
+			Namespace: r.loggerNamespace(cr),
 		},
 	}
 
 	role := &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      loggerName(cr),
-			Namespace: cr.Namespace,
+			Namespace: r.loggerNamespace(cr),
 		},
 	}
 	rb := &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      loggerName(cr),
-			Namespace: cr.Namespace,
+			Namespace: r.loggerNamespace(cr),
 		},
 	}
 
 	return sacc, role, rb
 }
+
+func clusterRbacForCR(cr eventloggerv1.Object) (*rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding) {
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: loggerName(cr),
+		},
+	}
+	crb := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: loggerName(cr),
+		},
+	}
+	return clusterRole, crb
+}
@@ -2,17 +2,25 @@ package setup
 
 import (
 	"context"
+	"flag"
 	"reflect"
 	"time"
 
 	"github.com/google/uuid"
 	gm "go.uber.org/mock/gomock"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -66,6 +74,55 @@ var _ = Describe("Logging", func() {
 		defer mockCtrl.Finish()
 	})
 
+	Context("deploymentCondition", func() {
+		var deploy *appsv1.Deployment
+		BeforeEach(func() {
+			deploy = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "logger", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: new(int32(2))},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					UpdatedReplicas:    2,
+					AvailableReplicas:  2,
+				},
+			}
+		})
+		It("should be false if rolled out", func() {
+			status, reason, message := deploymentCondition(deploy, &apiv1.Rollout{
+				Reason:        apiv1.RolloutReasonPodTemplateChanged,
+				ChangedFields: []string{"nodeSelector", "tolerations"},
+			})
+			Ω(status).Should(Equal(metav1.ConditionFalse))
+			Ω(reason).Should(Equal("RollingOut"))
+			Ω(message).Should(HaveSuffix("are rolled out, changed fields: nodeSelector, tolerations"))
+		})
+		It("should be false if not observed", func() {
+			deploy.Status.ObservedGeneration = 1
+			status, reason, _ := deploymentCondition(deploy, nil)
+			Ω(status).Should(Equal(metav1.ConditionFalse))
+			Ω(reason).Should(Equal("Progressing"))
+		})
+		It("should be false if not all replicas are updated", func() {
+			deploy.Status.UpdatedReplicas = 1
+			status, reason, _ := deploymentCondition(deploy, nil)
+			Ω(status).Should(Equal(metav1.ConditionFalse))
+			Ω(reason).Should(Equal("Progressing"))
+		})
+		It("should be false if no pod is available", func() {
+			deploy.Status.AvailableReplicas = 0
+			status, reason, _ := deploymentCondition(deploy, nil)
+			Ω(status).Should(Equal(metav1.ConditionFalse))
+			Ω(reason).Should(Equal("Unavailable"))
+		})
+		It("should be true if available", func() {
+			deploy.Status.AvailableReplicas = 1
+			status, reason, message := deploymentCondition(deploy, nil)
+			Ω(status).Should(Equal(metav1.ConditionTrue))
+			Ω(reason).Should(Equal("Available"))
+			Ω(message).Should(Equal("1/2 logger pods of deployment logger are available"))
+		})
+	})
+
 	Context("Reconcile", func() {
 		Context("EventLogger", func() {
 			It("update the eventlogger", func() {
@@ -83,31 +140,129 @@ var _ = Describe("Logging", func() {
 				Ω(updated.Status.Hash).ShouldNot(BeEmpty())
 				Ω(updated.Status.OperatorVersion).Should(Equal(version.Version))
 			})
+			It("should set the status conditions", func() {
+				el.Generation = 2
+				cl, _ := testReconcile(el)
+
+				updated := &apiv1.EventLogger{}
+				err := cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(updated.Status.ObservedGeneration).Should(Equal(int64(2)))
+				Ω(updated.Status.LoggerPod).Should(BeEmpty())
+				Ω(meta.IsStatusConditionTrue(updated.Status.Conditions, apiv1.ConditionConfigValid)).Should(BeTrue())
+				Ω(meta.IsStatusConditionTrue(updated.Status.Conditions, apiv1.ConditionRBACReady)).Should(BeTrue())
+				Ω(meta.IsStatusConditionFalse(updated.Status.Conditions, apiv1.ConditionPodRunning)).Should(BeTrue())
+				Ω(meta.FindStatusCondition(updated.Status.Conditions, apiv1.ConditionFilterApplied).Status).
+					Should(Equal(metav1.ConditionUnknown))
+				Ω(meta.IsStatusConditionFalse(updated.Status.Conditions, apiv1.ConditionReady)).Should(BeTrue())
+			})
+			It("should set config valid to false", func() {
+				el.Spec.Labels = map[string]string{"in valid": "foo"}
+				cl, _ := testReconcile(el)
+
+				updated := &apiv1.EventLogger{}
+				err := cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(updated.Status.Error).ShouldNot(BeEmpty())
+				Ω(meta.IsStatusConditionFalse(updated.Status.Conditions, apiv1.ConditionConfigValid)).Should(BeTrue())
+				Ω(meta.IsStatusConditionFalse(updated.Status.Conditions, apiv1.ConditionReady)).Should(BeTrue())
+			})
+		})
+		Context("Events", func() {
+			var (
+				recorder *events.FakeRecorder
+				r        *Reconciler
+			)
+			BeforeEach(func() {
+				recorder = events.NewFakeRecorder(10)
+				r = &Reconciler{Recorder: recorder}
+			})
+			recorded := func() []string {
+				var list []string
+				for len(recorder.Events) > 0 {
+					list = append(list, <-recorder.Events)
+				}
+				return list
+			}
+
+			It("should record the creation of the logger", func() {
+				testReconcileWith(r, client.ObjectKeyFromObject(el), el)
+
+				Ω(recorded()).Should(ConsistOf(
+					"Normal RBACUpdated Updated the rbac of the logger, watched namespaces: "+ns2,
+					"Normal LoggerCreated Created logger deployment "+loggerName(el),
+					"Normal ConfigApplied Applied the config of generation 0",
+				))
+			})
+
+			It("should record the replacement of the logger pods", func() {
+				cl, _ := testReconcile(el)
+				deploy := loggerDeployment(cl, el)
+				updated := &apiv1.EventLogger{}
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+
+				updated.Spec.NodeSelector = map[string]string{"ns-key": "other"}
+				updated.ResourceVersion = ""
+				testReconcileWith(r, client.ObjectKeyFromObject(el), updated, deploy)
+
+				Ω(recorded()).Should(ContainElement(
+					"Normal LoggerReplaced Replacing the logger pods of deployment " + loggerName(el) +
+						", changed fields: nodeSelector",
+				))
+			})
+
+			It("should record validation failures once", func() {
+				el.Spec.Labels = map[string]string{"in valid": "foo"}
+				cl, _ := testReconcileWith(r, client.ObjectKeyFromObject(el), el)
+
+				list := recorded()
+				Ω(list).Should(HaveLen(1))
+				Ω(list[0]).Should(HavePrefix("Warning ValidationFailed "))
+
+				updated := &apiv1.EventLogger{}
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+				updated.ResourceVersion = ""
+				testReconcileWith(r, client.ObjectKeyFromObject(el), updated)
+				Ω(recorded()).Should(BeEmpty())
+			})
 		})
-		Context("Pod", func() {
-			It("create a correct pod", func() {
+
+		Context("Deployment", func() {
+			It("create a correct deployment", func() {
 				cl, res := testReconcile(el)
 				Ω(res.RequeueAfter).Should(Equal(time.Duration(0)))
 
-				// check created pod
-				pods := &corev1.PodList{}
-				assertEntrySize(cl, el, pods, 1)
-				pod := pods.Items[0]
-
+				// check created deployment
+				deploy := loggerDeployment(cl, el)
+				Ω(deploy.Name).Should(Equal(loggerName(el)))
+				Ω(deploy.Namespace).Should(Equal(el.GetNamespace()))
+				Ω(deploy.OwnerReferences).Should(HaveLen(1))
+				Ω(deploy.Annotations).Should(HaveKey(annotationPodTemplateHash))
+				Ω(*deploy.Spec.Replicas).Should(Equal(int32(1)))
+				Ω(deploy.Spec.Strategy.Type).Should(Equal(appsv1.RecreateDeploymentStrategyType))
+				Ω(deploy.Spec.Selector.MatchLabels).Should(Equal(map[string]string{
+					labelComponent: loggerName(el),
+					labelManagedBy: "eventlogger",
+				}))
+
+				pod := deploy.Spec.Template
 				Ω(pod.ObjectMeta.Labels).Should(HaveKey(labelComponent))
 				Ω(pod.ObjectMeta.Labels).Should(HaveKey(labelManagedBy))
 				Ω(pod.ObjectMeta.Labels["test-label"]).Should(Equal("foo"))
 				Ω(pod.ObjectMeta.Annotations["test-annotation"]).Should(Equal("bar"))
 				Ω(pod.ObjectMeta.Annotations["prometheus.io/port"]).Should(Equal(c.DefaultMetricsAddr[:1]))
 				Ω(pod.ObjectMeta.Annotations["prometheus.io/scrape"]).Should(Equal("true"))
-				Ω(pod.ObjectMeta.Namespace).Should(Equal(el.GetNamespace()))
-				Ω(pod.ObjectMeta.OwnerReferences).Should(HaveLen(1))
 
 				Ω(pod.Spec.NodeSelector).Should(HaveLen(1))
 				Ω(pod.Spec.NodeSelector["ns-key"]).Should(Equal("ns-value"))
 
 				Ω(pod.Spec.Containers).Should(HaveLen(1))
 				container := pod.Spec.Containers[0]
+				Ω(parseLoggerArgs(container.Args)).Should(Equal(loggerFlags{
+					configName:  el.Name,
+					metricsAddr: c.DefaultMetricsAddr,
+					loggerMode:  true,
+				}))
 				Ω(*container.Resources.Requests.Cpu()).Should(Equal(resource.MustParse("111m")))
 				Ω(*container.Resources.Requests.Memory()).Should(Equal(resource.MustParse("222Mi")))
 				Ω(*container.Resources.Limits.Cpu()).Should(Equal(resource.MustParse("333m")))
@@ -118,18 +273,249 @@ var _ = Describe("Logging", func() {
 					evars[e.Name] = e
 				}
 				Ω(evars[c.EnvWatchNamespace].Value).Should(Equal(ns2))
+				Ω(evars[c.EnvEventAPI].Value).Should(Equal(string(apiv1.EventAPICoreV1)))
+
+				assertEntrySize(cl, el, &policyv1.PodDisruptionBudgetList{}, 0)
+			})
+
+			It("should roll out the pod template if the event api changes", func() {
+				cl, _ := testReconcile(el)
+				deploy := loggerDeployment(cl, el)
+				hash := deploy.Annotations[annotationPodTemplateHash]
+
+				el.Spec.EventAPI = apiv1.EventAPIEventsV1
+				cl, _ = testReconcile(el, deploy)
+
+				deploy = loggerDeployment(cl, el)
+				Ω(deploy.Annotations[annotationPodTemplateHash]).ShouldNot(Equal(hash))
+				Ω(podEnv(&deploy.Spec.Template.Spec, c.EnvEventAPI)).Should(Equal(string(apiv1.EventAPIEventsV1)))
+
+				updated := &apiv1.EventLogger{}
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+				cond := meta.FindStatusCondition(updated.Status.Conditions, apiv1.ConditionPodRunning)
+				Ω(cond.Reason).Should(Equal("RollingOut"))
+				Ω(updated.Status.LastRollout).ShouldNot(BeNil())
+				Ω(updated.Status.LastRollout.Reason).Should(Equal(apiv1.RolloutReasonPodTemplateChanged))
+				Ω(updated.Status.LastRollout.ChangedFields).Should(Equal([]string{"containers[event-logger].env"}))
+				Ω(updated.Status.LastRollout.PodTemplateHash).Should(Equal(deploy.Annotations[annotationPodTemplateHash]))
+			})
+
+			It("should report the changed fields of the pod template", func() {
+				cl, _ := testReconcile(el)
+				deploy := loggerDeployment(cl, el)
+
+				el.Spec.NodeSelector = map[string]string{"ns-key": "other"}
+				el.Spec.Labels["team"] = "payments"
+				el.Spec.Annotations = nil
+				el.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "secret"}}
+				cl, _ = testReconcile(el, deploy)
+
+				updated := &apiv1.EventLogger{}
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+				Ω(updated.Status.LastRollout.ChangedFields).Should(Equal([]string{
+					"imagePullSecrets", "metadata.annotations", "metadata.labels", "nodeSelector",
+				}))
+			})
+
+			It("should report the creation of the deployment", func() {
+				cl, _ := testReconcile(el)
+
+				updated := &apiv1.EventLogger{}
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+				Ω(updated.Status.LastRollout).ShouldNot(BeNil())
+				Ω(updated.Status.LastRollout.Reason).Should(Equal(apiv1.RolloutReasonCreated))
+				Ω(updated.Status.LastRollout.ChangedFields).Should(BeEmpty())
+			})
+
+			It("should not replace the pod template if the hash is unchanged", func() {
+				cl, _ := testReconcile(el)
+				deploy := loggerDeployment(cl, el)
+				// fields defaulted by the api server with the rollout are not compared
+				deploy.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+				deploy.Annotations[annotationLivePodTemplateFieldHashes] = podTemplateFieldHashes(deploy.Spec.Template)
+
+				cl, _ = testReconcile(el, deploy)
+
+				deploy = loggerDeployment(cl, el)
+				Ω(deploy.Spec.Template.Spec.RestartPolicy).Should(Equal(corev1.RestartPolicyAlways))
+
+				updated := &apiv1.EventLogger{}
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+				Ω(updated.Status.LastRollout).Should(BeNil())
+			})
+
+			It("should revert the manual changes of the pod template", func() {
+				cl, _ := testReconcile(el)
+				deploy := loggerDeployment(cl, el)
+				hash := deploy.Annotations[annotationPodTemplateHash]
+				deploy.Spec.Template.Spec.NodeSelector = map[string]string{"ns-key": "edited"}
+				deploy.Spec.Template.Spec.Containers[0].Image = "edited"
+
+				cl, _ = testReconcile(el, deploy)
+
+				deploy = loggerDeployment(cl, el)
+				Ω(deploy.Spec.Template.Spec.NodeSelector).Should(Equal(el.Spec.NodeSelector))
+				Ω(deploy.Spec.Template.Spec.Containers[0].Image).ShouldNot(Equal("edited"))
+				Ω(deploy.Annotations[annotationPodTemplateHash]).Should(Equal(hash))
+
+				updated := &apiv1.EventLogger{}
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+				Ω(updated.Status.LastRollout).ShouldNot(BeNil())
+				Ω(updated.Status.LastRollout.Reason).Should(Equal(apiv1.RolloutReasonPodTemplateDrifted))
+				Ω(updated.Status.LastRollout.ChangedFields).Should(Equal([]string{
+					"containers[event-logger].image", "nodeSelector",
+				}))
+			})
+
+			It("should merge the pod template of the cr", func() {
+				el.Spec.PodTemplate = &corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{"tier": "infra", labelManagedBy: "someone"},
+					},
+					Spec: corev1.PodSpec{
+						Tolerations: []corev1.Toleration{{
+							Key: "infra", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule,
+						}},
+						PriorityClassName:  "system-cluster-critical",
+						ServiceAccountName: "other",
+						Containers: []corev1.Container{
+							{
+								Name: "event-logger",
+								Args: []string{"--other"},
+								Env:  []corev1.EnvVar{{Name: c.EnvEventAPI, Value: "other"}, {Name: "TZ", Value: "UTC"}},
+								Resources: corev1.ResourceRequirements{
+									Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+								},
+							},
+							{Name: "sidecar", Image: "sidecar"},
+						},
+					},
+				}
+				cl, _ := testReconcile(el)
+
+				pod := loggerDeployment(cl, el).Spec.Template
+				Ω(pod.Labels["tier"]).Should(Equal("infra"))
+				Ω(pod.Labels[labelManagedBy]).Should(Equal("eventlogger"))
+				Ω(pod.Labels["test-label"]).Should(Equal("foo"))
+				Ω(pod.Spec.NodeSelector["ns-key"]).Should(Equal("ns-value"))
+				Ω(pod.Spec.Tolerations).Should(Equal(el.Spec.PodTemplate.Spec.Tolerations))
+				Ω(pod.Spec.PriorityClassName).Should(Equal("system-cluster-critical"))
+				Ω(pod.Spec.ServiceAccountName).Should(Equal(loggerName(el)))
+
+				Ω(pod.Spec.Containers).Should(HaveLen(2))
+				container := pod.Spec.Containers[0]
+				Ω(container.Name).Should(Equal("event-logger"))
+				Ω(container.Image).Should(Equal(testImage))
+				Ω(container.Args).ShouldNot(ContainElement("--other"))
+				Ω(*container.Resources.Requests.Cpu()).Should(Equal(resource.MustParse("111m")))
+				Ω(*container.Resources.Limits.Memory()).Should(Equal(resource.MustParse("1Gi")))
+				Ω(podEnv(&pod.Spec, c.EnvEventAPI)).Should(Equal(string(apiv1.EventAPICoreV1)))
+				Ω(podEnv(&pod.Spec, "TZ")).Should(Equal("UTC"))
+				Ω(pod.Spec.Containers[1].Name).Should(Equal("sidecar"))
 			})
 
-			It("should update the pod image", func() {
-				pod := newPod()
-				pod.Spec.Containers[0].Image = "foo"
+			It("should mount an emptyDir at the directories of the file sinks", func() {
+				el.Spec.Sinks = []apiv1.Sink{
+					{Name: "a", Type: apiv1.SinkTypeFile, File: &apiv1.FileSink{Path: "/var/log/events/a.log"}},
+					{Name: "b", Type: apiv1.SinkTypeFile, File: &apiv1.FileSink{Path: "/var/log/events/b.log"}},
+					{Name: "mounted", Type: apiv1.SinkTypeFile, File: &apiv1.FileSink{Path: "/data/events/c.log"}},
+					{Name: "log", Type: apiv1.SinkTypeLog},
+				}
+				el.Spec.PodTemplate = &corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{{
+						Name: "data",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"},
+						},
+					}},
+					Containers: []corev1.Container{{
+						Name:         "event-logger",
+						VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+					}},
+				}}
+				cl, _ := testReconcile(el)
+
+				pod := loggerDeployment(cl, el).Spec.Template
+				Ω(pod.Spec.Volumes).Should(HaveLen(2))
+				Ω(pod.Spec.Volumes[1].Name).Should(Equal("file-sink-0"))
+				Ω(pod.Spec.Volumes[1].EmptyDir).ShouldNot(BeNil())
+				Ω(pod.Spec.Containers[0].VolumeMounts).Should(Equal([]corev1.VolumeMount{
+					{Name: "data", MountPath: "/data"},
+					{Name: "file-sink-0", MountPath: "/var/log/events"},
+				}))
+			})
 
-				cl, _ := testReconcile(el, pod)
+			It("should roll out a changed pod template of the cr", func() {
+				cl, _ := testReconcile(el)
+				deploy := loggerDeployment(cl, el)
+				hash := deploy.Annotations[annotationPodTemplateHash]
+
+				el.Spec.PodTemplate = &corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+						PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+							Weight: 1,
+							Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{
+								Key: "infra", Operator: corev1.NodeSelectorOpExists,
+							}}},
+						}},
+					}},
+				}}
+				cl, _ = testReconcile(el, deploy)
+
+				deploy = loggerDeployment(cl, el)
+				Ω(deploy.Annotations[annotationPodTemplateHash]).ShouldNot(Equal(hash))
+				Ω(deploy.Spec.Template.Spec.Affinity).Should(Equal(el.Spec.PodTemplate.Spec.Affinity))
+			})
 
-				pods := &corev1.PodList{}
-				assertEntrySize(cl, el, pods, 1)
-				pod2 := pods.Items[0]
-				Ω(pod2.Spec.Containers[0].Image).Should(Equal(testImage))
+			It("should run multiple replicas with leader election", func() {
+				el.Spec.Replicas = new(int32(3))
+				cl, _ := testReconcile(el)
+
+				deploy := loggerDeployment(cl, el)
+				Ω(*deploy.Spec.Replicas).Should(Equal(int32(3)))
+				Ω(deploy.Spec.Strategy.Type).Should(Equal(appsv1.RollingUpdateDeploymentStrategyType))
+				Ω(parseLoggerArgs(deploy.Spec.Template.Spec.Containers[0].Args).leaderElection).Should(BeTrue())
+
+				pdbs := &policyv1.PodDisruptionBudgetList{}
+				assertEntrySize(cl, el, pdbs, 1)
+				Ω(pdbs.Items[0].Spec.MinAvailable.IntValue()).Should(Equal(1))
+				Ω(pdbs.Items[0].Spec.Selector.MatchLabels).Should(Equal(deploy.Spec.Selector.MatchLabels))
+
+				role := &rbacv1.Role{}
+				Ω(cl.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: loggerName(el)}, role)).
+					ShouldNot(HaveOccurred())
+				Ω(role.Rules[len(role.Rules)-1].Resources).Should(Equal([]string{"leases"}))
+			})
+
+			It("should delete the pod disruption budget of a single replica", func() {
+				pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{
+					Name:      loggerName(el),
+					Namespace: testNamespace,
+					Labels:    copyLabels(el),
+				}}
+				cl, _ := testReconcile(el, pdb)
+
+				assertEntrySize(cl, el, &policyv1.PodDisruptionBudgetList{}, 0)
+			})
+
+			It("should delete the legacy pods", func() {
+				legacy := newPod()
+				legacy.Name = "legacy"
+				current := newPod()
+				current.Name = "current"
+				current.Labels = copyLabels(el)
+				current.OwnerReferences = []metav1.OwnerReference{{
+					APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs", UID: "rs", Controller: new(true),
+				}}
+
+				cl, _ := testReconcile(el, legacy, current)
+
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(legacy), &corev1.Pod{})).Should(HaveOccurred())
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(current), &corev1.Pod{})).ShouldNot(HaveOccurred())
+
+				updated := &apiv1.EventLogger{}
+				Ω(cl.Get(context.TODO(), client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+				Ω(updated.Status.LoggerPod).Should(Equal("current"))
 			})
 
 			It("should update the imagePullSecrets", func() {
@@ -138,26 +524,23 @@ var _ = Describe("Logging", func() {
 				cl, res := testReconcile(el)
 				Ω(res.RequeueAfter).Should(Equal(time.Duration(0)))
 
-				pods := &corev1.PodList{}
-				assertEntrySize(cl, el, pods, 1)
-				pod2 := pods.Items[0]
+				pod := loggerDeployment(cl, el).Spec.Template
 
-				Ω(len(pod2.Spec.ImagePullSecrets)).Should(Equal(2))
-				Ω(pod2.Spec.ImagePullSecrets[0].Name).Should(Equal("secret1"))
-				Ω(pod2.Spec.ImagePullSecrets[1].Name).Should(Equal("secret2"))
+				Ω(len(pod.Spec.ImagePullSecrets)).Should(Equal(2))
+				Ω(pod.Spec.ImagePullSecrets[0].Name).Should(Equal("secret1"))
+				Ω(pod.Spec.ImagePullSecrets[1].Name).Should(Equal("secret2"))
 			})
 
 			It("should use an external service account", func() {
 				el.Spec.ServiceAccount = "foo"
 
-				sacc, role, rb := rbacForCR(el)
+				sacc, role, rb := (&Reconciler{}).rbacForCR(el)
 				cl, _ := testReconcile(el, sacc, role, rb)
 
-				pods := &corev1.PodList{}
-				assertEntrySize(cl, el, pods, 1)
-				pod2 := pods.Items[0]
+				pod := loggerDeployment(cl, el).Spec.Template
 
-				Ω(pod2.Spec.Containers[0].Image).Should(Equal(testImage))
+				Ω(pod.Spec.ServiceAccountName).Should(Equal("foo"))
+				Ω(pod.Spec.Containers[0].Image).Should(Equal(testImage))
 
 				assertEntrySize(cl, el, &corev1.ServiceAccountList{}, 0)
 				assertEntrySize(cl, el, &rbacv1.RoleList{}, 0)
@@ -193,7 +576,7 @@ var _ = Describe("Logging", func() {
 				Ω(role.ObjectMeta.Labels).Should(HaveKey(labelManagedBy))
 				Ω(role.ObjectMeta.OwnerReferences).Should(HaveLen(1))
 
-				Ω(role.Rules).Should(HaveLen(2))
+				Ω(role.Rules).Should(HaveLen(3))
 				Ω(role.Rules[0].APIGroups).Should(Equal([]string{""}))
 				Ω(role.Rules[0].Resources).Should(Equal([]string{"events", "pods"}))
 				Ω(role.Rules[0].Verbs).Should(Equal([]string{"watch", "get", "list"}))
@@ -201,6 +584,83 @@ var _ = Describe("Logging", func() {
 				Ω(role.Rules[1].APIGroups).Should(Equal([]string{"eventlogger.bakito.ch"}))
 				Ω(role.Rules[1].Resources).Should(Equal([]string{"eventloggers"}))
 				Ω(role.Rules[1].Verbs).Should(Equal([]string{"get", "list", "patch", "update", "watch"}))
+
+				Ω(role.Rules[2].APIGroups).Should(Equal([]string{"eventlogger.bakito.ch"}))
+				Ω(role.Rules[2].Resources).Should(Equal([]string{"eventloggers/status"}))
+				Ω(role.Rules[2].Verbs).Should(Equal([]string{"get", "patch", "update"}))
+			})
+		})
+		Context("Role with secrets", func() {
+			It("should allow to get the referenced secrets", func() {
+				el.Spec.Sinks = []apiv1.Sink{{
+					Name: "webhook",
+					Type: apiv1.SinkTypeWebhook,
+					Webhook: &apiv1.WebhookSink{
+						URL:              "https://example.com",
+						HeadersSecretRef: &corev1.LocalObjectReference{Name: "webhook-headers"},
+					},
+				}}
+				cl, _ := testReconcile(el)
+
+				roleList := &rbacv1.RoleList{}
+				assertEntrySize(cl, el, roleList, 1)
+				role := roleList.Items[0]
+				Ω(role.Rules).Should(HaveLen(4))
+				Ω(role.Rules[3].Resources).Should(Equal([]string{"secrets"}))
+				Ω(role.Rules[3].ResourceNames).Should(Equal([]string{"webhook-headers"}))
+				Ω(role.Rules[3].Verbs).Should(Equal([]string{"get"}))
+			})
+		})
+		Context("Role with events.k8s.io events", func() {
+			It("should allow to watch the events.k8s.io events", func() {
+				el.Spec.EventAPI = apiv1.EventAPIEventsV1
+				cl, _ := testReconcile(el)
+
+				roleList := &rbacv1.RoleList{}
+				assertEntrySize(cl, el, roleList, 1)
+				role := roleList.Items[0]
+				Ω(role.Rules).Should(HaveLen(4))
+				Ω(role.Rules[3].APIGroups).Should(Equal([]string{"events.k8s.io"}))
+				Ω(role.Rules[3].Resources).Should(Equal([]string{"events"}))
+				Ω(role.Rules[3].Verbs).Should(Equal([]string{"watch", "get", "list"}))
+			})
+		})
+		Context("Role with label selectors", func() {
+			It("should allow to read the kinds with label selector", func() {
+				el.Spec.Kinds = []apiv1.Kind{
+					{Name: "Deployment", APIGroup: new("apps"), LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "payments"},
+					}},
+					{Name: "Pod"},
+					{Name: "Ingress", LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "payments"},
+					}},
+				}
+				cl, _ := testReconcile(el)
+
+				roleList := &rbacv1.RoleList{}
+				assertEntrySize(cl, el, roleList, 1)
+				role := roleList.Items[0]
+				Ω(role.Rules).Should(HaveLen(6))
+				Ω(role.Rules[3].APIGroups).Should(Equal([]string{"apps"}))
+				Ω(role.Rules[3].Resources).Should(Equal([]string{"deployments"}))
+				Ω(role.Rules[3].Verbs).Should(Equal([]string{"watch", "get", "list"}))
+				Ω(role.Rules[4].APIGroups).Should(Equal([]string{"example.com"}))
+				Ω(role.Rules[4].Resources).Should(Equal([]string{"ingresses"}))
+				Ω(role.Rules[5].APIGroups).Should(Equal([]string{"networking.k8s.io"}))
+				Ω(role.Rules[5].Resources).Should(Equal([]string{"ingresses"}))
+			})
+			It("should not grant the kinds that are not served", func() {
+				el.Spec.Kinds = []apiv1.Kind{
+					{Name: "Unknown", LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "payments"},
+					}},
+				}
+				cl, _ := testReconcile(el)
+
+				roleList := &rbacv1.RoleList{}
+				assertEntrySize(cl, el, roleList, 1)
+				Ω(roleList.Items[0].Rules).Should(HaveLen(3))
 			})
 		})
 		Context("Rolebinding", func() {
@@ -229,6 +689,18 @@ var _ = Describe("Logging", func() {
 })
 
 func testReconcile(initialObjects ...client.Object) (client.Client, reconcile.Result) {
+	return testReconcileWith(&Reconciler{}, types.NamespacedName{
+		Name:      "eventlogger",
+		Namespace: testNamespace,
+	}, initialObjects...)
+}
+
+// testReconcileWith reconciles the cr with the given name with the reconciler.
+func testReconcileWith(
+	r *Reconciler,
+	name types.NamespacedName,
+	initialObjects ...client.Object,
+) (client.Client, reconcile.Result) {
 	s := scheme.Scheme
 
 	Ω(apiv1.SchemeBuilder.AddToScheme(s)).ShouldNot(HaveOccurred())
@@ -265,7 +737,11 @@ resources:
 
 	initialObjects = append(initialObjects, operatorPod, cfg)
 
-	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(initialObjects...).Build()
+	cl := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(initialObjects...).
+		WithStatusSubresource(&apiv1.EventLogger{}, &apiv1.ClusterEventLogger{}).
+		Build()
 
 	cr := config.Reconciler{
 		Reader: cl,
@@ -281,26 +757,34 @@ resources:
 	})
 	Ω(err).ShouldNot(HaveOccurred())
 
-	r := &Reconciler{
-		Client:    cl,
-		Log:       ctrl.Log.WithName("controllers").WithName("Pod"),
-		Scheme:    s,
-		ConfigCtx: cr.Ctx(),
+	r.Client = cl
+	r.Log = ctrl.Log.WithName("controllers").WithName("Pod")
+	r.Scheme = s
+	r.ConfigCtx = cr.Ctx()
+	if r.Recorder == nil {
+		r.Recorder = events.NewFakeRecorder(100)
 	}
-
-	req := reconcile.Request{
-		NamespacedName: types.NamespacedName{
-			Name:      "eventlogger",
-			Namespace: testNamespace,
-		},
+	if r.Discovery == nil {
+		r.Discovery = memory.NewMemCacheClient(&fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{
+			Resources: []*metav1.APIResourceList{
+				{GroupVersion: "networking.k8s.io/v1", APIResources: []metav1.APIResource{
+					{Name: "ingresses", Kind: "Ingress"},
+				}},
+				{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{
+					{Name: "ingresses", Kind: "Ingress"},
+					{Name: "ingresses/status", Kind: "Ingress"},
+				}},
+			},
+		}})
 	}
-	res, err := r.Reconcile(context.Background(), req)
+
+	res, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: name})
 	Ω(err).ShouldNot(HaveOccurred())
 
 	return cl, res
 }
 
-func assertEntrySize(cl client.Client, el *apiv1.EventLogger, list client.ObjectList, expected int) {
+func assertEntrySize(cl client.Client, el apiv1.Object, list client.ObjectList, expected int) {
 	option := client.MatchingLabels{}
 	applyDefaultLabels(el, option)
 	err := cl.List(context.TODO(), list, option)
@@ -311,6 +795,13 @@ func assertEntrySize(cl client.Client, el *apiv1.EventLogger, list client.Object
 	Ω(f.Len()).Should(Equal(expected))
 }
 
+// loggerDeployment returns the logger deployment of the cr.
+func loggerDeployment(cl client.Client, el apiv1.Object) *appsv1.Deployment {
+	deploys := &appsv1.DeploymentList{}
+	assertEntrySize(cl, el, deploys, 1)
+	return &deploys.Items[0]
+}
+
 func newPod() *corev1.Pod {
 	return &corev1.Pod{
 		TypeMeta: metav1.TypeMeta{
@@ -330,3 +821,35 @@ func newPod() *corev1.Pod {
 		},
 	}
 }
+
+// loggerFlags the flags of the logger parsed from the args of the logger container.
+type loggerFlags struct {
+	configName     string
+	metricsAddr    string
+	loggerMode     bool
+	clusterConfig  bool
+	leaderElection bool
+}
+
+// parseLoggerArgs parses the args with the flags registered by the operator main.
+func parseLoggerArgs(args []string) loggerFlags {
+	var f loggerFlags
+	fs := flag.NewFlagSet("logger", flag.ContinueOnError)
+	fs.StringVar(&f.configName, c.ArgConfigName, "", "")
+	fs.StringVar(&f.metricsAddr, c.ArgMetricsAddr, "", "")
+	fs.BoolVar(&f.loggerMode, c.ArgEnableLoggerMode, false, "")
+	fs.BoolVar(&f.clusterConfig, c.ArgClusterConfig, false, "")
+	fs.BoolVar(&f.leaderElection, c.ArgEnableLeaderElection, false, "")
+	Ω(fs.Parse(args)).ShouldNot(HaveOccurred())
+	Ω(fs.Args()).Should(BeEmpty(), "all args must be parsed as flags")
+	return f
+}
+
+func podEnv(spec *corev1.PodSpec, name string) string {
+	for _, env := range spec.Containers[0].Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+	return "N/A"
+}
@@ -21,27 +21,36 @@ import (
 	"fmt"
 	"os"
 	gr "runtime"
+	"strings"
+	"time"
 
 	"github.com/go-logr/zapr"
 	zap2 "go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	crtlcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	eventloggerv2 "github.com/bakito/k8s-event-logger-operator/api/v2"
 	"github.com/bakito/k8s-event-logger-operator/controllers/config"
+	"github.com/bakito/k8s-event-logger-operator/controllers/conversion"
 	"github.com/bakito/k8s-event-logger-operator/controllers/logging"
 	"github.com/bakito/k8s-event-logger-operator/controllers/setup"
 	cnst "github.com/bakito/k8s-event-logger-operator/pkg/constants"
+	"github.com/bakito/k8s-event-logger-operator/pkg/expression"
 	"github.com/bakito/k8s-event-logger-operator/version"
 	"github.com/bakito/operator-utils/pkg/pprof"
 
@@ -56,8 +65,11 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(eventloggerv1.AddToScheme(scheme))
+	utilruntime.Must(eventloggerv2.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 	utilruntime.Must(corev1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
+	eventloggerv1.RegisterExpressionParser(expression.Parser{})
 }
 
 func main() {
@@ -68,6 +80,7 @@ func main() {
 	var enableLeaderElection bool
 	var enableLoggerMode bool
 	var enableProfiling bool
+	var clusterConfig bool
 	flag.StringVar(
 		&metricsAddr,
 		cnst.ArgMetricsAddr,
@@ -93,6 +106,8 @@ func main() {
 
 	flag.StringVar(&configName, cnst.ArgConfigName, "",
 		"The name of the eventlogger config to work with.")
+	flag.BoolVar(&clusterConfig, cnst.ArgClusterConfig, false,
+		"The eventlogger config is a ClusterEventLogger.")
 	flag.Parse()
 
 	o := func(o *zap.Options) {
@@ -109,12 +124,38 @@ func main() {
 
 	watchNamespace := os.Getenv(cnst.EnvWatchNamespace)
 	podNamespace := os.Getenv(cnst.EnvPodNamespace)
+	podName := os.Getenv(cnst.EnvPodName)
+	if podName == "" {
+		podName, _ = os.Hostname()
+	}
 
-	defaultNamespaces := map[string]crtlcache.Config{
-		watchNamespace: {},
+	// the logger pod may watch multiple comma separated namespaces
+	var watchNamespaces []string
+	var defaultNamespaces map[string]crtlcache.Config
+	for ns := range strings.SplitSeq(watchNamespace, ",") {
+		if ns != "" {
+			watchNamespaces = append(watchNamespaces, ns)
+			if defaultNamespaces == nil {
+				defaultNamespaces = make(map[string]crtlcache.Config)
+			}
+			defaultNamespaces[ns] = crtlcache.Config{}
+		}
+	}
+	var byObject map[client.Object]crtlcache.ByObject
+	if enableLoggerMode && !clusterConfig && podNamespace != "" {
+		// the EventLogger is in the namespace of the logger pod, which is not necessarily watched
+		byObject = map[client.Object]crtlcache.ByObject{
+			&eventloggerv1.EventLogger{}: {Namespaces: map[string]crtlcache.Config{podNamespace: {}}},
+		}
 	}
-	if watchNamespace == "" {
-		defaultNamespaces = nil
+
+	leaderElectionID := "leader.eventlogger.bakito.ch"
+	if enableLoggerMode {
+		// the logger pods of an event logger elect their own leader
+		leaderElectionID = configName + ".logger.eventlogger.bakito.ch"
+		if clusterConfig {
+			leaderElectionID = "cluster-" + leaderElectionID
+		}
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -126,14 +167,21 @@ func main() {
 			Port:    9443,
 			CertDir: "certs",
 		}),
-		LeaderElection:                enableLeaderElection && !enableLoggerMode,
-		LeaderElectionID:              "leader.eventlogger.bakito.ch",
+		LeaderElection:                enableLeaderElection,
+		LeaderElectionID:              leaderElectionID,
 		LeaderElectionResourceLock:    os.Getenv(cnst.EnvLeaderElectionResourceLock),
 		HealthProbeBindAddress:        healthAddr,
 		LeaderElectionReleaseOnCancel: true,
 
 		Cache: crtlcache.Options{
 			DefaultNamespaces: defaultNamespaces,
+			ByObject:          byObject,
+		},
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				// secrets are read only on demand, no need to watch them
+				DisableFor: []client.Object{&corev1.Secret{}},
+			},
 		},
 	})
 	if err != nil {
@@ -141,15 +189,33 @@ func main() {
 		os.Exit(1)
 	}
 
+	var eventReconciler *logging.Reconciler
 	if enableLoggerMode {
-		setupLog.WithValues("configName", configName).Info("Current configuration")
-		if err = (&logging.Reconciler{
-			Client:     mgr.GetClient(),
-			Log:        ctrl.Log.WithName("controllers").WithName("Event"),
-			Scheme:     mgr.GetScheme(),
-			Config:     logging.ConfigFor(configName, podNamespace, watchNamespace),
-			LoggerMode: true,
-		}).SetupWithManager(mgr, watchNamespace); err != nil {
+		setupLog.WithValues("configName", configName, "cluster", clusterConfig).Info("Current configuration")
+		cfg := logging.ConfigFor(configName, podNamespace)
+		if clusterConfig {
+			cfg = logging.ClusterConfigFor(configName, podNamespace)
+		}
+		eventReconciler = &logging.Reconciler{
+			Client:         mgr.GetClient(),
+			Log:            ctrl.Log.WithName("controllers").WithName("Event"),
+			Scheme:         mgr.GetScheme(),
+			Config:         cfg,
+			Cache:          mgr.GetCache(),
+			LoggerMode:     true,
+			PodName:        podName,
+			LeaderElection: enableLeaderElection,
+			EventAPI:       eventloggerv1.EventAPI(os.Getenv(cnst.EnvEventAPI)),
+		}
+		if cm := os.Getenv(cnst.EnvCheckpointConfigMap); cm != "" {
+			interval, err := time.ParseDuration(os.Getenv(cnst.EnvCheckpointInterval))
+			if err != nil {
+				setupLog.Error(err, "invalid checkpoint interval")
+				os.Exit(1)
+			}
+			eventReconciler.Checkpoint = &logging.Checkpoint{ConfigMap: cm, Interval: interval}
+		}
+		if err = eventReconciler.SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "Event")
 			os.Exit(1)
 		}
@@ -165,31 +231,76 @@ func main() {
 				setupLog.Error(err, "unable to create controller", "controller", "Config")
 				os.Exit(1)
 			}
+			dc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+			if err != nil {
+				setupLog.Error(err, "unable to create discovery client")
+				os.Exit(1)
+			}
+			kindDiscovery := memory.NewMemCacheClient(dc)
 			if err = (&setup.Reconciler{
 				Client:    mgr.GetClient(),
 				Log:       ctrl.Log.WithName("controllers").WithName("EventLogger"),
 				Scheme:    mgr.GetScheme(),
 				ConfigCtx: cr.Ctx(),
+				Recorder:  mgr.GetEventRecorder(cnst.EventReportingController),
+				Discovery: kindDiscovery,
 			}).SetupWithManager(mgr); err != nil {
 				setupLog.Error(err, "unable to create controller", "controller", "EventLogger")
 				os.Exit(1)
 			}
+			if err = (&setup.Reconciler{
+				Client:    mgr.GetClient(),
+				Log:       ctrl.Log.WithName("controllers").WithName("ClusterEventLogger"),
+				Scheme:    mgr.GetScheme(),
+				ConfigCtx: cr.Ctx(),
+				Cluster:   true,
+				Namespace: podNamespace,
+				Recorder:  mgr.GetEventRecorder(cnst.EventReportingController),
+				Discovery: kindDiscovery,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "ClusterEventLogger")
+				os.Exit(1)
+			}
 			setupLog.Info("Running in global mode.")
 
 			if os.Getenv(cnst.EnvEnableWebhook) != "false" {
-				if err = (&eventloggerv1.EventLogger{}).SetupWebhookWithManager(mgr); err != nil {
+				configCtx := cr.Ctx()
+				defaultLogFields := func() []eventloggerv1.LogField {
+					return config.GetCfg(configCtx).DefaultLogFields
+				}
+				if err = (&eventloggerv1.EventLogger{}).SetupWebhookWithManager(mgr, defaultLogFields); err != nil {
 					setupLog.Error(err, "unable to create webhook", "webhook", "EventLogger")
 					os.Exit(1)
 				}
+				if err = (&eventloggerv1.ClusterEventLogger{}).SetupWebhookWithManager(mgr, defaultLogFields); err != nil {
+					setupLog.Error(err, "unable to create webhook", "webhook", "ClusterEventLogger")
+					os.Exit(1)
+				}
+				// the EventLogger webhook serves the conversion between the api versions
+				if err = mgr.Add(&conversion.Enabler{
+					Reader:    mgr.GetAPIReader(),
+					Client:    mgr.GetClient(),
+					Log:       ctrl.Log.WithName("conversion"),
+					Service:   os.Getenv(cnst.EnvOperatorName),
+					Namespace: podNamespace,
+					Interval:  10 * time.Second,
+				}); err != nil {
+					setupLog.Error(err, "unable to create conversion enabler")
+					os.Exit(1)
+				}
 			}
 		} else {
-			if err = (&logging.Reconciler{
+			eventReconciler = &logging.Reconciler{
 				Client:     mgr.GetClient(),
 				Log:        ctrl.Log.WithName("controllers").WithName("Event"),
 				Scheme:     mgr.GetScheme(),
-				Config:     logging.ConfigFor(configName, podNamespace, watchNamespace),
+				Config:     logging.ConfigFor(configName, podNamespace, watchNamespaces...),
+				Cache:      mgr.GetCache(),
 				LoggerMode: false,
-			}).SetupWithManager(mgr, watchNamespace); err != nil {
+				PodName:    podName,
+				EventAPI:   eventloggerv1.EventAPI(os.Getenv(cnst.EnvEventAPI)),
+			}
+			if err = eventReconciler.SetupWithManager(mgr); err != nil {
 				setupLog.Error(err, "unable to create controller", "controller", "Event")
 				os.Exit(1)
 			}
@@ -215,7 +326,12 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	if eventReconciler != nil {
+		// flush the output of the logger before the pod terminates
+		eventReconciler.Close()
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
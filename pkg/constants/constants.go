@@ -10,6 +10,8 @@ const (
 
 	// ArgConfigName name of the config.
 	ArgConfigName = "config-name"
+	// ArgClusterConfig the config is a ClusterEventLogger.
+	ArgClusterConfig = "cluster-config"
 
 	// ArgMetricsAddr metrics address.
 	ArgMetricsAddr = "metrics-addr"
@@ -38,6 +40,15 @@ const (
 	// EnvWatchNamespace watch namespace env variable.
 	EnvWatchNamespace = "WATCH_NAMESPACE"
 
+	// EnvEventAPI the api version of the events to watch.
+	EnvEventAPI = "EVENT_API"
+
+	// EnvCheckpointConfigMap the name of the config map the logger stores its checkpoint in.
+	EnvCheckpointConfigMap = "CHECKPOINT_CONFIG_MAP"
+
+	// EnvCheckpointInterval the interval the logger stores its checkpoint in.
+	EnvCheckpointInterval = "CHECKPOINT_INTERVAL"
+
 	// EnvEventLoggerImage env variable name for the image if the event logger.
 	EnvEventLoggerImage = "EVENT_LOGGER_IMAGE"
 
@@ -56,6 +67,21 @@ const (
 	// EnvConfigReload watch the configmap for changes.
 	EnvConfigReload = "CONFIG_RELOAD"
 
+	// EnvOperatorName the name of the operator, its service serves the webhooks.
+	EnvOperatorName = "OPERATOR_NAME"
+
+	// WebhookConfigurationName the name of the webhook configurations of the operator.
+	WebhookConfigurationName = "eventlogger.bakito.ch"
+
+	// EventLoggerCRDName the name of the EventLogger crd.
+	EventLoggerCRDName = "eventloggers.eventlogger.bakito.ch"
+
 	// ConfigKeyContainerTemplate pod template config key.
 	ConfigKeyContainerTemplate = "container_template.yaml"
+
+	// ConfigKeyDefaultLogFields default log fields config key.
+	ConfigKeyDefaultLogFields = "default_log_fields.yaml"
+
+	// EventReportingController the reporting controller of the events recorded by the operator.
+	EventReportingController = "eventlogger.bakito.ch/operator"
 )
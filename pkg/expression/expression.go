@@ -0,0 +1,40 @@
+// Package expression parses the templates, CEL expressions and JSONPaths of the event logger specs for their
+// validation.
+package expression
+
+import (
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/filter"
+	"github.com/bakito/k8s-event-logger-operator/pkg/logfield"
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+)
+
+var _ eventloggerv1.ExpressionParser = Parser{}
+
+// Parser parses the templates with the sink, the CEL expressions with the filter and the log fields with the
+// logfield package.
+type Parser struct{}
+
+// ParseTemplate returns an error if the template can not be rendered with an event.
+func (Parser) ParseTemplate(tmpl string) error {
+	_, err := sink.ParseTemplate(tmpl)
+	return err
+}
+
+// ParseCEL returns an error if the expression does not compile to a bool.
+func (Parser) ParseCEL(expr string) error {
+	_, err := filter.NewCEL(expr)
+	return err
+}
+
+// ParseJSONPath returns an error if the JSONPath is invalid or selects unknown fields of an event.
+func (Parser) ParseJSONPath(expr string) error {
+	_, err := logfield.NewJSONPath(expr)
+	return err
+}
+
+// ParseLogTemplate returns an error if the template is invalid or uses unknown fields of an event.
+func (Parser) ParseLogTemplate(tmpl string) error {
+	_, err := logfield.NewTemplate(tmpl)
+	return err
+}
@@ -0,0 +1,27 @@
+package expression_test
+
+import (
+	"github.com/bakito/k8s-event-logger-operator/pkg/expression"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parser", func() {
+	It("should parse a template", func() {
+		Ω(expression.Parser{}.ParseTemplate("{{ .Reason }}: {{ .Message }}")).ShouldNot(HaveOccurred())
+		Ω(expression.Parser{}.ParseTemplate("{{ .Reason ")).Should(HaveOccurred())
+	})
+	It("should parse a CEL expression", func() {
+		Ω(expression.Parser{}.ParseCEL(`event.type == "Warning"`)).ShouldNot(HaveOccurred())
+		Ω(expression.Parser{}.ParseCEL(`event.type`)).Should(HaveOccurred())
+	})
+	It("should parse the JSONPath of a log field", func() {
+		Ω(expression.Parser{}.ParseJSONPath(".involvedObject.name")).ShouldNot(HaveOccurred())
+		Ω(expression.Parser{}.ParseJSONPath(".involvedObject.foo")).Should(HaveOccurred())
+	})
+	It("should parse the template of a log field", func() {
+		Ω(expression.Parser{}.ParseLogTemplate("{{ .involvedObject.name }}")).ShouldNot(HaveOccurred())
+		Ω(expression.Parser{}.ParseLogTemplate("{{ .InvolvedObject.Name }}")).Should(HaveOccurred())
+	})
+})
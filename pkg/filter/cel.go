@@ -0,0 +1,77 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// CELVariable the name of the variable the event is bound to in CEL expressions.
+	CELVariable = "event"
+	// CELCostLimit the max cost of the evaluation of an expression per event, the limit of the kubernetes api server.
+	// The evaluation of expressions exceeding it is aborted, the event does not match.
+	CELCostLimit = 1000000
+)
+
+var celEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		ext.NativeTypes(ext.ParseStructTag("json"), reflect.TypeFor[*corev1.Event]()),
+		ext.Strings(),
+		cel.Variable(CELVariable, cel.ObjectType("v1.Event")),
+	)
+})
+
+// NewCEL compiles the CEL expression and creates a new Filter evaluating it against the event.
+// The fields of the event are accessed by their json names e.g. event.count > 5 && event.reason.startsWith("Failed").
+// An error is returned if the expression does not compile, type-check or evaluate to bool.
+func NewCEL(expression string) (Filter, error) {
+	env, err := celEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(expression)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must evaluate to bool, but evaluates to %s", ast.OutputType())
+	}
+	prg, err := env.Program(ast, cel.CostLimit(CELCostLimit))
+	if err != nil {
+		return nil, err
+	}
+	return &CEL{Expression: expression, program: prg}, nil
+}
+
+// CEL is a Filter evaluating a CEL expression.
+type CEL struct {
+	Expression string
+	program    cel.Program
+}
+
+// Match implements Filter interface. Events the expression can not be evaluated for do not match.
+func (c *CEL) Match(e *corev1.Event) bool {
+	if e == nil {
+		return false
+	}
+	out, _, err := c.program.Eval(map[string]any{CELVariable: e})
+	if err != nil {
+		return false
+	}
+	match, ok := out.Value().(bool)
+	return ok && match
+}
+
+// Equals implements Filter interface.
+func (c *CEL) Equals(o Filter) bool {
+	return c.String() == o.String()
+}
+
+func (c *CEL) String() string {
+	return fmt.Sprintf("CEL '%s'", c.Expression)
+}
@@ -0,0 +1,74 @@
+package filter_test
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	f "github.com/bakito/k8s-event-logger-operator/pkg/filter"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CEL", func() {
+	var event *corev1.Event
+	BeforeEach(func() {
+		event = &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "my-event", Namespace: "shop"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "payments-api-1"},
+			Reason:         "FailedMount",
+			Count:          6,
+			Type:           "Warning",
+		}
+	})
+	It("should match the expression", func() {
+		filter, err := f.NewCEL(`event.count > 5 && event.reason.startsWith("Failed")`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(filter.Match(event)).Should(BeTrue())
+
+		event.Count = 5
+		Ω(filter.Match(event)).Should(BeFalse())
+		Ω(filter.Match(nil)).Should(BeFalse())
+		Ω(filter.String()).Should(Equal(`CEL 'event.count > 5 && event.reason.startsWith("Failed")'`))
+	})
+	It("should access nested fields", func() {
+		filter, err := f.NewCEL(`event.involvedObject.kind == "Pod" && event.metadata.namespace == "shop"`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(filter.Match(event)).Should(BeTrue())
+	})
+	It("should not match if the expression can not be evaluated", func() {
+		filter, err := f.NewCEL(`event.metadata.labels["team"] == "payments"`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(filter.Match(event)).Should(BeFalse())
+	})
+	It("should not match if the expression exceeds the cost limit", func() {
+		list := "[" + strings.Repeat("0, ", 99) + "0]"
+		filter, err := f.NewCEL(fmt.Sprintf("%s.all(a, %s.all(b, %s.all(c, a == b)))", list, list, list))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(filter.Match(event)).Should(BeFalse())
+	})
+	It("should be equal for the same expression", func() {
+		filter1, _ := f.NewCEL(`event.count > 5`)
+		filter2, _ := f.NewCEL(`event.count > 5`)
+		filter3, _ := f.NewCEL(`event.count > 6`)
+		Ω(filter1.Equals(filter2)).Should(BeTrue())
+		Ω(filter1.Equals(filter3)).Should(BeFalse())
+	})
+	It("should fail to compile", func() {
+		_, err := f.NewCEL(`event.count >`)
+		Ω(err).Should(HaveOccurred())
+	})
+	It("should fail to type-check unknown fields", func() {
+		_, err := f.NewCEL(`event.unknown == 1`)
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring("undefined field 'unknown'"))
+	})
+	It("should fail if the expression does not evaluate to bool", func() {
+		_, err := f.NewCEL(`event.count`)
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring("must evaluate to bool"))
+	})
+})
@@ -0,0 +1,226 @@
+package logfield
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+var (
+	eventType     = reflect.TypeFor[corev1.Event]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// field a field of the corev1.Event or one of its nested structs.
+type field struct {
+	// jsonName the name of the field in the json representation
+	jsonName string
+	typ      reflect.Type
+}
+
+// fields returns the fields of the struct type, nil if the type is no struct.
+func fields(t reflect.Type) []field {
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var result []field
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && (name == "" || strings.Contains(opts, "inline")) {
+			result = append(result, fields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		result = append(result, field{jsonName: name, typ: f.Type})
+	}
+	return result
+}
+
+// child returns the type of the named field in the json representation of a value of type t. Nil is returned if
+// the type is not known, e.g. for the values of maps, and an error if there is no such field.
+func child(t reflect.Type, parent, name string) (reflect.Type, error) {
+	t = deref(t)
+	switch {
+	case t == nil || t.Kind() == reflect.Interface:
+		return nil, nil
+	case t.Kind() == reflect.Map:
+		return t.Elem(), nil
+	case t.Kind() == reflect.Struct && !isMarshaler(t):
+		var suggestion string
+		for _, f := range fields(t) {
+			if f.jsonName == name {
+				return f.typ, nil
+			}
+			if strings.EqualFold(f.jsonName, name) {
+				suggestion = f.jsonName
+			}
+		}
+		return nil, fieldError(parent, name, suggestion)
+	}
+	return nil, fmt.Errorf("%s of type %s has no fields", parent, t)
+}
+
+// elem returns the type of the elements of a list in the json representation of a value of type t.
+func elem(t reflect.Type, parent string) (reflect.Type, error) {
+	t = deref(t)
+	switch {
+	case t == nil || t.Kind() == reflect.Interface || t.Kind() == reflect.Map:
+		return nil, nil
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return t.Elem(), nil
+	}
+	return nil, fmt.Errorf("%s of type %s is no list", parent, t)
+}
+
+func deref(t reflect.Type) reflect.Type {
+	if t != nil && t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// isMarshaler returns true if the type has a custom json representation e.g. metav1.Time.
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType)
+}
+
+// checkJSONPath verifies the fields of the JSONPath exist in the corev1.Event. Checking stops at values of unknown
+// type. Range blocks are not supported, the matched values are logged as list.
+func checkJSONPath(root *jsonpath.ListNode) error {
+	for _, n := range root.Nodes {
+		list, ok := n.(*jsonpath.ListNode)
+		if !ok {
+			continue
+		}
+		t := eventType
+		var path string
+		for _, n := range list.Nodes {
+			var err error
+			switch n := n.(type) {
+			case *jsonpath.FieldNode:
+				if n.Value != "" {
+					t, err = child(t, path, n.Value)
+					path += "." + n.Value
+				}
+			case *jsonpath.ArrayNode, *jsonpath.FilterNode:
+				t, err = elem(t, path)
+				path += "[]"
+			case *jsonpath.IdentifierNode:
+				return fmt.Errorf("%s is not supported", n.Name)
+			default:
+				t = nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkTemplate verifies the fields used by the template exist in the corev1.Event. Fields of the dot are only
+// checked where the dot is the event, the fields of the $ variable everywhere.
+func checkTemplate(tmpl *template.Template) error {
+	if tmpl.Tree == nil {
+		return nil
+	}
+	return checkNode(tmpl.Tree.Root, true)
+}
+
+func checkNode(node parse.Node, dotIsEvent bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, c := range n.Nodes {
+			if err := checkNode(c, dotIsEvent); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkPipe(n.Pipe, dotIsEvent)
+	case *parse.TemplateNode:
+		return checkPipe(n.Pipe, dotIsEvent)
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode, dotIsEvent, dotIsEvent)
+	case *parse.RangeNode:
+		return checkBranch(&n.BranchNode, dotIsEvent, false)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode, dotIsEvent, false)
+	}
+	return nil
+}
+
+// checkBranch checks a branch, the dot of the list of range and with blocks is not the event.
+func checkBranch(n *parse.BranchNode, dotIsEvent, listDotIsEvent bool) error {
+	if err := checkPipe(n.Pipe, dotIsEvent); err != nil {
+		return err
+	}
+	if err := checkNode(n.List, listDotIsEvent && dotIsEvent); err != nil {
+		return err
+	}
+	return checkNode(n.ElseList, dotIsEvent)
+}
+
+func checkPipe(pipe *parse.PipeNode, dotIsEvent bool) error {
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			var err error
+			switch a := arg.(type) {
+			case *parse.FieldNode:
+				if dotIsEvent {
+					err = checkFields(a.Ident)
+				}
+			case *parse.VariableNode:
+				if a.Ident[0] == "$" {
+					err = checkFields(a.Ident[1:])
+				}
+			case *parse.PipeNode:
+				err = checkPipe(a, dotIsEvent)
+			case *parse.ChainNode:
+				if p, ok := a.Node.(*parse.PipeNode); ok {
+					err = checkPipe(p, dotIsEvent)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkFields(ident []string) error {
+	t := eventType
+	var path string
+	for _, name := range ident {
+		var err error
+		if t, err = child(t, path, name); err != nil {
+			return err
+		}
+		path += "." + name
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package logfield
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+var relaxedJSONPath = regexp.MustCompile(`^\{?(\.?[^{}]*)\}?$`)
+
+// Extractor extracts the value of a log field from the json representation of an event.
+type Extractor interface {
+	// Extract returns the value of the log field, false if the event has no value for it.
+	Extract(event map[string]any) (any, bool)
+}
+
+// EventMap returns the json representation of the event the extractors are evaluated on.
+func EventMap(evt *corev1.Event) (map[string]any, error) {
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(evt)
+}
+
+// NewJSONPath compiles the kubectl style JSONPath expression e.g. .involvedObject.name or {.related.name}. The
+// braces and the leading dot are optional. The fields are verified to exist in the corev1.Event.
+func NewJSONPath(expression string) (Extractor, error) {
+	m := relaxedJSONPath.FindStringSubmatch(expression)
+	if m == nil || strings.TrimSpace(m[1]) == "" {
+		return nil, errors.New("must be a single JSONPath expression e.g. .involvedObject.name")
+	}
+	expr := m[1]
+	if !strings.HasPrefix(expr, ".") {
+		expr = "." + expr
+	}
+	parser, err := jsonpath.Parse("logField", "{"+expr+"}")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkJSONPath(parser.Root); err != nil {
+		return nil, err
+	}
+	jp := jsonpath.New("logField").AllowMissingKeys(true)
+	if err := jp.Parse("{" + expr + "}"); err != nil {
+		return nil, err
+	}
+	return &jsonPathExtractor{jp: jp}, nil
+}
+
+// jsonPathExtractor evaluates a JSONPath. The paths are checked not to contain range blocks, the evaluation of
+// the other nodes does not change the state of the JSONPath, so it can be used concurrently.
+type jsonPathExtractor struct {
+	jp *jsonpath.JSONPath
+}
+
+// Extract implements Extractor interface. A single result is returned as value, multiple results as list.
+func (e *jsonPathExtractor) Extract(event map[string]any) (any, bool) {
+	results, err := e.jp.FindResults(event)
+	if err != nil {
+		return nil, false
+	}
+	var values []any
+	for _, r := range results {
+		for _, v := range r {
+			if v.Kind() == reflect.Interface {
+				v = v.Elem()
+			}
+			if v.IsValid() {
+				values = append(values, v.Interface())
+			}
+		}
+	}
+	switch len(values) {
+	case 0:
+		return nil, false
+	case 1:
+		return values[0], true
+	}
+	return values, true
+}
+
+// NewTemplate compiles the go template e.g. {{ .involvedObject.kind }}/{{ .involvedObject.name }}. The template
+// is rendered with the json representation of the event, the fields are verified to exist in the corev1.Event.
+// Fields that are not set in the event are rendered as <no value>, optional fields can be guarded by if or with.
+func NewTemplate(text string) (Extractor, error) {
+	tmpl, err := template.New("logField").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTemplate(tmpl); err != nil {
+		return nil, err
+	}
+	return &templateExtractor{tmpl: tmpl}, nil
+}
+
+type templateExtractor struct {
+	tmpl *template.Template
+}
+
+// Extract implements Extractor interface. The rendered template is returned, false if it can not be rendered.
+func (e *templateExtractor) Extract(event map[string]any) (any, bool) {
+	var sb strings.Builder
+	if err := e.tmpl.Execute(&sb, event); err != nil {
+		return nil, false
+	}
+	return sb.String(), true
+}
+
+// fieldError the error of a field that does not exist in the corev1.Event.
+func fieldError(parent, name, suggestion string) error {
+	if parent == "" {
+		parent = "the event"
+	}
+	if suggestion != "" {
+		return fmt.Errorf("%s has no field %s, did you mean %s", parent, name, suggestion)
+	}
+	return fmt.Errorf("%s has no field %s", parent, name)
+}
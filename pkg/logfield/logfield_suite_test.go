@@ -0,0 +1,13 @@
+package logfield_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLogField(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LogField Suite")
+}
@@ -0,0 +1,127 @@
+package logfield_test
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	lf "github.com/bakito/k8s-event-logger-operator/pkg/logfield"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LogField", func() {
+	var event map[string]any
+	BeforeEach(func() {
+		var err error
+		event, err = lf.EventMap(&corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-event",
+				Namespace: "shop",
+				Labels:    map[string]string{"team": "payments"},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "ReplicaSet", Name: "payments-api"},
+					{Kind: "Deployment", Name: "payments"},
+				},
+			},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "payments-api-1"},
+			Related:        &corev1.ObjectReference{Kind: "Node", Name: "my-node"},
+			Reason:         "FailedMount",
+			Count:          6,
+			LastTimestamp:  metav1.NewTime(time.Date(2026, 10, 17, 8, 30, 0, 0, time.UTC)),
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	Context("NewJSONPath", func() {
+		DescribeTable("should extract the value",
+			func(expression string, expected any) {
+				e, err := lf.NewJSONPath(expression)
+				Ω(err).ShouldNot(HaveOccurred())
+				val, ok := e.Extract(event)
+				Ω(ok).Should(BeTrue())
+				Ω(val).Should(Equal(expected))
+			},
+			Entry("nested field", ".involvedObject.name", "payments-api-1"),
+			Entry("braces", "{.related.name}", "my-node"),
+			Entry("without leading dot", "metadata.namespace", "shop"),
+			Entry("number", ".count", int64(6)),
+			Entry("timestamp", ".lastTimestamp", "2026-10-17T08:30:00Z"),
+			Entry("map key", ".metadata.labels.team", "payments"),
+			Entry("index", ".metadata.ownerReferences[1].kind", "Deployment"),
+			Entry("multiple values", ".metadata.ownerReferences[*].name", []any{"payments-api", "payments"}),
+			Entry("filter", `.metadata.ownerReferences[?(@.kind=="ReplicaSet")].name`, "payments-api"),
+			Entry("struct", ".related", map[string]any{"kind": "Node", "name": "my-node"}),
+		)
+		It("should not extract missing values", func() {
+			e, err := lf.NewJSONPath(".series.count")
+			Ω(err).ShouldNot(HaveOccurred())
+			_, ok := e.Extract(event)
+			Ω(ok).Should(BeFalse())
+		})
+		DescribeTable("should fail to compile",
+			func(expression, expected string) {
+				_, err := lf.NewJSONPath(expression)
+				Ω(err).Should(HaveOccurred())
+				Ω(err.Error()).Should(ContainSubstring(expected))
+			},
+			Entry("empty", "", "must be a single JSONPath expression"),
+			Entry("multiple expressions", "{.reason}/{.message}", "must be a single JSONPath expression"),
+			Entry("go field name", ".InvolvedObject.Name",
+				"the event has no field InvolvedObject, did you mean involvedObject"),
+			Entry("unknown nested field", ".involvedObject.foo", ".involvedObject has no field foo"),
+			Entry("field of a value", ".reason.foo", ".reason of type string has no fields"),
+			Entry("field of a timestamp", ".lastTimestamp.Time", ".lastTimestamp of type v1.Time has no fields"),
+			Entry("index of a struct", ".involvedObject[0]", ".involvedObject of type v1.ObjectReference is no list"),
+			Entry("range", ".metadata range", "range is not supported"),
+			Entry("syntax", ".metadata.ownerReferences[", "unterminated array"),
+		)
+	})
+
+	Context("NewTemplate", func() {
+		It("should render the template", func() {
+			e, err := lf.NewTemplate(`{{ .involvedObject.kind }}/{{ .involvedObject.name }}`)
+			Ω(err).ShouldNot(HaveOccurred())
+			val, ok := e.Extract(event)
+			Ω(ok).Should(BeTrue())
+			Ω(val).Should(Equal("Pod/payments-api-1"))
+		})
+		It("should render blocks", func() {
+			e, err := lf.NewTemplate(
+				`{{ range .metadata.ownerReferences }}{{ .kind }}:{{ $.metadata.namespace }} {{ end }}` +
+					`{{ with .series }}{{ .count }}{{ else }}{{ .count }}{{ end }}`)
+			Ω(err).ShouldNot(HaveOccurred())
+			val, ok := e.Extract(event)
+			Ω(ok).Should(BeTrue())
+			Ω(val).Should(Equal("ReplicaSet:shop Deployment:shop 6"))
+		})
+		It("should render missing fields as no value", func() {
+			e, err := lf.NewTemplate(`{{ .series.count }}`)
+			Ω(err).ShouldNot(HaveOccurred())
+			val, ok := e.Extract(event)
+			Ω(ok).Should(BeTrue())
+			Ω(val).Should(Equal("<no value>"))
+		})
+		It("should not extract a value if the template can not be rendered", func() {
+			e, err := lf.NewTemplate(`{{ index .metadata.ownerReferences 5 }}`)
+			Ω(err).ShouldNot(HaveOccurred())
+			_, ok := e.Extract(event)
+			Ω(ok).Should(BeFalse())
+		})
+		DescribeTable("should fail to compile",
+			func(text, expected string) {
+				_, err := lf.NewTemplate(text)
+				Ω(err).Should(HaveOccurred())
+				Ω(err.Error()).Should(ContainSubstring(expected))
+			},
+			Entry("syntax", "{{ .reason ", "unclosed action"),
+			Entry("go field name", "{{ .Reason }}", "the event has no field Reason, did you mean reason"),
+			Entry("unknown nested field", "{{ .involvedObject.foo }}", ".involvedObject has no field foo"),
+			Entry("root variable", "{{ range .metadata.ownerReferences }}{{ $.foo }}{{ end }}", "the event has no field foo"),
+			Entry("if block", "{{ if .reason }}{{ .Message }}{{ end }}", "the event has no field Message"),
+			Entry("nested pipe", "{{ printf \"%s\" (.involvedObject.Kind) }}", ".involvedObject has no field Kind"),
+		)
+	})
+})
@@ -0,0 +1,80 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ChatType the type of chat a notification is sent to.
+type ChatType string
+
+const (
+	// ChatSlack sends the notification to a slack incoming webhook.
+	ChatSlack ChatType = "slack"
+	// ChatTeams sends the notification to a microsoft teams incoming webhook.
+	ChatTeams ChatType = "teams"
+	// ChatGeneric posts the notification with the event fields as json.
+	ChatGeneric ChatType = "generic"
+
+	// DefaultChatTemplate the default template of a chat notification.
+	DefaultChatTemplate = `{{ .Type }} {{ .InvolvedObject.Kind }} {{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}: ` +
+		`{{ .Reason }} - {{ .Message }} (count: {{ .Count }})`
+)
+
+var errNoEvent = errors.New("the record has no event")
+
+// ParseTemplate parses a chat notification template and verifies it can be rendered with a corev1.Event.
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultChatTemplate
+	}
+	tmpl, err := template.New("notification").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, &corev1.Event{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// NewChat creates a new sink that sends each record as chat notification rendered by the template.
+func NewChat(name string, chatType ChatType, url string, tmpl *template.Template) Async {
+	return NewWebhook(name, WebhookOptions{
+		URL:       url,
+		BatchSize: 1,
+		Encode: func(records []*Record) ([]byte, error) {
+			return encodeChat(chatType, tmpl, records[0])
+		},
+	})
+}
+
+func encodeChat(chatType ChatType, tmpl *template.Template, r *Record) ([]byte, error) {
+	if r.Event == nil {
+		return nil, errNoEvent
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r.Event); err != nil {
+		return nil, err
+	}
+	text := buf.String()
+
+	switch chatType {
+	case ChatSlack:
+		return json.Marshal(map[string]any{"text": text})
+	case ChatTeams:
+		return json.Marshal(map[string]any{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  r.Event.Reason,
+			"text":     text,
+		})
+	default:
+		return json.Marshal(map[string]any{"text": text, "event": r.Map()})
+	}
+}
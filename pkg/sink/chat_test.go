@@ -0,0 +1,72 @@
+package sink_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Chat", func() {
+	Context("ParseTemplate", func() {
+		It("should parse the default template", func() {
+			_, err := sink.ParseTemplate("")
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+		It("should parse a valid template", func() {
+			_, err := sink.ParseTemplate("{{ .Reason }} {{ .InvolvedObject.Name }}: {{ .Message }} {{ .Count }}")
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+		It("should fail on an invalid template", func() {
+			_, err := sink.ParseTemplate("{{ .Reason ")
+			Ω(err).Should(HaveOccurred())
+		})
+		It("should fail on an unknown field", func() {
+			_, err := sink.ParseTemplate("{{ .Foo }}")
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	DescribeTable("NewChat",
+		func(chatType sink.ChatType, expected map[string]any) {
+			body := make(chan map[string]any, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				m := map[string]any{}
+				_ = json.Unmarshal(b, &m)
+				body <- m
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			tmpl, err := sink.ParseTemplate("{{ .Reason }} {{ .InvolvedObject.Name }}")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			s := sink.NewChat("chat", chatType, server.URL, tmpl)
+			Ω(s.Send(context.Background(), &sink.Record{
+				Event: &corev1.Event{
+					Reason:         "BackOff",
+					InvolvedObject: corev1.ObjectReference{Name: "my-pod"},
+				},
+				Message: "msg",
+			})).ShouldNot(HaveOccurred())
+			Ω(s.Close()).ShouldNot(HaveOccurred())
+
+			b := <-body
+			for k, v := range expected {
+				Ω(b).Should(HaveKeyWithValue(k, v))
+			}
+		},
+		Entry("slack", sink.ChatSlack, map[string]any{"text": "BackOff my-pod"}),
+		Entry("teams", sink.ChatTeams, map[string]any{"text": "BackOff my-pod", "@type": "MessageCard", "summary": "BackOff"}),
+		Entry("generic", sink.ChatGeneric, map[string]any{"text": "BackOff my-pod", "event": map[string]any{"msg": "msg"}}),
+	)
+})
@@ -0,0 +1,30 @@
+package sink
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+)
+
+// NewLogr creates a new sink that logs the records with the given logger.
+func NewLogr(name string, log logr.Logger) Sink {
+	return &logrSink{name: name, log: log}
+}
+
+type logrSink struct {
+	name string
+	log  logr.Logger
+}
+
+func (s *logrSink) Send(_ context.Context, r *Record) error {
+	s.log.WithValues(r.Fields...).Info(r.Message)
+	return nil
+}
+
+func (*logrSink) Close() error {
+	return nil
+}
+
+func (s *logrSink) String() string {
+	return s.name
+}
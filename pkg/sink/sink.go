@@ -0,0 +1,214 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Record is a matched event with the fields to be sent to a sink.
+type Record struct {
+	// Event the matched event
+	Event *corev1.Event
+	// Message the message of the record
+	Message string
+	// Fields the fields of the record as alternating key value pairs
+	Fields []any
+}
+
+// Map returns the message and the fields of the record as map.
+func (r *Record) Map() map[string]any {
+	m := map[string]any{"msg": r.Message}
+	for i := 0; i+1 < len(r.Fields); i += 2 {
+		m[fmt.Sprint(r.Fields[i])] = r.Fields[i+1]
+	}
+	return m
+}
+
+// Sink is an output matched events are sent to.
+type Sink interface {
+	// Send sends the record to the sink
+	Send(ctx context.Context, r *Record) error
+	// Close releases the resources of the sink
+	Close() error
+	// String returns the name of the sink
+	String() string
+}
+
+// Encoder encodes a record into a single line.
+type Encoder func(r *Record) ([]byte, error)
+
+// JSON encodes the record as json object.
+func JSON(r *Record) ([]byte, error) {
+	return json.Marshal(r.Map())
+}
+
+// Text encodes the record as key=value pairs.
+func Text(r *Record) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("msg=")
+	buf.WriteString(strconv.Quote(r.Message))
+	for i := 0; i+1 < len(r.Fields); i += 2 {
+		buf.WriteByte(' ')
+		buf.WriteString(fmt.Sprint(r.Fields[i]))
+		buf.WriteByte('=')
+		if s, ok := r.Fields[i+1].(string); ok {
+			buf.WriteString(strconv.Quote(s))
+			continue
+		}
+		b, err := json.Marshal(r.Fields[i+1])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
+// FailurePolicy defines how failures of a sink are handled.
+type FailurePolicy string
+
+const (
+	// FailureLog logs the error of the sink.
+	FailureLog FailurePolicy = "Log"
+	// FailureIgnore ignores the error of the sink.
+	FailureIgnore FailurePolicy = "Ignore"
+	// FailureFallback sends the record to the fallback sink.
+	FailureFallback FailurePolicy = "Fallback"
+)
+
+// Target is a sink with its failure policy.
+type Target struct {
+	Sink
+	OnFailure FailurePolicy
+}
+
+// queueFullLogInterval the min interval between the logs of the records dropped by a full queue of a sink.
+const queueFullLogInterval = 10 * time.Second
+
+// Fanout sends records to multiple sinks and handles the failures per sink.
+type Fanout struct {
+	targets  []Target
+	fallback Sink
+	log      logr.Logger
+	onError  func(sink string, records int)
+
+	mu sync.Mutex
+	// queueFull the records dropped by a full queue per sink, since they were logged last
+	queueFull map[string]*droppedRecords
+}
+
+// droppedRecords the records dropped by a sink since they were logged last.
+type droppedRecords struct {
+	count  int
+	logged time.Time
+}
+
+// NewFanout creates a new Fanout. Errors are logged with log, records of failed targets with policy
+// FailureFallback are sent to the fallback sink. If onError is not nil, it is called with the name of the sink and
+// the number of records whenever sending to a sink failed, independent of the failure policy of the target.
+func NewFanout(log logr.Logger, fallback Sink, onError func(sink string, records int), targets ...Target) *Fanout {
+	f := &Fanout{
+		targets:   targets,
+		fallback:  fallback,
+		log:       log,
+		onError:   onError,
+		queueFull: make(map[string]*droppedRecords),
+	}
+	for _, t := range targets {
+		if a, ok := t.Sink.(Async); ok {
+			a.OnError(func(records []*Record, err error) {
+				f.countError(t, len(records))
+				f.handleAsyncError(t, records, err)
+			})
+		}
+	}
+	return f
+}
+
+// Send sends the record to all targets.
+func (f *Fanout) Send(ctx context.Context, r *Record) {
+	for _, t := range f.targets {
+		if err := t.Send(ctx, r); err != nil {
+			f.countError(t, 1)
+			f.handleError(ctx, t, r, err)
+		}
+	}
+}
+
+func (f *Fanout) countError(t Target, records int) {
+	if f.onError != nil {
+		f.onError(t.String(), records)
+	}
+}
+
+func (f *Fanout) handleError(ctx context.Context, t Target, r *Record, err error) {
+	switch t.OnFailure {
+	case FailureIgnore:
+	case FailureFallback:
+		if f.fallback != nil {
+			if ferr := f.fallback.Send(ctx, r); ferr != nil {
+				f.log.Error(ferr, "error sending event to fallback sink", "sink", t.String())
+			}
+		}
+	default:
+		if errors.Is(err, ErrQueueFull) {
+			f.logQueueFull(t, err)
+			return
+		}
+		f.log.Error(err, "error sending event to sink", "sink", t.String())
+	}
+}
+
+// logQueueFull logs the records dropped by a full queue of the sink at most once per queueFullLogInterval, a full
+// queue drops every record sent until the sink catches up.
+func (f *Fanout) logQueueFull(t Target, err error) {
+	f.mu.Lock()
+	d, ok := f.queueFull[t.String()]
+	if !ok {
+		d = &droppedRecords{}
+		f.queueFull[t.String()] = d
+	}
+	d.count++
+	now := time.Now()
+	if now.Sub(d.logged) < queueFullLogInterval {
+		f.mu.Unlock()
+		return
+	}
+	dropped := d.count
+	d.count = 0
+	d.logged = now
+	f.mu.Unlock()
+	f.log.Error(err, "events dropped by sink", "sink", t.String(), "events", dropped)
+}
+
+func (f *Fanout) handleAsyncError(t Target, records []*Record, err error) {
+	switch t.OnFailure {
+	case FailureIgnore:
+	case FailureFallback:
+		for _, r := range records {
+			f.handleError(context.Background(), t, r, err)
+		}
+	default:
+		f.log.Error(err, "error sending events to sink", "sink", t.String(), "events", len(records))
+	}
+}
+
+// Close closes all targets.
+func (f *Fanout) Close() error {
+	var errs []error
+	for _, t := range f.targets {
+		if err := t.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing sink %q: %w", t.String(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,157 @@
+package sink_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sink", func() {
+	var (
+		ctx context.Context
+		r   *sink.Record
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		r = &sink.Record{
+			Event:   &corev1.Event{Type: corev1.EventTypeWarning},
+			Message: "the message",
+			Fields:  []any{"name", "foo", "count", 3, "involvedObject", corev1.ObjectReference{Kind: "Pod"}},
+		}
+	})
+
+	Context("Encoder", func() {
+		It("should encode as json", func() {
+			b, err := sink.JSON(r)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(Equal(`{"count":3,"involvedObject":{"kind":"Pod"},"msg":"the message","name":"foo"}`))
+		})
+		It("should encode as text", func() {
+			b, err := sink.Text(r)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(Equal(`msg="the message" name="foo" count=3 involvedObject={"kind":"Pod"}`))
+		})
+	})
+
+	Context("Writer", func() {
+		It("should write one line per record", func() {
+			var buf bytes.Buffer
+			s := sink.NewWriter("stdout", &buf, sink.Text)
+			Ω(s.Send(ctx, r)).ShouldNot(HaveOccurred())
+			Ω(s.Send(ctx, r)).ShouldNot(HaveOccurred())
+			Ω(s.Close()).ShouldNot(HaveOccurred())
+			Ω(s.String()).Should(Equal("stdout"))
+			Ω(bytes.Count(buf.Bytes(), []byte("\n"))).Should(Equal(2))
+		})
+		It("should append to a file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "events.log")
+			s, err := sink.NewFile("file", path, sink.JSON)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(s.Send(ctx, r)).ShouldNot(HaveOccurred())
+			Ω(s.Close()).ShouldNot(HaveOccurred())
+
+			b, err := os.ReadFile(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(ContainSubstring(`"msg":"the message"`))
+		})
+	})
+
+	Context("Fanout", func() {
+		var (
+			ok       *bytes.Buffer
+			fallback *bytes.Buffer
+		)
+		BeforeEach(func() {
+			ok = &bytes.Buffer{}
+			fallback = &bytes.Buffer{}
+		})
+		It("should send to all targets", func() {
+			other := &bytes.Buffer{}
+			f := sink.NewFanout(logr.Discard(), nil, nil,
+				sink.Target{Sink: sink.NewWriter("a", ok, sink.JSON)},
+				sink.Target{Sink: sink.NewWriter("b", other, sink.Text)},
+			)
+			f.Send(ctx, r)
+			Ω(ok.String()).ShouldNot(BeEmpty())
+			Ω(other.String()).ShouldNot(BeEmpty())
+		})
+		It("should send to the fallback on failure", func() {
+			f := sink.NewFanout(logr.Discard(), sink.NewWriter("fallback", fallback, sink.JSON), nil,
+				sink.Target{Sink: &failingSink{}, OnFailure: sink.FailureFallback},
+				sink.Target{Sink: sink.NewWriter("a", ok, sink.JSON)},
+			)
+			f.Send(ctx, r)
+			Ω(ok.String()).ShouldNot(BeEmpty())
+			Ω(fallback.String()).ShouldNot(BeEmpty())
+		})
+		It("should not send to the fallback if ignored", func() {
+			f := sink.NewFanout(logr.Discard(), sink.NewWriter("fallback", fallback, sink.JSON), nil,
+				sink.Target{Sink: &failingSink{}, OnFailure: sink.FailureIgnore},
+			)
+			f.Send(ctx, r)
+			Ω(fallback.String()).Should(BeEmpty())
+		})
+		It("should report the errors independent of the failure policy", func() {
+			errs := map[string]int{}
+			f := sink.NewFanout(logr.Discard(), nil, func(name string, records int) { errs[name] += records },
+				sink.Target{Sink: &failingSink{}, OnFailure: sink.FailureIgnore},
+				sink.Target{Sink: sink.NewWriter("a", ok, sink.JSON)},
+			)
+			f.Send(ctx, r)
+			f.Send(ctx, r)
+			Ω(errs).Should(Equal(map[string]int{"failing": 2}))
+		})
+		It("should log the records dropped by a full queue once per interval", func() {
+			var logs []string
+			log := funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{})
+			errs := 0
+			f := sink.NewFanout(log, nil, func(_ string, records int) { errs += records },
+				sink.Target{Sink: &failingSink{err: sink.ErrQueueFull}},
+			)
+			for range 3 {
+				f.Send(ctx, r)
+			}
+			Ω(errs).Should(Equal(3))
+			Ω(logs).Should(HaveLen(1))
+			Ω(logs[0]).Should(ContainSubstring(`"msg"="events dropped by sink" "error"="queue is full, record dropped" ` +
+				`"sink"="failing" "events"=1`))
+		})
+		It("should return the close errors", func() {
+			f := sink.NewFanout(logr.Discard(), nil, nil,
+				sink.Target{Sink: &failingSink{}},
+				sink.Target{Sink: sink.NewWriter("a", ok, sink.JSON)},
+			)
+			Ω(f.Close()).Should(MatchError(ContainSubstring(`error closing sink "failing"`)))
+		})
+	})
+})
+
+type failingSink struct {
+	err error
+}
+
+func (s *failingSink) Send(context.Context, *sink.Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	return errors.New("send failed")
+}
+
+func (*failingSink) Close() error {
+	return errors.New("close failed")
+}
+
+func (*failingSink) String() string {
+	return "failing"
+}
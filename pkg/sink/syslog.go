@@ -0,0 +1,43 @@
+package sink
+
+import (
+	"context"
+	"log/syslog"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NewSyslog creates a new sink that sends the records to syslog. If network is empty, the local syslog
+// server is used.
+func NewSyslog(name, network, address, tag string, enc Encoder) (Sink, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{name: name, w: w, enc: enc}, nil
+}
+
+type syslogSink struct {
+	name string
+	w    *syslog.Writer
+	enc  Encoder
+}
+
+func (s *syslogSink) Send(_ context.Context, r *Record) error {
+	b, err := s.enc(r)
+	if err != nil {
+		return err
+	}
+	if r.Event != nil && r.Event.Type == corev1.EventTypeWarning {
+		return s.w.Warning(string(b))
+	}
+	return s.w.Info(string(b))
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
+
+func (s *syslogSink) String() string {
+	return s.name
+}
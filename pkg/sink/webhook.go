@@ -0,0 +1,285 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// DefaultBatchSize the default max number of records sent with one request.
+	DefaultBatchSize = 100
+	// DefaultFlushInterval the default max time records are buffered before being sent.
+	DefaultFlushInterval = 5 * time.Second
+	// DefaultQueueSize the default max number of records buffered in memory.
+	DefaultQueueSize = 1000
+	// DefaultMaxRetries the default max number of retries of a failed request.
+	DefaultMaxRetries = 5
+)
+
+// ErrQueueFull is returned if a record is dropped because the queue of an async sink is full.
+var ErrQueueFull = errors.New("queue is full, record dropped")
+
+// ErrorHandler is called with the records an async sink could not send.
+type ErrorHandler func(records []*Record, err error)
+
+// Async is a sink that sends the records asynchronously and reports the failures to an ErrorHandler.
+type Async interface {
+	Sink
+	// OnError registers the handler for records that could not be sent
+	OnError(h ErrorHandler)
+	// Dropped returns the number of records that were dropped
+	Dropped() int64
+}
+
+// WebhookOptions the options of a webhook sink.
+type WebhookOptions struct {
+	// URL the records are posted to
+	URL string
+	// Header additional headers of the requests
+	Header http.Header
+	// RefreshHeader reads the headers again, e.g. from a rotated secret. It is called once per batch if the endpoint
+	// rejects the request with status 401 or 403, the request is retried with the new headers.
+	RefreshHeader func() (http.Header, error)
+	// BatchSize the max number of records sent with one request
+	BatchSize int
+	// FlushInterval the max time records are buffered before being sent
+	FlushInterval time.Duration
+	// QueueSize the max number of records buffered in memory
+	QueueSize int
+	// MaxRetries the max number of retries of a failed request
+	MaxRetries int
+	// RetryInterval the initial interval between the retries, it is doubled with every retry
+	RetryInterval time.Duration
+	// Client the http client to use
+	Client *http.Client
+	// Encode encodes a batch of records into the request body. Default: json array of the record maps
+	Encode func(records []*Record) ([]byte, error)
+}
+
+// NewWebhook creates a new sink that posts the records in batches to an http endpoint.
+func NewWebhook(name string, opts WebhookOptions) Async {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 500 * time.Millisecond
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if opts.Encode == nil {
+		opts.Encode = encodeJSONBatch
+	}
+
+	w := &webhookSink{
+		name:  name,
+		opts:  opts,
+		queue: make(chan *Record, opts.QueueSize),
+		stop:  make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+type webhookSink struct {
+	name      string
+	opts      WebhookOptions
+	queue     chan *Record
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+	dropped   atomic.Int64
+	onError   atomic.Pointer[ErrorHandler]
+}
+
+func (w *webhookSink) Send(_ context.Context, r *Record) error {
+	select {
+	case <-w.stop:
+		w.dropped.Add(1)
+		return ErrQueueFull
+	default:
+	}
+	select {
+	case w.queue <- r:
+		return nil
+	default:
+		w.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+func (w *webhookSink) OnError(h ErrorHandler) {
+	w.onError.Store(&h)
+}
+
+func (w *webhookSink) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+func (w *webhookSink) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	w.wg.Wait()
+	return nil
+}
+
+func (w *webhookSink) String() string {
+	return w.name
+}
+
+func (w *webhookSink) run() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Record, 0, w.opts.BatchSize)
+	for {
+		select {
+		case r := <-w.queue:
+			batch = append(batch, r)
+			if len(batch) >= w.opts.BatchSize {
+				w.flush(batch)
+				batch = make([]*Record, 0, w.opts.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = make([]*Record, 0, w.opts.BatchSize)
+			}
+		case <-w.stop:
+			// drain the records still in the queue
+			for {
+				select {
+				case r := <-w.queue:
+					batch = append(batch, r)
+					if len(batch) >= w.opts.BatchSize {
+						w.flush(batch)
+						batch = make([]*Record, 0, w.opts.BatchSize)
+					}
+				default:
+					if len(batch) > 0 {
+						w.flush(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *webhookSink) flush(batch []*Record) {
+	body, err := w.opts.Encode(batch)
+	if err == nil {
+		err = w.postWithRetry(body)
+	}
+	if err != nil {
+		w.dropped.Add(int64(len(batch)))
+		if h := w.onError.Load(); h != nil {
+			(*h)(batch, err)
+		}
+	}
+}
+
+func (w *webhookSink) postWithRetry(body []byte) error {
+	backoff := wait.Backoff{
+		Duration: w.opts.RetryInterval,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    w.opts.MaxRetries,
+		Cap:      time.Minute,
+	}
+	refreshed := false
+	for {
+		retry, err := w.post(body)
+		if !refreshed && rejected(err) && w.opts.RefreshHeader != nil {
+			refreshed = true
+			header, herr := w.opts.RefreshHeader()
+			if herr != nil {
+				return errors.Join(err, herr)
+			}
+			w.opts.Header = header
+			continue
+		}
+		if err == nil || !retry || backoff.Steps == 0 {
+			return err
+		}
+		select {
+		case <-time.After(backoff.Step()):
+		case <-w.stop:
+			// no more retries when closing
+			return err
+		}
+	}
+}
+
+// post sends the body and returns true if the request should be retried.
+func (w *webhookSink) post(body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range w.opts.Header {
+		req.Header[k] = v
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := w.opts.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = &statusError{name: w.name, code: resp.StatusCode}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError, err
+}
+
+// statusError the error of a request the endpoint responded with a status other than 2xx.
+type statusError struct {
+	name string
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook %q returned status %d", e.name, e.code)
+}
+
+// rejected returns true if the endpoint rejected the credentials of the request.
+func rejected(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && (se.code == http.StatusUnauthorized || se.code == http.StatusForbidden)
+}
+
+func encodeJSONBatch(records []*Record) ([]byte, error) {
+	batch := make([]map[string]any, len(records))
+	for i, r := range records {
+		batch[i] = r.Map()
+	}
+	return json.Marshal(batch)
+}
@@ -0,0 +1,243 @@
+package sink_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/bakito/k8s-event-logger-operator/pkg/sink"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Webhook", func() {
+	var (
+		ctx      context.Context
+		server   *httptest.Server
+		mu       sync.Mutex
+		batches  [][]map[string]any
+		headers  []http.Header
+		requests atomic.Int32
+		status   func(n int32) int
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		batches = nil
+		headers = nil
+		requests.Store(0)
+		status = func(int32) int { return http.StatusOK }
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := requests.Add(1)
+			code := status(n)
+			if code == http.StatusOK {
+				b, _ := io.ReadAll(r.Body)
+				var batch []map[string]any
+				_ = json.Unmarshal(b, &batch)
+				mu.Lock()
+				batches = append(batches, batch)
+				headers = append(headers, r.Header.Clone())
+				mu.Unlock()
+			}
+			w.WriteHeader(code)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	received := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := 0
+		for _, b := range batches {
+			n += len(b)
+		}
+		return n
+	}
+
+	It("should send the records in batches", func() {
+		s := sink.NewWebhook("webhook", sink.WebhookOptions{
+			URL:           server.URL,
+			BatchSize:     2,
+			FlushInterval: time.Hour,
+			Header:        http.Header{"Authorization": []string{"Bearer token"}},
+		})
+		for i := range 5 {
+			Ω(s.Send(ctx, &sink.Record{Message: "msg", Fields: []any{"i", i}})).ShouldNot(HaveOccurred())
+		}
+		Eventually(received).Should(Equal(4))
+		Ω(s.Close()).ShouldNot(HaveOccurred())
+
+		Ω(received()).Should(Equal(5))
+		Ω(batches).Should(HaveLen(3))
+		Ω(batches[0][0]).Should(HaveKeyWithValue("msg", "msg"))
+		Ω(headers[0].Get("Authorization")).Should(Equal("Bearer token"))
+		Ω(headers[0].Get("Content-Type")).Should(Equal("application/json"))
+		Ω(s.Dropped()).Should(BeZero())
+	})
+
+	It("should flush after the interval", func() {
+		s := sink.NewWebhook("webhook", sink.WebhookOptions{
+			URL:           server.URL,
+			FlushInterval: 10 * time.Millisecond,
+		})
+		defer s.Close()
+		Ω(s.Send(ctx, &sink.Record{Message: "msg"})).ShouldNot(HaveOccurred())
+		Eventually(received).Should(Equal(1))
+	})
+
+	It("should retry failed requests", func() {
+		status = func(n int32) int {
+			if n < 3 {
+				return http.StatusServiceUnavailable
+			}
+			return http.StatusOK
+		}
+		s := sink.NewWebhook("webhook", sink.WebhookOptions{
+			URL:           server.URL,
+			BatchSize:     1,
+			MaxRetries:    3,
+			RetryInterval: time.Millisecond,
+		})
+		defer s.Close()
+		Ω(s.Send(ctx, &sink.Record{Message: "msg"})).ShouldNot(HaveOccurred())
+		Eventually(received).Should(Equal(1))
+		Ω(requests.Load()).Should(Equal(int32(3)))
+		Ω(s.Dropped()).Should(BeZero())
+	})
+
+	It("should not retry client errors and report the dropped records", func() {
+		status = func(int32) int { return http.StatusBadRequest }
+		var failed atomic.Int32
+		s := sink.NewWebhook("webhook", sink.WebhookOptions{
+			URL:           server.URL,
+			BatchSize:     1,
+			MaxRetries:    3,
+			RetryInterval: time.Millisecond,
+		})
+		s.OnError(func(records []*sink.Record, err error) {
+			Ω(err).Should(MatchError(ContainSubstring("returned status 400")))
+			failed.Add(int32(len(records)))
+		})
+		Ω(s.Send(ctx, &sink.Record{Message: "msg"})).ShouldNot(HaveOccurred())
+		Ω(s.Close()).ShouldNot(HaveOccurred())
+		Ω(requests.Load()).Should(Equal(int32(1)))
+		Ω(failed.Load()).Should(Equal(int32(1)))
+		Ω(s.Dropped()).Should(Equal(int64(1)))
+	})
+
+	It("should refresh the headers if the request is rejected", func() {
+		status = func(n int32) int {
+			if n == 1 {
+				return http.StatusUnauthorized
+			}
+			return http.StatusOK
+		}
+		var refreshed atomic.Int32
+		s := sink.NewWebhook("webhook", sink.WebhookOptions{
+			URL:       server.URL,
+			BatchSize: 1,
+			Header:    http.Header{"Authorization": []string{"Bearer old"}},
+			RefreshHeader: func() (http.Header, error) {
+				refreshed.Add(1)
+				return http.Header{"Authorization": []string{"Bearer new"}}, nil
+			},
+		})
+		Ω(s.Send(ctx, &sink.Record{Message: "msg"})).ShouldNot(HaveOccurred())
+		Ω(s.Close()).ShouldNot(HaveOccurred())
+		Ω(received()).Should(Equal(1))
+		Ω(headers[0].Get("Authorization")).Should(Equal("Bearer new"))
+		Ω(refreshed.Load()).Should(Equal(int32(1)))
+	})
+
+	It("should refresh the headers only once per batch", func() {
+		status = func(int32) int { return http.StatusForbidden }
+		var refreshed atomic.Int32
+		s := sink.NewWebhook("webhook", sink.WebhookOptions{
+			URL:       server.URL,
+			BatchSize: 1,
+			RefreshHeader: func() (http.Header, error) {
+				refreshed.Add(1)
+				return http.Header{}, nil
+			},
+		})
+		Ω(s.Send(ctx, &sink.Record{Message: "msg"})).ShouldNot(HaveOccurred())
+		Ω(s.Close()).ShouldNot(HaveOccurred())
+		Ω(requests.Load()).Should(Equal(int32(2)))
+		Ω(refreshed.Load()).Should(Equal(int32(1)))
+		Ω(s.Dropped()).Should(Equal(int64(1)))
+	})
+
+	It("should drop records if the queue is full", func() {
+		block := make(chan struct{})
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			<-block
+			w.WriteHeader(http.StatusOK)
+		})
+		s := sink.NewWebhook("webhook", sink.WebhookOptions{
+			URL:       server.URL,
+			BatchSize: 1,
+			QueueSize: 1,
+		})
+		var err error
+		for range 10 {
+			if err = s.Send(ctx, &sink.Record{Message: "msg"}); err != nil {
+				break
+			}
+		}
+		Ω(err).Should(MatchError(sink.ErrQueueFull))
+		Ω(s.Dropped()).Should(BeNumerically(">", 0))
+		close(block)
+		Ω(s.Close()).ShouldNot(HaveOccurred())
+	})
+
+	It("should send failed records to the fallback of the fanout", func() {
+		status = func(int32) int { return http.StatusBadRequest }
+		fallback := &recordingSink{}
+		s := sink.NewWebhook("webhook", sink.WebhookOptions{URL: server.URL, BatchSize: 2})
+		f := sink.NewFanout(logr.Discard(), fallback, nil, sink.Target{Sink: s, OnFailure: sink.FailureFallback})
+		f.Send(ctx, &sink.Record{Message: "a"})
+		f.Send(ctx, &sink.Record{Message: "b"})
+		Ω(f.Close()).ShouldNot(HaveOccurred())
+		Ω(fallback.messages()).Should(Equal([]string{"a", "b"}))
+	})
+})
+
+type recordingSink struct {
+	mu      sync.Mutex
+	records []*sink.Record
+}
+
+func (s *recordingSink) Send(_ context.Context, r *sink.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, r)
+	return nil
+}
+
+func (*recordingSink) Close() error {
+	return nil
+}
+
+func (*recordingSink) String() string {
+	return "recording"
+}
+
+func (s *recordingSink) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var msgs []string
+	for _, r := range s.records {
+		msgs = append(msgs, r.Message)
+	}
+	return msgs
+}
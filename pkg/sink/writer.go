@@ -0,0 +1,52 @@
+package sink
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// NewWriter creates a new sink that writes each record as a single line to w.
+func NewWriter(name string, w io.Writer, enc Encoder) Sink {
+	return &writerSink{name: name, w: w, enc: enc}
+}
+
+// NewFile creates a new sink that appends each record as a single line to the file at path.
+func NewFile(name, path string, enc Encoder) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &writerSink{name: name, w: f, enc: enc, closer: f}, nil
+}
+
+type writerSink struct {
+	name   string
+	w      io.Writer
+	enc    Encoder
+	closer io.Closer
+	mu     sync.Mutex
+}
+
+func (s *writerSink) Send(_ context.Context, r *Record) error {
+	b, err := s.enc(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+func (s *writerSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+func (s *writerSink) String() string {
+	return s.name
+}
@@ -0,0 +1,39 @@
+package status
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	eventloggerv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+)
+
+// Patch applies mutate to the status of the event logger or cluster event logger and patches the status
+// subresource. The patch is guarded by the resource version of the event logger. On a conflict the latest version
+// is read, mutate is applied again and the patch is retried. If mutate does not change the status, no patch is sent.
+func Patch[T eventloggerv1.Object](
+	ctx context.Context,
+	cl client.Client,
+	el T,
+	mutate func(el T),
+) error {
+	key := client.ObjectKeyFromObject(el)
+	first := true
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if !first {
+			if err := cl.Get(ctx, key, el); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		base := el.DeepCopyObject().(T)
+		mutate(el)
+		if equality.Semantic.DeepEqual(base.GetStatus(), el.GetStatus()) {
+			return nil
+		}
+		return cl.Status().Patch(ctx, el, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	})
+}
@@ -0,0 +1,132 @@
+package status_test
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	apiv1 "github.com/bakito/k8s-event-logger-operator/api/v1"
+	"github.com/bakito/k8s-event-logger-operator/pkg/status"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Patch", func() {
+	var (
+		ctx context.Context
+		s   *runtime.Scheme
+		el  *apiv1.EventLogger
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		s = runtime.NewScheme()
+		Ω(apiv1.AddToScheme(s)).ShouldNot(HaveOccurred())
+		el = &apiv1.EventLogger{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "el"},
+			Spec:       apiv1.EventLoggerSpec{EventTypes: []string{"Warning"}},
+		}
+	})
+
+	newClient := func(funcs interceptor.Funcs) client.Client {
+		return fake.NewClientBuilder().
+			WithScheme(s).
+			WithObjects(el).
+			WithStatusSubresource(el).
+			WithInterceptorFuncs(funcs).
+			Build()
+	}
+
+	It("should patch the status only", func() {
+		cl := newClient(interceptor.Funcs{})
+		current := &apiv1.EventLogger{}
+		Ω(cl.Get(ctx, client.ObjectKeyFromObject(el), current)).ShouldNot(HaveOccurred())
+		current.Spec.EventTypes = []string{"Normal"}
+
+		Ω(status.Patch(ctx, cl, current, func(el *apiv1.EventLogger) {
+			el.Status.LoggerPod = "pod"
+		})).ShouldNot(HaveOccurred())
+
+		updated := &apiv1.EventLogger{}
+		Ω(cl.Get(ctx, client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+		Ω(updated.Status.LoggerPod).Should(Equal("pod"))
+		Ω(updated.Spec.EventTypes).Should(Equal([]string{"Warning"}))
+	})
+
+	It("should not patch if the status is unchanged", func() {
+		patched := 0
+		cl := newClient(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, cl client.Client, subResourceName string, obj client.Object,
+				patch client.Patch, opts ...client.SubResourcePatchOption,
+			) error {
+				patched++
+				return cl.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		})
+		current := &apiv1.EventLogger{}
+		Ω(cl.Get(ctx, client.ObjectKeyFromObject(el), current)).ShouldNot(HaveOccurred())
+
+		Ω(status.Patch(ctx, cl, current, func(*apiv1.EventLogger) {})).ShouldNot(HaveOccurred())
+		Ω(patched).Should(Equal(0))
+	})
+
+	It("should retry with the latest version on conflicts", func() {
+		cl := newClient(interceptor.Funcs{})
+		stale := &apiv1.EventLogger{}
+		Ω(cl.Get(ctx, client.ObjectKeyFromObject(el), stale)).ShouldNot(HaveOccurred())
+
+		// another writer updates the status in the meantime
+		other := stale.DeepCopy()
+		other.Status.LoggerPod = "other"
+		Ω(cl.Status().Update(ctx, other)).ShouldNot(HaveOccurred())
+
+		calls := 0
+		Ω(status.Patch(ctx, cl, stale, func(el *apiv1.EventLogger) {
+			calls++
+			el.Status.Error = "failed"
+		})).ShouldNot(HaveOccurred())
+		Ω(calls).Should(Equal(2))
+
+		updated := &apiv1.EventLogger{}
+		Ω(cl.Get(ctx, client.ObjectKeyFromObject(el), updated)).ShouldNot(HaveOccurred())
+		Ω(updated.Status.Error).Should(Equal("failed"))
+		Ω(updated.Status.LoggerPod).Should(Equal("other"))
+	})
+
+	It("should return other errors", func() {
+		cl := newClient(interceptor.Funcs{
+			SubResourcePatch: func(context.Context, client.Client, string, client.Object, client.Patch,
+				...client.SubResourcePatchOption,
+			) error {
+				return errors.NewForbidden(apiv1.GroupVersion.WithResource("eventloggers").GroupResource(), "el", nil)
+			},
+		})
+		current := &apiv1.EventLogger{}
+		Ω(cl.Get(ctx, client.ObjectKeyFromObject(el), current)).ShouldNot(HaveOccurred())
+
+		err := status.Patch(ctx, cl, current, func(el *apiv1.EventLogger) {
+			el.Status.LoggerPod = "pod"
+		})
+		Ω(errors.IsForbidden(err)).Should(BeTrue())
+	})
+
+	It("should patch the status of a cluster event logger", func() {
+		cel := &apiv1.ClusterEventLogger{ObjectMeta: metav1.ObjectMeta{Name: "cel"}}
+		cl := fake.NewClientBuilder().WithScheme(s).WithObjects(cel).WithStatusSubresource(cel).Build()
+		current := &apiv1.ClusterEventLogger{}
+		Ω(cl.Get(ctx, client.ObjectKeyFromObject(cel), current)).ShouldNot(HaveOccurred())
+
+		Ω(status.Patch(ctx, cl, current, func(cel *apiv1.ClusterEventLogger) {
+			cel.Status.LoggerPod = "pod"
+		})).ShouldNot(HaveOccurred())
+
+		updated := &apiv1.ClusterEventLogger{}
+		Ω(cl.Get(ctx, client.ObjectKeyFromObject(cel), updated)).ShouldNot(HaveOccurred())
+		Ω(updated.Status.LoggerPod).Should(Equal("pod"))
+	})
+})
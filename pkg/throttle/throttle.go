@@ -0,0 +1,256 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+)
+
+// DefaultSummaryInterval the interval the suppressed events of rate limited keys are reported, if no
+// deduplication window is defined.
+const DefaultSummaryInterval = time.Minute
+
+// Key identifies repeated events.
+type Key struct {
+	UID     types.UID
+	Reason  string
+	Message string
+}
+
+// KeyOf returns the key of the event.
+func KeyOf(evt *corev1.Event) Key {
+	return Key{
+		UID:     evt.InvolvedObject.UID,
+		Reason:  evt.Reason,
+		Message: evt.Message,
+	}
+}
+
+// Limit a token bucket rate limit.
+type Limit struct {
+	// Rate the number of events per second
+	Rate rate.Limit
+	// Burst the max number of events at once
+	Burst int
+}
+
+func (l *Limit) newLimiter() *rate.Limiter {
+	if l == nil {
+		return nil
+	}
+	return rate.NewLimiter(l.Rate, l.Burst)
+}
+
+// SummaryFunc is called with the last suppressed event and the number of suppressed repeats when the window of a
+// key closes.
+type SummaryFunc func(evt *corev1.Event, suppressed int)
+
+// Options the options of a Throttle.
+type Options struct {
+	// Window the time window repeated events are logged only once
+	Window time.Duration
+	// PerKey the rate limit per key
+	PerKey *Limit
+	// Global the rate limit of all events
+	Global *Limit
+	// OnSummary is called when the window of a key with suppressed events closes. It is never called by Allow, the
+	// summaries of the windows closed by Allow are reported by the next flush, so the caller of Allow may hold locks
+	// that OnSummary acquires.
+	OnSummary SummaryFunc
+	// Clock the clock to use, default the real clock
+	Clock clock.WithTicker
+}
+
+// entry the state of a key. The window is closed if start is zero, the limiter is kept across the windows.
+type entry struct {
+	start      time.Time
+	last       time.Time
+	suppressed int
+	evt        *corev1.Event
+	limiter    *rate.Limiter
+}
+
+// summary the suppressed events of a closed window.
+type summary struct {
+	evt        *corev1.Event
+	suppressed int
+}
+
+// Throttle deduplicates repeated events and limits the rate of events.
+type Throttle struct {
+	opts    Options
+	period  time.Duration
+	global  *rate.Limiter
+	entries map[Key]*entry
+	// pending the summaries of the windows closed by Allow
+	pending []summary
+	mu      sync.Mutex
+	// kick triggers the periodic flush to report the pending summaries
+	kick chan struct{}
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// New creates a new Throttle.
+func New(opts Options) *Throttle {
+	if opts.Clock == nil {
+		opts.Clock = clock.RealClock{}
+	}
+	period := opts.Window
+	if period <= 0 {
+		period = DefaultSummaryInterval
+	}
+	return &Throttle{
+		opts:    opts,
+		period:  period,
+		global:  opts.Global.newLimiter(),
+		entries: make(map[Key]*entry),
+		kick:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Allow returns true if the event should be logged.
+func (t *Throttle) Allow(evt *corev1.Event) bool {
+	allowed, closed := t.allow(evt, t.opts.Clock.Now())
+	if closed {
+		select {
+		case t.kick <- struct{}{}:
+		default:
+		}
+	}
+	return allowed
+}
+
+// allow returns true if the event should be logged and if a window with suppressed events was closed.
+func (t *Throttle) allow(evt *corev1.Event, now time.Time) (allowed, closed bool) {
+	key := KeyOf(evt)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		e = &entry{limiter: t.opts.PerKey.newLimiter()}
+		t.entries[key] = e
+	}
+	if !e.start.IsZero() && !now.Before(e.start.Add(t.period)) {
+		pending := len(t.pending)
+		t.pending = e.closeWindow(t.pending)
+		closed = len(t.pending) > pending
+	}
+	if e.start.IsZero() {
+		e.start = now
+	} else if t.opts.Window > 0 {
+		// repeated event within the window
+		e.suppress(evt, now)
+		return false, closed
+	}
+
+	if e.limiter != nil && !e.limiter.AllowN(now, 1) {
+		e.suppress(evt, now)
+		return false, closed
+	}
+	if t.global != nil && !t.global.AllowN(now, 1) {
+		e.suppress(evt, now)
+		return false, closed
+	}
+	e.last = now
+	return true, closed
+}
+
+func (e *entry) suppress(evt *corev1.Event, now time.Time) {
+	e.suppressed++
+	e.evt = evt
+	e.last = now
+}
+
+// closeWindow appends the suppressed events of the window to the summaries and closes the window.
+func (e *entry) closeWindow(s []summary) []summary {
+	if e.suppressed > 0 {
+		s = append(s, summary{evt: e.evt, suppressed: e.suppressed})
+	}
+	e.start = time.Time{}
+	e.suppressed = 0
+	e.evt = nil
+	return s
+}
+
+// idle returns true if the limiter of the entry is refilled, a new limiter would allow the same events.
+func (e *entry) idle(now time.Time) bool {
+	return e.limiter == nil || e.limiter.TokensAt(now) >= float64(e.limiter.Burst())
+}
+
+// report calls OnSummary with the summaries, it must not be called while holding the lock.
+func (t *Throttle) report(s []summary) {
+	if t.opts.OnSummary == nil {
+		return
+	}
+	for _, sum := range s {
+		t.opts.OnSummary(sum.evt, sum.suppressed)
+	}
+}
+
+// Flush reports the pending summaries and closes all windows that have expired. Entries with a closed window are
+// removed once their limiter is refilled.
+func (t *Throttle) Flush() {
+	now := t.opts.Clock.Now()
+	t.mu.Lock()
+	s := t.pending
+	t.pending = nil
+	for key, e := range t.entries {
+		if !e.start.IsZero() && !now.Before(e.start.Add(t.period)) {
+			s = e.closeWindow(s)
+		}
+		if e.start.IsZero() && e.idle(now) {
+			delete(t.entries, key)
+		}
+	}
+	t.mu.Unlock()
+	t.report(s)
+}
+
+// Start flushes the expired entries periodically and the pending summaries as soon as a window is closed by Allow,
+// until Close is called.
+func (t *Throttle) Start() {
+	interval := min(t.period, time.Second)
+	ticker := t.opts.Clock.NewTicker(interval)
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				t.Flush()
+			case <-t.kick:
+				t.Flush()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the periodic flush and reports the suppressed events of all entries.
+func (t *Throttle) Close() {
+	t.once.Do(func() {
+		close(t.stop)
+	})
+	t.wg.Wait()
+
+	t.mu.Lock()
+	s := t.pending
+	t.pending = nil
+	for key, e := range t.entries {
+		s = e.closeWindow(s)
+		delete(t.entries, key)
+	}
+	t.mu.Unlock()
+	t.report(s)
+}
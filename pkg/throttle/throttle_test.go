@@ -0,0 +1,171 @@
+package throttle_test
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/bakito/k8s-event-logger-operator/pkg/throttle"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Throttle", func() {
+	var (
+		clk        *clocktesting.FakeClock
+		summaries  map[string]int
+		onSummary  throttle.SummaryFunc
+		backOff    *corev1.Event
+		otherEvent *corev1.Event
+	)
+	BeforeEach(func() {
+		clk = clocktesting.NewFakeClock(time.Now())
+		summaries = map[string]int{}
+		onSummary = func(evt *corev1.Event, suppressed int) {
+			summaries[evt.Reason] += suppressed
+		}
+		backOff = &corev1.Event{
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+			InvolvedObject: corev1.ObjectReference{UID: "uid-1"},
+		}
+		otherEvent = &corev1.Event{
+			Reason:         "Pulled",
+			Message:        "Container image pulled",
+			InvolvedObject: corev1.ObjectReference{UID: "uid-1"},
+		}
+	})
+
+	Context("Deduplication", func() {
+		var t *throttle.Throttle
+		BeforeEach(func() {
+			t = throttle.New(throttle.Options{Window: time.Minute, OnSummary: onSummary, Clock: clk})
+		})
+		It("should log repeated events once per window", func() {
+			Ω(t.Allow(backOff)).Should(BeTrue())
+			Ω(t.Allow(backOff)).Should(BeFalse())
+			Ω(t.Allow(backOff)).Should(BeFalse())
+			Ω(t.Allow(otherEvent)).Should(BeTrue())
+
+			clk.Step(30 * time.Second)
+			t.Flush()
+			Ω(summaries).Should(BeEmpty())
+
+			clk.Step(30 * time.Second)
+			t.Flush()
+			Ω(summaries).Should(Equal(map[string]int{"BackOff": 2}))
+
+			Ω(t.Allow(backOff)).Should(BeTrue())
+		})
+		It("should report the summary with the next flush when an expired key is seen again", func() {
+			Ω(t.Allow(backOff)).Should(BeTrue())
+			Ω(t.Allow(backOff)).Should(BeFalse())
+			clk.Step(time.Minute)
+			Ω(t.Allow(backOff)).Should(BeTrue())
+			Ω(summaries).Should(BeEmpty())
+			t.Flush()
+			Ω(summaries).Should(Equal(map[string]int{"BackOff": 1}))
+		})
+		It("should report the pending summaries on close", func() {
+			Ω(t.Allow(backOff)).Should(BeTrue())
+			Ω(t.Allow(backOff)).Should(BeFalse())
+			t.Close()
+			Ω(summaries).Should(Equal(map[string]int{"BackOff": 1}))
+		})
+		It("should distinguish the involved objects", func() {
+			other := backOff.DeepCopy()
+			other.InvolvedObject.UID = "uid-2"
+			Ω(t.Allow(backOff)).Should(BeTrue())
+			Ω(t.Allow(other)).Should(BeTrue())
+		})
+	})
+
+	Context("Rate limit", func() {
+		It("should limit per key", func() {
+			t := throttle.New(throttle.Options{
+				PerKey:    &throttle.Limit{Rate: 1, Burst: 2},
+				OnSummary: onSummary,
+				Clock:     clk,
+			})
+			Ω(t.Allow(backOff)).Should(BeTrue())
+			Ω(t.Allow(backOff)).Should(BeTrue())
+			Ω(t.Allow(backOff)).Should(BeFalse())
+			Ω(t.Allow(otherEvent)).Should(BeTrue())
+
+			clk.Step(time.Second)
+			Ω(t.Allow(backOff)).Should(BeTrue())
+
+			clk.Step(throttle.DefaultSummaryInterval)
+			t.Flush()
+			Ω(summaries).Should(Equal(map[string]int{"BackOff": 1}))
+		})
+		It("should keep the limit of a key across the summaries", func() {
+			t := throttle.New(throttle.Options{
+				PerKey:    &throttle.Limit{Rate: rate.Every(time.Hour), Burst: 1},
+				OnSummary: onSummary,
+				Clock:     clk,
+			})
+			Ω(t.Allow(backOff)).Should(BeTrue())
+			Ω(t.Allow(backOff)).Should(BeFalse())
+
+			clk.Step(throttle.DefaultSummaryInterval)
+			t.Flush()
+			Ω(summaries).Should(Equal(map[string]int{"BackOff": 1}))
+			Ω(t.Allow(backOff)).Should(BeFalse())
+
+			clk.Step(time.Hour)
+			t.Flush()
+			Ω(summaries).Should(Equal(map[string]int{"BackOff": 2}))
+			Ω(t.Allow(backOff)).Should(BeTrue())
+		})
+		It("should limit globally", func() {
+			t := throttle.New(throttle.Options{
+				Global:    &throttle.Limit{Rate: 1, Burst: 1},
+				OnSummary: onSummary,
+				Clock:     clk,
+			})
+			Ω(t.Allow(backOff)).Should(BeTrue())
+			Ω(t.Allow(otherEvent)).Should(BeFalse())
+			clk.Step(time.Second)
+			Ω(t.Allow(otherEvent)).Should(BeTrue())
+			t.Close()
+			Ω(summaries).Should(Equal(map[string]int{"Pulled": 1}))
+		})
+	})
+
+	It("should not report the summaries while allowing an event", func() {
+		var mu sync.Mutex
+		t := throttle.New(throttle.Options{
+			Window: time.Minute,
+			OnSummary: func(evt *corev1.Event, suppressed int) {
+				mu.Lock()
+				defer mu.Unlock()
+				onSummary(evt, suppressed)
+			},
+			Clock: clk,
+		})
+		mu.Lock()
+		Ω(t.Allow(backOff)).Should(BeTrue())
+		Ω(t.Allow(backOff)).Should(BeFalse())
+		clk.Step(time.Minute)
+		Ω(t.Allow(backOff)).Should(BeTrue())
+		mu.Unlock()
+		t.Flush()
+		Ω(summaries).Should(Equal(map[string]int{"BackOff": 1}))
+	})
+
+	It("should flush periodically", func() {
+		t := throttle.New(throttle.Options{Window: time.Second, OnSummary: onSummary, Clock: clk})
+		t.Start()
+		Ω(t.Allow(backOff)).Should(BeTrue())
+		Ω(t.Allow(backOff)).Should(BeFalse())
+		Eventually(clk.HasWaiters).Should(BeTrue())
+		clk.Step(time.Second)
+		t.Close()
+		Ω(summaries).Should(Equal(map[string]int{"BackOff": 1}))
+	})
+})